@@ -2,13 +2,49 @@ package main
 
 import (
 	"net/http"
+	"record-services/internal/admin"
+	"record-services/internal/appointment"
 	"record-services/internal/auth"
+	"record-services/internal/availability"
+	"record-services/internal/booking"
+	"record-services/internal/client"
 	"record-services/internal/config"
+	"record-services/internal/employee"
+	"record-services/internal/holiday"
+	"record-services/internal/jobs"
 	"record-services/internal/middleware"
 	"record-services/internal/migrations"
+	"record-services/internal/reminder"
+	"record-services/internal/repositories/absence_repository"
+	"record-services/internal/repositories/appointment_repository"
+	"record-services/internal/repositories/audit_repository"
+	"record-services/internal/repositories/booking_repository"
+	"record-services/internal/repositories/client_repository"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/holiday_repository"
+	"record-services/internal/repositories/job_repository"
+	"record-services/internal/repositories/recovery_code_repository"
+	"record-services/internal/repositories/refresh_token_repository"
+	"record-services/internal/repositories/reminder_repository"
+	"record-services/internal/repositories/resource_repository"
+	"record-services/internal/repositories/revocation_repository"
+	"record-services/internal/repositories/role_repository"
+	"record-services/internal/repositories/schedule_repository"
+	"record-services/internal/repositories/section_repository"
+	"record-services/internal/repositories/series_repository"
+	"record-services/internal/repositories/session_repository"
 	"record-services/internal/repositories/user_repository"
+	"record-services/internal/repositories/user_token_repository"
+	"record-services/internal/repositories/waitlist_repository"
+	"record-services/internal/resource"
+	"record-services/internal/schedule"
+	"record-services/internal/section"
+	"record-services/internal/waitlist"
 	"record-services/pkg/database"
 	"record-services/pkg/logger"
+	"record-services/pkg/mailer"
+	"record-services/pkg/password"
+	"record-services/pkg/ratelimit"
 	"record-services/pkg/validator"
 )
 
@@ -32,7 +68,71 @@ func main() {
 	loggerApp.Info().Msg("Миграции к БД успешно применены")
 
 	// регистрация репозиториев
-	userRepository := user_repository.NewUserRepository(db, loggerApp)
+	revocationRepository, err := revocation_repository.NewRevocationRepository(db, loggerApp, cfg.Token.AccessTTL)
+	if err != nil {
+		loggerApp.Fatal().Err(err).Msg("ошибка загрузки отозванных токенов")
+	}
+	userRepository := user_repository.NewUserRepository(db, loggerApp, revocationRepository)
+	refreshTokenRepository := refresh_token_repository.NewRefreshTokenRepository(db, loggerApp)
+	userTokenRepository := user_token_repository.NewUserTokenRepository(db, loggerApp)
+	recoveryCodeRepository := recovery_code_repository.NewRecoveryCodeRepository(db, loggerApp)
+	roleRepository := role_repository.NewRoleRepository(db, loggerApp)
+	auditRepository := audit_repository.NewAuditRepository(db, loggerApp)
+	sectionRepository := section_repository.NewSectionRepository(db, loggerApp)
+	resourceRepository := resource_repository.NewResourceRepository(db, loggerApp)
+	employeeRepository := employee_repository.NewEmployeeRepository(db, loggerApp)
+	appointmentRepository := appointment_repository.NewAppointmentRepository(db, loggerApp)
+	scheduleRepository := schedule_repository.NewScheduleRepository(db, loggerApp)
+	absenceRepository := absence_repository.NewAbsenceRepository(db, loggerApp)
+	holidayRepository := holiday_repository.NewHolidayRepository(db, loggerApp)
+	clientRepository := client_repository.NewClientRepository(db, loggerApp)
+	seriesRepository := series_repository.NewSeriesRepository(db, loggerApp)
+	sessionRepository := session_repository.NewSessionRepository(db, loggerApp)
+	bookingRepository := booking_repository.NewBookingRepository(db, loggerApp)
+	waitlistRepository := waitlist_repository.NewWaitlistRepository(db, loggerApp)
+	reminderRepository := reminder_repository.NewReminderRepository(db, loggerApp)
+	jobRepository := job_repository.NewJobRepository(db, loggerApp)
+
+	// почта
+	mail, err := mailer.New(mailer.Config{
+		Driver:   cfg.Mail.Driver,
+		Host:     cfg.Mail.SMTPHost,
+		Port:     cfg.Mail.SMTPPort,
+		Username: cfg.Mail.SMTPUser,
+		Password: cfg.Mail.SMTPPassword,
+		From:     cfg.Mail.From,
+	}, loggerApp)
+	if err != nil {
+		loggerApp.Fatal().Err(err).Msg("ошибка настройки почты")
+	}
+
+	// хеширование паролей
+	passwordHasher, err := password.NewHasher(cfg.Password.Hasher)
+	if err != nil {
+		loggerApp.Fatal().Err(err).Msg("ошибка настройки хеширования паролей")
+	}
+	passwords := password.NewService(passwordHasher, cfg.Secret.HashSecret,
+		password.NewArgon2id(password.DefaultArgon2idParams),
+		password.NewBcrypt(password.DefaultBcryptCost),
+	)
+
+	// расчет свободного времени
+	availabilityEngine := availability.NewEngine(userRepository, employeeRepository, scheduleRepository, appointmentRepository, absenceRepository, holidayRepository, sessionRepository)
+
+	// предложение освободившегося времени листу ожидания
+	waitlistWorker := waitlist.NewWorker(loggerApp, waitlistRepository, jobRepository, employeeRepository, sectionRepository, availabilityEngine, mail, cfg.Server.AppURL)
+
+	// напоминания о записях, отправляемые фоновыми задачами
+	reminderScheduler := reminder.NewScheduler(loggerApp, reminderRepository, jobRepository, appointmentRepository, clientRepository, mail)
+	jobRunner := jobs.NewRunner(loggerApp, jobRepository)
+	jobRunner.Register(reminder.JobKind, reminderScheduler.Send)
+	jobRunner.Register(waitlist.JobKind, waitlistWorker.Offer)
+	jobRunner.Start()
+	reminderScheduler.Start()
+	waitlistWorker.Start()
+
+	// лимиты запросов
+	limiterStore := ratelimit.NewMemoryStore()
 
 	//валидация
 	validate := validator.NewValidate()
@@ -44,7 +144,19 @@ func main() {
 	})
 
 	//регистрация routes
-	authHandlers := auth.NewAuthHandlers(mux, loggerApp, userRepository, validate, cfg.Secret.HashSecret, cfg.Secret.JwtSecret)
+	authHandlers := auth.NewAuthHandlers(mux, loggerApp, userRepository, refreshTokenRepository, revocationRepository, userTokenRepository, recoveryCodeRepository, validate, passwords, mail, limiterStore, cfg.Token, cfg.Server.AppURL, cfg.Secret.JwtSecret)
+	admin.NewAdminHandlers(mux, loggerApp, userRepository, roleRepository, auditRepository, validate, mail, cfg.Server.AppURL)
+	section.NewSectionHandlers(mux, loggerApp, sectionRepository, validate)
+	resource.NewResourceHandlers(mux, loggerApp, resourceRepository, validate)
+	employee.NewEmployeeHandlers(mux, loggerApp, employeeRepository, validate)
+	appointment.NewAppointmentHandlers(mux, loggerApp, appointmentRepository, employeeRepository, sectionRepository, clientRepository, seriesRepository, sessionRepository, availabilityEngine, waitlistWorker, reminderScheduler, validate)
+	schedule.NewScheduleHandlers(mux, loggerApp, scheduleRepository, absenceRepository, employeeRepository, validate)
+	holiday.NewHolidayHandlers(mux, loggerApp, holidayRepository, validate)
+	client.NewClientHandlers(mux, loggerApp, clientRepository, appointmentRepository, validate)
+	booking.NewBookingHandlers(mux, loggerApp, bookingRepository, sectionRepository, employeeRepository, appointmentRepository, clientRepository, sessionRepository, availabilityEngine, waitlistWorker, reminderScheduler, validate, mail, limiterStore, cfg.Server.AppURL)
+	waitlist.NewWaitlistHandlers(mux, loggerApp, waitlistRepository, bookingRepository, sectionRepository, employeeRepository, clientRepository, waitlistWorker, reminderScheduler, validate, mail, limiterStore, cfg.Server.AppURL)
+	reminder.NewReminderHandlers(mux, loggerApp, reminderRepository, validate)
+	availability.NewAvailabilityHandlers(mux, loggerApp, availabilityEngine, employeeRepository, sectionRepository)
 
 	//middlewares
 	middlewareAuth := middleware.AuthMiddleware(authHandlers)(mux)
@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/pkg/httputils"
+)
+
+const auditTargetUser = "user"
+
+// Журнал действий. Фильтры: actor_id, target_id, action
+func (h *AdminHandlers) listAudit(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.auditLog.GetAllWithPagination(
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.AuditFilter{
+			ActorID:  uint(max(httputils.QueryInt(r, "actor_id", 0), 0)),
+			TargetID: uint(max(httputils.QueryInt(r, "target_id", 0), 0)),
+			Action:   models.AuditAction(r.URL.Query().Get("action")),
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении журнала", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, entries)
+}
+
+// Действие уже выполнено, ошибка записи в журнал только логируется репозиторием
+func (h *AdminHandlers) audit(r *http.Request, action models.AuditAction, targetID uint, details string) {
+	entry := &models.AuditLog{
+		Action:     action,
+		TargetType: auditTargetUser,
+		TargetID:   targetID,
+		Details:    details,
+		IP:         httputils.ClientIP(r),
+	}
+	if claims := middleware.GetUserFromContext(r.Context()); claims != nil {
+		entry.ActorID = claims.ID
+	}
+
+	_ = h.auditLog.Create(entry)
+}
@@ -0,0 +1,193 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/repositories/audit_repository"
+	"record-services/internal/repositories/role_repository"
+	"record-services/internal/repositories/user_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/mailer"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+type AdminHandlers struct {
+	mux        *http.ServeMux
+	logger     *zerolog.Logger
+	repository user_repository.UserRepository
+	roles      role_repository.RoleRepository
+	auditLog   audit_repository.AuditRepository
+	validator  *validator.Validate
+	mailer     mailer.Mailer
+	appURL     string
+}
+
+func NewAdminHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository user_repository.UserRepository, roles role_repository.RoleRepository, auditLog audit_repository.AuditRepository, validator *validator.Validate, mailer mailer.Mailer, appURL string) *AdminHandlers {
+	adminHandlers := &AdminHandlers{
+		mux:        mux,
+		logger:     logger,
+		repository: repository,
+		roles:      roles,
+		auditLog:   auditLog,
+		validator:  validator,
+		mailer:     mailer,
+		appURL:     appURL,
+	}
+
+	manageUsers := middleware.RequirePermission(consts.PermUsersManage)
+	manageRoles := middleware.RequirePermission(consts.PermRolesManage)
+
+	adminHandlers.mux.Handle("GET /api/admin/users", manageUsers(http.HandlerFunc(adminHandlers.listUsers)))
+	adminHandlers.mux.Handle("GET /api/admin/users/{id}", manageUsers(http.HandlerFunc(adminHandlers.getUserByID)))
+	adminHandlers.mux.Handle("POST /api/admin/users/{id}/activate", manageUsers(http.HandlerFunc(adminHandlers.activate)))
+	adminHandlers.mux.Handle("POST /api/admin/users/{id}/deactivate", manageUsers(http.HandlerFunc(adminHandlers.deactivate)))
+	adminHandlers.mux.Handle("DELETE /api/admin/users/{id}", manageUsers(http.HandlerFunc(adminHandlers.deleteUser)))
+	adminHandlers.mux.Handle("POST /api/admin/users/{id}/restore", manageUsers(http.HandlerFunc(adminHandlers.restoreUser)))
+	adminHandlers.mux.Handle("PUT /api/admin/users/{id}/owner", manageUsers(http.HandlerFunc(adminHandlers.setOwner)))
+	adminHandlers.mux.Handle("POST /api/admin/users/{id}/admin", manageRoles(http.HandlerFunc(adminHandlers.grantAdmin)))
+	adminHandlers.mux.Handle("DELETE /api/admin/users/{id}/admin", manageRoles(http.HandlerFunc(adminHandlers.revokeAdmin)))
+	adminHandlers.mux.Handle("GET /api/admin/audit", manageUsers(http.HandlerFunc(adminHandlers.listAudit)))
+	adminHandlers.mux.Handle("GET /api/admin/users/pending", manageUsers(http.HandlerFunc(adminHandlers.listPending)))
+	adminHandlers.mux.Handle("POST /api/admin/users/{id}/approve", manageUsers(http.HandlerFunc(adminHandlers.approve)))
+	adminHandlers.mux.Handle("POST /api/admin/users/{id}/reject", manageUsers(http.HandlerFunc(adminHandlers.reject)))
+	adminHandlers.mux.Handle("GET /api/admin/roles", manageRoles(http.HandlerFunc(adminHandlers.listRoles)))
+	adminHandlers.mux.Handle("PUT /api/admin/users/{id}/roles", manageRoles(http.HandlerFunc(adminHandlers.setRoles)))
+
+	return adminHandlers
+}
+
+// Заявки на регистрацию. verified=true|false|all фильтрует по подтверждению email
+func (h *AdminHandlers) listPending(w http.ResponseWriter, r *http.Request) {
+	users, err := h.repository.GetAllWithPagination(
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.UserFilter{
+			Name:           r.URL.Query().Get("name"),
+			ApprovalStatus: models.ApprovalStatusPending,
+			EmailVerified:  httputils.QueryCheckValue(r, "verified"),
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении заявок", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, users)
+}
+
+func (h *AdminHandlers) approve(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.getPendingUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.EmailVerifiedAt == nil {
+		httputils.SendError(w, "Email пользователя не подтвержден", http.StatusConflict)
+		return
+	}
+
+	user.ApprovalStatus = models.ApprovalStatusApproved
+	user.IsActive = true
+
+	if _, err := h.repository.Update(user); err != nil {
+		httputils.SendError(w, "Ошибка при одобрении заявки", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Регистрация пользователя %s одобрена", user.Email)
+	h.audit(r, models.AuditActionUserApprove, user.ID, "")
+
+	h.notify(r.Context(), user, "Регистрация одобрена",
+		fmt.Sprintf("Здравствуйте, %s!\n\nВаша регистрация одобрена. Войти можно по ссылке:\n%s/login", user.Name, h.appURL))
+
+	httputils.SendJSONResponse(w, user.ToResponse())
+}
+
+func (h *AdminHandlers) reject(w http.ResponseWriter, r *http.Request) {
+	var rejectData struct {
+		Reason string `json:"reason" validate:"max=500"`
+	}
+
+	// Причина необязательна, тело может отсутствовать
+	if r.ContentLength != 0 && !httputils.DecodeAndValidate(w, r, h.validator, &rejectData) {
+		return
+	}
+
+	user, ok := h.getPendingUser(w, r)
+	if !ok {
+		return
+	}
+
+	user.ApprovalStatus = models.ApprovalStatusRejected
+	user.IsActive = false
+
+	if _, err := h.repository.Update(user); err != nil {
+		httputils.SendError(w, "Ошибка при отклонении заявки", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Регистрация пользователя %s отклонена", user.Email)
+	h.audit(r, models.AuditActionUserReject, user.ID, rejectData.Reason)
+
+	body := fmt.Sprintf("Здравствуйте, %s!\n\nВаша регистрация отклонена администратором.", user.Name)
+	if rejectData.Reason != "" {
+		body += "\nПричина: " + rejectData.Reason
+	}
+	h.notify(r.Context(), user, "Регистрация отклонена", body)
+
+	httputils.SendJSONResponse(w, user.ToResponse())
+}
+
+func (h *AdminHandlers) getPendingUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	if user.ApprovalStatus != models.ApprovalStatusPending {
+		httputils.SendError(w, "Заявка уже рассмотрена", http.StatusConflict)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// Пользователь по {id} из пути. При ошибке сам отправляет ответ
+func (h *AdminHandlers) getUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	user, err := h.repository.GetById(id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении пользователя", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if user == nil {
+		httputils.SendError(w, "Пользователь не найден", http.StatusNotFound)
+		return nil, false
+	}
+
+	return user, true
+}
+
+// Решение уже сохранено, ошибка отправки письма только логируется
+func (h *AdminHandlers) notify(ctx context.Context, user *models.User, subject string, body string) {
+	err := h.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: subject,
+		Body:    body,
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отправке письма пользователю: %s", user.Email)
+	}
+}
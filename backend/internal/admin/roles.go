@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"slices"
+)
+
+func (h *AdminHandlers) listRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roles.GetAll()
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении ролей", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, roles)
+}
+
+// Полностью заменяет набор ролей пользователя
+func (h *AdminHandlers) setRoles(w http.ResponseWriter, r *http.Request) {
+	var rolesData struct {
+		Roles []consts.RoleName `json:"roles" validate:"required,dive,required,max=50"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &rolesData) {
+		return
+	}
+
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !slices.Contains(rolesData.Roles, consts.RoleAdmin) && h.isSelf(r, user) && user.IsAdmin {
+		httputils.SendError(w, "Нельзя снять с себя роль администратора", http.StatusConflict)
+		return
+	}
+
+	h.applyRoles(w, r, user, rolesData.Roles, models.AuditActionUserSetRoles)
+}
+
+func (h *AdminHandlers) grantAdmin(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.IsAdmin {
+		httputils.SendJSONResponse(w, user.ToResponse())
+		return
+	}
+
+	roles := append(roleNames(user), consts.RoleAdmin)
+	h.applyRoles(w, r, user, roles, models.AuditActionUserGrantAdmin)
+}
+
+func (h *AdminHandlers) revokeAdmin(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	if h.isSelf(r, user) {
+		httputils.SendError(w, "Нельзя снять с себя роль администратора", http.StatusConflict)
+		return
+	}
+
+	if !user.IsAdmin {
+		httputils.SendJSONResponse(w, user.ToResponse())
+		return
+	}
+
+	roles := slices.DeleteFunc(roleNames(user), func(role consts.RoleName) bool {
+		return role == consts.RoleAdmin
+	})
+	h.applyRoles(w, r, user, roles, models.AuditActionUserRevokeAdmin)
+}
+
+// Сохраняет роли, пишет журнал и отправляет обновленного пользователя
+func (h *AdminHandlers) applyRoles(w http.ResponseWriter, r *http.Request, user *models.User, roles []consts.RoleName, action models.AuditAction) {
+	if err := h.repository.SetRoles(user.ID, roles); err != nil {
+		if errors.Is(err, consts.ErrBadData) {
+			httputils.SendError(w, "Неизвестная роль", http.StatusBadRequest)
+			return
+		}
+		httputils.SendError(w, "Ошибка при назначении ролей", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Пользователю %s назначены роли: %v", user.Email, roles)
+	h.audit(r, action, user.ID, fmt.Sprintf("%v", roles))
+
+	user, err := h.repository.GetById(user.ID)
+	if err != nil || user == nil {
+		httputils.SendError(w, "Ошибка при получении пользователя", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, user.ToResponse())
+}
+
+func roleNames(user *models.User) []consts.RoleName {
+	names := make([]consts.RoleName, len(user.Roles))
+	for i, role := range user.Roles {
+		names[i] = consts.RoleName(role.Name)
+	}
+	return names
+}
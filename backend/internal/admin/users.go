@@ -0,0 +1,211 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/types"
+)
+
+// Поиск пользователей. active, admin, verified - true|false|all (по умолчанию all),
+// deleted - true|false|all (по умолчанию false)
+func (h *AdminHandlers) listUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.repository.GetAllWithPagination(
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.UserFilter{
+			Name:           r.URL.Query().Get("name"),
+			Email:          r.URL.Query().Get("email"),
+			ApprovalStatus: models.ApprovalStatus(r.URL.Query().Get("status")),
+			EmailVerified:  httputils.QueryCheckValue(r, "verified"),
+			IsActive:       httputils.QueryCheckValue(r, "active"),
+			IsAdmin:        httputils.QueryCheckValue(r, "admin"),
+			Deleted:        httputils.QueryCheckValueOr(r, "deleted", types.CheckValueFalse),
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении пользователей", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, users)
+}
+
+func (h *AdminHandlers) getUserByID(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, user.ToResponse())
+}
+
+func (h *AdminHandlers) activate(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	// Заявка рассматривается отдельно: approve или reject
+	if user.ApprovalStatus != models.ApprovalStatusApproved {
+		httputils.SendError(w, "Регистрация пользователя не одобрена", http.StatusConflict)
+		return
+	}
+
+	h.setActive(w, r, user, true)
+}
+
+func (h *AdminHandlers) deactivate(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	if h.isSelf(r, user) {
+		httputils.SendError(w, "Нельзя деактивировать самого себя", http.StatusConflict)
+		return
+	}
+
+	h.setActive(w, r, user, false)
+}
+
+func (h *AdminHandlers) setActive(w http.ResponseWriter, r *http.Request, user *models.User, active bool) {
+	if user.IsActive == active {
+		httputils.SendJSONResponse(w, user.ToResponse())
+		return
+	}
+
+	user.IsActive = active
+
+	// При деактивации репозиторий отзывает токены пользователя
+	if _, err := h.repository.Update(user); err != nil {
+		httputils.SendError(w, "Ошибка при изменении пользователя", http.StatusInternalServerError)
+		return
+	}
+
+	action := models.AuditActionUserActivate
+	if !active {
+		action = models.AuditActionUserDeactivate
+	}
+	h.logger.Info().Msgf("Пользователь %s: %s", user.Email, action)
+	h.audit(r, action, user.ID, "")
+
+	httputils.SendJSONResponse(w, user.ToResponse())
+}
+
+func (h *AdminHandlers) deleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	if h.isSelf(r, user) {
+		httputils.SendError(w, "Нельзя удалить самого себя", http.StatusConflict)
+		return
+	}
+
+	if err := h.repository.Delete(user.ID); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Пользователь не найден", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при удалении пользователя", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Пользователь %s удален", user.Email)
+	h.audit(r, models.AuditActionUserDelete, user.ID, "")
+
+	httputils.SendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+func (h *AdminHandlers) restoreUser(w http.ResponseWriter, r *http.Request) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repository.Restore(id); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Удаленный пользователь не найден", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при восстановлении пользователя", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(r, models.AuditActionUserRestore, id, "")
+
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	h.logger.Info().Msgf("Пользователь %s восстановлен", user.Email)
+
+	httputils.SendJSONResponse(w, user.ToResponse())
+}
+
+// Привязка менеджера, администратора зала или сотрудника к организации
+// владельца: пользователь работает с его услугами, сотрудниками и записями.
+// ownerId: null отвязывает пользователя. Владельцем назначается только
+// пользователь, который сам не работает в другой организации, и только
+// тому, у кого нет своих сотрудников
+func (h *AdminHandlers) setOwner(w http.ResponseWriter, r *http.Request) {
+	var ownerData struct {
+		OwnerID *uint `json:"ownerId" validate:"omitempty,min=1"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &ownerData) {
+		return
+	}
+
+	user, ok := h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	if ownerData.OwnerID != nil && *ownerData.OwnerID == user.ID {
+		httputils.SendError(w, "Пользователь не может быть владельцем самого себя", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repository.SetOwner(user.ID, ownerData.OwnerID); err != nil {
+		switch {
+		case errors.Is(err, consts.ErrNotFound):
+			httputils.SendError(w, "Пользователь не найден", http.StatusNotFound)
+		case errors.Is(err, consts.ErrBadData):
+			httputils.SendError(w, "Владелец не найден или сам работает в другой организации", http.StatusBadRequest)
+		case errors.Is(err, consts.ErrConflict):
+			httputils.SendError(w, "У пользователя есть свои сотрудники, владелец ему не назначается", http.StatusConflict)
+		default:
+			httputils.SendError(w, "Ошибка при назначении владельца", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	details := "нет"
+	if ownerData.OwnerID != nil {
+		details = fmt.Sprintf("%d", *ownerData.OwnerID)
+	}
+	h.logger.Info().Msgf("Пользователю %s назначен владелец: %s", user.Email, details)
+	h.audit(r, models.AuditActionUserSetOwner, user.ID, details)
+
+	user, ok = h.getUser(w, r)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, user.ToResponse())
+}
+
+// Действие администратора над собственной учетной записью
+func (h *AdminHandlers) isSelf(r *http.Request, user *models.User) bool {
+	claims := middleware.GetUserFromContext(r.Context())
+	return claims != nil && claims.ID == user.ID
+}
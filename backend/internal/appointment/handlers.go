@@ -0,0 +1,600 @@
+package appointment
+
+import (
+	"errors"
+	"net/http"
+	"record-services/internal/availability"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/reminder"
+	"record-services/internal/repositories/appointment_repository"
+	"record-services/internal/repositories/client_repository"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/section_repository"
+	"record-services/internal/repositories/series_repository"
+	"record-services/internal/repositories/session_repository"
+	"record-services/internal/waitlist"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/phone"
+	"record-services/pkg/types"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+// Защита от опечаток в дате окончания
+const maxAppointmentDuration = 12 * time.Hour
+
+type AppointmentHandlers struct {
+	mux        *http.ServeMux
+	logger     *zerolog.Logger
+	repository appointment_repository.AppointmentRepository
+	employees  employee_repository.EmployeeRepository
+	sections   section_repository.SectionRepository
+	clients    client_repository.ClientRepository
+	series     series_repository.SeriesRepository
+	sessions   session_repository.SessionRepository
+	engine     *availability.Engine
+	waitlist   *waitlist.Worker
+	reminders  *reminder.Scheduler
+	validator  *validator.Validate
+}
+
+func NewAppointmentHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository appointment_repository.AppointmentRepository, employees employee_repository.EmployeeRepository, sections section_repository.SectionRepository, clients client_repository.ClientRepository, series series_repository.SeriesRepository, sessions session_repository.SessionRepository, engine *availability.Engine, waitlist *waitlist.Worker, reminders *reminder.Scheduler, validator *validator.Validate) *AppointmentHandlers {
+	appointmentHandlers := &AppointmentHandlers{
+		mux:        mux,
+		logger:     logger,
+		repository: repository,
+		employees:  employees,
+		sections:   sections,
+		clients:    clients,
+		series:     series,
+		sessions:   sessions,
+		engine:     engine,
+		waitlist:   waitlist,
+		reminders:  reminders,
+		validator:  validator,
+	}
+
+	read := middleware.RequirePermission(consts.PermAppointmentsRead)
+	write := middleware.RequirePermission(consts.PermAppointmentsWrite)
+
+	appointmentHandlers.mux.Handle("GET /api/appointments", read(http.HandlerFunc(appointmentHandlers.list)))
+	appointmentHandlers.mux.Handle("GET /api/appointments/{id}", read(http.HandlerFunc(appointmentHandlers.get)))
+	appointmentHandlers.mux.Handle("POST /api/appointments", write(http.HandlerFunc(appointmentHandlers.create)))
+	appointmentHandlers.mux.Handle("PUT /api/appointments/{id}", write(http.HandlerFunc(appointmentHandlers.update)))
+	appointmentHandlers.mux.Handle("POST /api/appointments/{id}/status", write(http.HandlerFunc(appointmentHandlers.setStatus)))
+
+	appointmentHandlers.mux.Handle("POST /api/appointment-series", write(http.HandlerFunc(appointmentHandlers.createSeries)))
+	appointmentHandlers.mux.Handle("GET /api/appointment-series/{id}", read(http.HandlerFunc(appointmentHandlers.getSeriesWithOccurrences)))
+	appointmentHandlers.mux.Handle("PUT /api/appointment-series/{id}", write(http.HandlerFunc(appointmentHandlers.updateSeries)))
+	appointmentHandlers.mux.Handle("POST /api/appointment-series/{id}/cancel", write(http.HandlerFunc(appointmentHandlers.cancelSeries)))
+
+	appointmentHandlers.mux.Handle("GET /api/sessions", read(http.HandlerFunc(appointmentHandlers.listSessions)))
+	appointmentHandlers.mux.Handle("GET /api/sessions/{id}", read(http.HandlerFunc(appointmentHandlers.getSessionWithAttendees)))
+	appointmentHandlers.mux.Handle("PUT /api/sessions/{id}", write(http.HandlerFunc(appointmentHandlers.updateSession)))
+
+	return appointmentHandlers
+}
+
+// Клиент задается карточкой clientId или именем и контактами: по телефону
+// или email находится существующая карточка, иначе создается новая
+type appointmentData struct {
+	EmployeeID  uint      `json:"employeeId" validate:"required"`
+	SectionID   uint      `json:"sectionId" validate:"required"`
+	ClientID    *uint     `json:"clientId" validate:"omitempty,gt=0"`
+	ClientName  string    `json:"clientName" validate:"required_without=ClientID,max=255"`
+	ClientPhone string    `json:"clientPhone" validate:"max=20"`
+	ClientEmail string    `json:"clientEmail" validate:"omitempty,email,max=255"`
+	StartAt     time.Time `json:"startAt" validate:"required"`
+	// Если не задано - по длительности услуги
+	EndAt   time.Time `json:"endAt" validate:"omitempty,gtfield=StartAt"`
+	Comment string    `json:"comment" validate:"max=5000"`
+}
+
+// Фильтры: employee_id, section_id, series_id, session_id, resource_id, status,
+// from/to в RFC3339
+func (h *AppointmentHandlers) list(w http.ResponseWriter, r *http.Request) {
+	from, err := httputils.QueryTime(r, "from")
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата from", http.StatusBadRequest)
+		return
+	}
+
+	to, err := httputils.QueryTime(r, "to")
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата to", http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	appointments, err := h.repository.GetAllWithPagination(r.Context(),
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.AppointmentFilter{
+			UserID:     claims.OwnerScope(),
+			EmployeeID: uint(max(httputils.QueryInt(r, "employee_id", 0), 0)),
+			SectionID:  uint(max(httputils.QueryInt(r, "section_id", 0), 0)),
+			SeriesID:   uint(max(httputils.QueryInt(r, "series_id", 0), 0)),
+			SessionID:  uint(max(httputils.QueryInt(r, "session_id", 0), 0)),
+			ResourceID: uint(max(httputils.QueryInt(r, "resource_id", 0), 0)),
+			Status:     models.AppointmentStatus(r.URL.Query().Get("status")),
+			From:       from,
+			To:         to,
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении записей", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, appointments)
+}
+
+func (h *AppointmentHandlers) get(w http.ResponseWriter, r *http.Request) {
+	appointment, ok := h.getAppointment(w, r)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, appointment.ToResponse())
+}
+
+func (h *AppointmentHandlers) create(w http.ResponseWriter, r *http.Request) {
+	var data appointmentData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	target, ok := h.resolveTarget(w, r, &data, nil)
+	if !ok || !h.checkSlot(w, r, target, &data, nil) {
+		return
+	}
+
+	clientID, ok := h.resolveClient(w, r, &data, target.employee.UserID)
+	if !ok {
+		return
+	}
+
+	appointment := &models.Appointment{Status: models.AppointmentStatusPending, ClientID: clientID}
+	target.apply(appointment)
+	applyData(appointment, &data)
+
+	// На групповую услугу запись занимает место в занятии
+	var err error
+	if target.section.IsGroup() {
+		err = h.sessions.BookSeat(r.Context(), appointment, target.terms.Capacity)
+	} else {
+		_, err = h.repository.Create(r.Context(), appointment)
+	}
+	if err != nil {
+		h.sendSaveError(w, err, "Ошибка при создании записи")
+		return
+	}
+
+	h.reminders.Schedule(r.Context(), appointment)
+
+	httputils.SendJSONWithStatus(w, appointment.ToResponse(), http.StatusCreated)
+}
+
+// Перенос и изменение данных клиента. Доступно, пока запись активна
+func (h *AppointmentHandlers) update(w http.ResponseWriter, r *http.Request) {
+	var data appointmentData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	appointment, ok := h.getAppointment(w, r)
+	if !ok {
+		return
+	}
+
+	h.updateAppointment(w, r, appointment, &data)
+}
+
+// Изменение вхождения серии делает его исключением из серии.
+// Место в групповом занятии не переносится: у занятия общее время
+func (h *AppointmentHandlers) updateAppointment(w http.ResponseWriter, r *http.Request, appointment *models.Appointment, data *appointmentData) {
+	if !appointment.Status.IsActive() {
+		httputils.SendError(w, "Завершенную или отмененную запись изменить нельзя", http.StatusConflict)
+		return
+	}
+
+	// Окончание места совпадает с занятием, даже если длительность услуги изменилась
+	if appointment.SessionID != nil && data.EndAt.IsZero() && data.StartAt.Equal(appointment.StartAt) {
+		data.EndAt = appointment.EndAt
+	}
+
+	target, ok := h.resolveTarget(w, r, data, appointment)
+	if !ok {
+		return
+	}
+
+	moved := appointment.EmployeeID != data.EmployeeID || appointment.SectionID != data.SectionID ||
+		!appointment.StartAt.Equal(data.StartAt) || !appointment.EndAt.Equal(data.EndAt)
+	if moved && (appointment.SessionID != nil || target.section.IsGroup()) {
+		httputils.SendError(w, "Запись на групповое занятие нельзя перенести: отмените ее и запишите клиента на другое занятие", http.StatusConflict)
+		return
+	}
+
+	if !h.checkSlot(w, r, target, data, appointment) {
+		return
+	}
+
+	// Без клиента и контактов запись остается за прежним клиентом
+	if data.ClientID == nil && data.ClientPhone == "" && data.ClientEmail == "" &&
+		appointment.ClientID != nil && appointment.UserID == target.employee.UserID {
+		data.ClientID = appointment.ClientID
+	}
+
+	clientID, ok := h.resolveClient(w, r, data, target.employee.UserID)
+	if !ok {
+		return
+	}
+
+	// Сотрудник связал онлайн-запись с карточкой: согласие на напоминания,
+	// данное при записи, переходит в карточку
+	if appointment.RemindersConsent && clientID != nil && (appointment.ClientID == nil || *appointment.ClientID != *clientID) {
+		if err := h.clients.GrantRemindersConsent(r.Context(), *clientID); err != nil {
+			h.logger.Error().Err(err).Msgf("Ошибка при переносе согласия на напоминания из записи %d", appointment.ID)
+		}
+	}
+
+	previous := *appointment
+	appointment.ClientID = clientID
+	appointment.SeriesException = appointment.SeriesID != nil
+	target.apply(appointment)
+	applyData(appointment, data)
+
+	if _, err := h.repository.Update(r.Context(), appointment); err != nil {
+		h.sendSaveError(w, err, "Ошибка при обновлении записи")
+		return
+	}
+
+	if previous.EmployeeID != appointment.EmployeeID || !previous.StartAt.Equal(appointment.StartAt) || !previous.EndAt.Equal(appointment.EndAt) {
+		h.waitlist.Released(previous)
+	}
+
+	// Напоминания планируются к новому времени
+	h.reminders.Schedule(r.Context(), appointment)
+
+	httputils.SendJSONResponse(w, appointment.ToResponse())
+}
+
+func (h *AppointmentHandlers) setStatus(w http.ResponseWriter, r *http.Request) {
+	var statusData struct {
+		Status models.AppointmentStatus `json:"status" validate:"required,max=20"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &statusData) {
+		return
+	}
+
+	if !statusData.Status.IsValid() {
+		httputils.SendError(w, "Неизвестный статус", http.StatusBadRequest)
+		return
+	}
+
+	appointment, ok := h.getAppointment(w, r)
+	if !ok {
+		return
+	}
+
+	if appointment.Status == statusData.Status {
+		httputils.SendJSONResponse(w, appointment.ToResponse())
+		return
+	}
+
+	if !appointment.Status.CanTransitionTo(statusData.Status) {
+		httputils.SendError(w, "Недопустимая смена статуса записи", http.StatusConflict)
+		return
+	}
+
+	appointment.Status = statusData.Status
+
+	if _, err := h.repository.Update(r.Context(), appointment); err != nil {
+		h.sendSaveError(w, err, "Ошибка при смене статуса записи")
+		return
+	}
+
+	h.logger.Info().Msgf("Запись %d переведена в статус %s", appointment.ID, appointment.Status)
+
+	if appointment.Status == models.AppointmentStatusCancelled {
+		h.waitlist.Released(*appointment)
+	}
+
+	if !appointment.Status.IsActive() {
+		h.reminders.Cancel(r.Context(), appointment.ID)
+	}
+
+	httputils.SendJSONResponse(w, appointment.ToResponse())
+}
+
+// Сотрудник и услуга записи с условиями, действующими для этого сотрудника,
+// и часовой пояс его графика
+type bookingTarget struct {
+	employee *models.Employee
+	section  *models.Section
+	terms    models.ServiceTerms
+	location *time.Location
+}
+
+// Привязывает запись к сотруднику и услуге и фиксирует текущие условия
+func (t *bookingTarget) apply(appointment *models.Appointment) {
+	appointment.UserID = t.employee.UserID
+	appointment.Employee = *t.employee
+	appointment.Section = *t.section
+	appointment.Price = t.terms.Price
+	appointment.Currency = t.terms.Currency
+	appointment.BufferBeforeMinutes = int(t.terms.BufferBefore / time.Minute)
+	appointment.BufferAfterMinutes = int(t.terms.BufferAfter / time.Minute)
+	appointment.ResourceIDs = types.IDList(t.terms.Resources)
+	appointment.TimeZone = t.location.String()
+}
+
+// Проверяет сотрудника и секцию из запроса: оба доступны пользователю,
+// принадлежат одному владельцу, сотрудник активен и работает в секции.
+// current - переносимая запись, nil при создании. Заполняет data.EndAt
+// по длительности услуги. При ошибке сам отправляет ответ
+func (h *AppointmentHandlers) resolveTarget(w http.ResponseWriter, r *http.Request, data *appointmentData, current *models.Appointment) (*bookingTarget, bool) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	employee, err := h.employees.GetById(r.Context(), data.EmployeeID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении сотрудника", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if employee == nil || !claims.CanAccess(employee.UserID) {
+		httputils.SendError(w, "Сотрудник не найден", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if !employee.IsActive {
+		httputils.SendError(w, "Сотрудник неактивен", http.StatusBadRequest)
+		return nil, false
+	}
+
+	section, err := h.sections.GetById(r.Context(), data.SectionID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении секции", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if section == nil || section.UserID != employee.UserID {
+		httputils.SendError(w, "Секция не найдена", http.StatusBadRequest)
+		return nil, false
+	}
+
+	override, err := h.employees.GetSectionSettings(r.Context(), employee.ID, section.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении параметров секции", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if override == nil {
+		httputils.SendError(w, "Сотрудник не работает в этой секции", http.StatusBadRequest)
+		return nil, false
+	}
+
+	location, err := h.engine.Location(r.Context(), employee.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении часового пояса сотрудника", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	target := &bookingTarget{employee: employee, section: section, terms: section.Terms(override), location: location}
+
+	// Цена, буферы и ресурсы фиксируются при записи и пересчитываются
+	// только при смене сотрудника или услуги
+	if current != nil && current.EmployeeID == employee.ID && current.SectionID == section.ID {
+		target.terms.Price = current.Price
+		target.terms.Currency = current.Currency
+		target.terms.BufferBefore = time.Duration(current.BufferBeforeMinutes) * time.Minute
+		target.terms.BufferAfter = time.Duration(current.BufferAfterMinutes) * time.Minute
+		target.terms.Resources = current.ResourceIDs
+	}
+
+	if data.EndAt.IsZero() {
+		data.EndAt = data.StartAt.Add(target.terms.Duration)
+	}
+
+	if data.EndAt.Sub(data.StartAt) > maxAppointmentDuration {
+		httputils.SendError(w, "Слишком длинная запись", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return target, true
+}
+
+// Проверяет, что время записи попадает в окно записи услуги, сотрудник
+// не отсутствует и свободен с учетом буферов, а нужные услуге ресурсы
+// свободны. Занятие, к которому присоединяется запись групповой услуги,
+// занятостью не считается, окончание записи берется из него.
+// При ошибке сам отправляет ответ
+func (h *AppointmentHandlers) checkSlot(w http.ResponseWriter, r *http.Request, target *bookingTarget, data *appointmentData, current *models.Appointment) bool {
+	employee := target.employee
+
+	var excludeID, sessionID uint
+	switch {
+	case current != nil:
+		excludeID = current.ID
+		if current.SessionID != nil {
+			sessionID = *current.SessionID
+		}
+
+	case target.section.IsGroup():
+		session, err := h.sessions.GetBySlot(r.Context(), employee.ID, target.section.ID, data.StartAt.UTC())
+		if err != nil {
+			httputils.SendError(w, "Ошибка при получении занятия", http.StatusInternalServerError)
+			return false
+		}
+
+		if session != nil {
+			if session.Available() == 0 {
+				httputils.SendError(w, "В занятии нет свободных мест", http.StatusConflict)
+				return false
+			}
+			sessionID = session.ID
+			data.EndAt = session.EndAt
+		}
+	}
+
+	// Окно записи проверяется для нового времени, перенос в пределах
+	// уже согласованного времени не ограничивается
+	if current == nil || !current.StartAt.Equal(data.StartAt) {
+		notBefore, notAfter := target.terms.BookingWindow(time.Now())
+		if data.StartAt.Before(notBefore) {
+			httputils.SendError(w, "Слишком поздно для записи на это время", http.StatusBadRequest)
+			return false
+		}
+		if !notAfter.IsZero() && data.StartAt.After(notAfter) {
+			httputils.SendError(w, "Запись на это время еще не открыта", http.StatusBadRequest)
+			return false
+		}
+	}
+
+	start, end := data.StartAt.UTC(), data.EndAt.UTC()
+
+	blocked, err := h.engine.IsBlocked(r.Context(), employee.UserID, employee.ID, start, end)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при проверке отсутствий сотрудника", http.StatusInternalServerError)
+		return false
+	}
+
+	if blocked {
+		httputils.SendError(w, "Сотрудник отсутствует или в это время выходной", http.StatusConflict)
+		return false
+	}
+
+	busy, err := h.engine.IsBusy(r.Context(), employee.ID, start, end, target.terms, excludeID, sessionID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при проверке занятости сотрудника", http.StatusInternalServerError)
+		return false
+	}
+
+	if busy {
+		httputils.SendError(w, "Сотрудник уже занят в это время", http.StatusConflict)
+		return false
+	}
+
+	busy, err = h.engine.IsResourceBusy(r.Context(), start, end, target.terms, excludeID, sessionID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при проверке занятости ресурсов", http.StatusInternalServerError)
+		return false
+	}
+
+	if busy {
+		httputils.SendError(w, "Помещение или оборудование для услуги занято в это время", http.StatusConflict)
+		return false
+	}
+
+	return true
+}
+
+// Карточка клиента записи владельца ownerID. Нормализует контакты в data
+// и дополняет их из карточки. Без клиента и контактов возвращает nil.
+// При ошибке сам отправляет ответ
+func (h *AppointmentHandlers) resolveClient(w http.ResponseWriter, r *http.Request, data *appointmentData, ownerID uint) (*uint, bool) {
+	normalizedPhone, err := phone.Normalize(data.ClientPhone)
+	if err != nil {
+		httputils.SendError(w, "Некорректный номер телефона", http.StatusBadRequest)
+		return nil, false
+	}
+	data.ClientPhone = normalizedPhone
+	data.ClientEmail = strings.ToLower(strings.TrimSpace(data.ClientEmail))
+
+	var client *models.Client
+	switch {
+	case data.ClientID != nil:
+		client, err = h.clients.GetById(r.Context(), *data.ClientID)
+		if err != nil {
+			httputils.SendError(w, "Ошибка при получении клиента", http.StatusInternalServerError)
+			return nil, false
+		}
+		if client == nil || client.UserID != ownerID {
+			httputils.SendError(w, "Клиент не найден", http.StatusBadRequest)
+			return nil, false
+		}
+
+	case data.ClientPhone != "" || data.ClientEmail != "":
+		client, err = h.clients.FindOrCreate(r.Context(), &models.Client{
+			UserID: ownerID,
+			Name:   data.ClientName,
+			Phone:  data.ClientPhone,
+			Email:  data.ClientEmail,
+		})
+		if err != nil {
+			httputils.SendError(w, "Ошибка при сохранении клиента", http.StatusInternalServerError)
+			return nil, false
+		}
+
+	default:
+		return nil, true
+	}
+
+	if data.ClientName == "" {
+		data.ClientName = client.Name
+	}
+	if data.ClientPhone == "" {
+		data.ClientPhone = client.Phone
+	}
+	if data.ClientEmail == "" {
+		data.ClientEmail = client.Email
+	}
+
+	return &client.ID, true
+}
+
+// Запись по {id} из пути, доступная текущему пользователю.
+// Чужие записи неотличимы от несуществующих. При ошибке сам отправляет ответ
+func (h *AppointmentHandlers) getAppointment(w http.ResponseWriter, r *http.Request) (*models.Appointment, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	appointment, err := h.repository.GetById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении записи", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if appointment == nil || !claims.CanAccess(appointment.UserID) {
+		httputils.SendError(w, "Запись не найдена", http.StatusNotFound)
+		return nil, false
+	}
+
+	return appointment, true
+}
+
+// Пересечение с другой записью сотрудника или ресурса или нет мест в занятии - 409,
+// остальные ошибки - 500
+func (h *AppointmentHandlers) sendSaveError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, consts.ErrConflict) {
+		httputils.SendError(w, "Сотрудник или ресурс уже заняты в это время", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, consts.ErrNoSeats) {
+		httputils.SendError(w, "В занятии нет свободных мест", http.StatusConflict)
+		return
+	}
+	httputils.SendError(w, message, http.StatusInternalServerError)
+}
+
+func applyData(appointment *models.Appointment, data *appointmentData) {
+	appointment.EmployeeID = data.EmployeeID
+	appointment.SectionID = data.SectionID
+	appointment.ClientName = data.ClientName
+	appointment.ClientPhone = data.ClientPhone
+	appointment.ClientEmail = data.ClientEmail
+	appointment.StartAt = data.StartAt.UTC()
+	appointment.EndAt = data.EndAt.UTC()
+	appointment.Comment = data.Comment
+}
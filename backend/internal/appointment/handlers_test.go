@@ -0,0 +1,37 @@
+package appointment
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"record-services/pkg/consts"
+	"strings"
+	"testing"
+)
+
+func TestSendSaveError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		// Часть текста ошибки
+		wantMessage string
+	}{
+		{"сотрудник или ресурс заняты", consts.ErrConflict, http.StatusConflict, "уже заняты"},
+		{"нет мест в занятии", consts.ErrNoSeats, http.StatusConflict, "нет свободных мест"},
+		{"обернутая ошибка", fmt.Errorf("запись на место: %w", consts.ErrNoSeats), http.StatusConflict, "нет свободных мест"},
+		{"ошибка БД", errors.New("соединение закрыто"), http.StatusInternalServerError, "Ошибка при создании записи"},
+	}
+
+	h := &AppointmentHandlers{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.sendSaveError(w, tt.err, "Ошибка при создании записи")
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantMessage) {
+				t.Fatalf("ответ %d %s, ожидался %d с %q", w.Code, w.Body.String(), tt.wantStatus, tt.wantMessage)
+			}
+		})
+	}
+}
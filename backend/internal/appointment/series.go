@@ -0,0 +1,532 @@
+package appointment
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"record-services/internal/availability"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/pkg/httputils"
+	"record-services/pkg/rrule"
+	"time"
+)
+
+// Вхождения серии создаются сразу все, поэтому правило должно быть
+// конечным, заканчиваться не дальше года от текущего момента
+// и давать не больше ограничения будущих вхождений
+const (
+	maxSeriesOccurrences = 200
+	seriesHorizon        = 366 * 24 * time.Hour
+)
+
+const (
+	seriesScopeThis      = "this"
+	seriesScopeFollowing = "following"
+	seriesScopeAll       = "all"
+)
+
+type seriesOptions struct {
+	// Сохранить серию без конфликтующих вхождений. Иначе при конфликтах
+	// ничего не сохраняется, а конфликты возвращаются с кодом 409
+	SkipConflicts bool `json:"skipConflicts"`
+	// Только рассчитать вхождения и конфликты, ничего не сохраняя
+	DryRun bool `json:"dryRun"`
+}
+
+// Параметры первого вхождения и правило повторения. Окно онлайн-записи
+// услуги к сериям не применяется
+type seriesData struct {
+	appointmentData
+	seriesOptions
+	RRule string `json:"rrule" validate:"required,max=255"`
+}
+
+// this - только вхождение appointmentId, как при изменении обычной записи.
+// following - вхождение и следующие: серия разделяется, startAt задает
+// новое время этого вхождения. all - будущие вхождения всей серии,
+// startAt задает новое первое вхождение
+type seriesUpdateData struct {
+	appointmentData
+	seriesOptions
+	Scope         string `json:"scope" validate:"required,oneof=this following all"`
+	AppointmentID uint   `json:"appointmentId" validate:"required_unless=Scope all"`
+	// Пусто - правило серии не меняется
+	RRule string `json:"rrule" validate:"max=255"`
+}
+
+type seriesConflict struct {
+	StartAt time.Time                   `json:"start_at"`
+	EndAt   time.Time                   `json:"end_at"`
+	Reason  availability.ConflictReason `json:"reason"`
+}
+
+type seriesResponse struct {
+	Series       *models.AppointmentSeries    `json:"series"`
+	Appointments []models.AppointmentResponse `json:"appointments"`
+	Conflicts    []seriesConflict             `json:"conflicts"`
+}
+
+func (h *AppointmentHandlers) createSeries(w http.ResponseWriter, r *http.Request) {
+	var data seriesData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	rule, err := rrule.Parse(data.RRule)
+	if err != nil {
+		httputils.SendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !seriesRuleAllowed(w, rule) {
+		return
+	}
+
+	target, ok := h.resolveTarget(w, r, &data.appointmentData, nil)
+	if !ok || !seriesAllowed(w, target) {
+		return
+	}
+
+	clientID, ok := h.resolveClient(w, r, &data.appointmentData, target.employee.UserID)
+	if !ok {
+		return
+	}
+
+	series := &models.AppointmentSeries{}
+	applySeriesData(series, target, &data.appointmentData, clientID, rule)
+
+	times, ok := expandSeries(rule, series.LocalStart(), time.Now(), nil)
+	if !ok {
+		sendSeriesTooLong(w)
+		return
+	}
+	occurrences := seriesOccurrences(series, target, times)
+
+	h.commitSeries(w, r, series, target, occurrences, nil, data.seriesOptions, http.StatusCreated,
+		func(occurrences []models.Appointment) error {
+			return h.series.Create(r.Context(), series, occurrences)
+		})
+}
+
+func (h *AppointmentHandlers) getSeriesWithOccurrences(w http.ResponseWriter, r *http.Request) {
+	series, ok := h.getSeries(w, r)
+	if !ok {
+		return
+	}
+
+	occurrences, err := h.series.GetOccurrences(r.Context(), series.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении записей серии", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, seriesResponse{
+		Series:       series,
+		Appointments: toResponses(occurrences),
+		Conflicts:    []seriesConflict{},
+	})
+}
+
+// Прошедшие, завершенные, отмененные вхождения и исключения сохраняются,
+// остальные будущие вхождения пересоздаются по новым параметрам
+func (h *AppointmentHandlers) updateSeries(w http.ResponseWriter, r *http.Request) {
+	var data seriesUpdateData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	series, ok := h.getSeries(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := h.series.GetOccurrences(r.Context(), series.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении записей серии", http.StatusInternalServerError)
+		return
+	}
+
+	var occurrence *models.Appointment
+	if data.Scope != seriesScopeAll {
+		for i := range existing {
+			if existing[i].ID == data.AppointmentID {
+				occurrence = &existing[i]
+				break
+			}
+		}
+		if occurrence == nil || occurrence.OccurrenceAt == nil {
+			httputils.SendError(w, "Запись не относится к серии", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if data.Scope == seriesScopeThis {
+		h.updateAppointment(w, r, occurrence, &data.appointmentData)
+		return
+	}
+
+	currentRule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при разборе правила серии", http.StatusInternalServerError)
+		return
+	}
+
+	rule := currentRule
+	if data.RRule != "" {
+		if rule, err = rrule.Parse(data.RRule); err != nil {
+			httputils.SendError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if !seriesRuleAllowed(w, rule) {
+		return
+	}
+
+	now := time.Now()
+	from := now
+	split := false
+	if data.Scope == seriesScopeFollowing {
+		if !occurrence.OccurrenceAt.After(now) {
+			httputils.SendError(w, "Прошедшие вхождения серии изменить нельзя", http.StatusConflict)
+			return
+		}
+		from = *occurrence.OccurrenceAt
+		// Изменение с первого вхождения затрагивает всю серию
+		split = from.After(series.StartAt)
+	}
+
+	target, ok := h.resolveTarget(w, r, &data.appointmentData, nil)
+	if !ok || !seriesAllowed(w, target) {
+		return
+	}
+
+	clientID, ok := h.resolveClient(w, r, &data.appointmentData, target.employee.UserID)
+	if !ok {
+		return
+	}
+
+	updated := series
+	if split {
+		// Правило с COUNT продолжает оставшимся числом вхождений
+		if data.RRule == "" && currentRule.Count > 0 {
+			before := len(currentRule.Expand(series.LocalStart(), from.Add(-time.Second), currentRule.Count))
+			remaining := *currentRule
+			remaining.Count = max(currentRule.Count-before, 1)
+			rule = &remaining
+		}
+
+		series.RRule = currentRule.EndingAt(from.Add(-time.Second)).String()
+		updated = &models.AppointmentSeries{}
+	} else if rule.Count > 0 {
+		// COUNT - число вхождений всей серии, прошедшие в него входят
+		past := 0
+		for _, existingOccurrence := range existing {
+			if existingOccurrence.OccurrenceAt != nil && existingOccurrence.OccurrenceAt.Before(from) {
+				past++
+			}
+		}
+		if past >= rule.Count {
+			httputils.SendError(w, "В серии нет будущих вхождений", http.StatusBadRequest)
+			return
+		}
+		remaining := *rule
+		remaining.Count -= past
+		rule = &remaining
+	}
+	applySeriesData(updated, target, &data.appointmentData, clientID, rule)
+
+	// Заменяемые вхождения не считаются занятостью, время остальных
+	// не занимается повторно
+	var replaced []uint
+	replacedStatus := make(map[int64]models.AppointmentStatus)
+	kept := make(map[int64]bool)
+	for _, existingOccurrence := range existing {
+		if existingOccurrence.OccurrenceAt == nil || existingOccurrence.OccurrenceAt.Before(from) {
+			continue
+		}
+		if existingOccurrence.Status.IsActive() && !existingOccurrence.SeriesException {
+			replaced = append(replaced, existingOccurrence.ID)
+			if existingOccurrence.EmployeeID == updated.EmployeeID {
+				replacedStatus[existingOccurrence.StartAt.Unix()] = existingOccurrence.Status
+			}
+			continue
+		}
+		kept[existingOccurrence.OccurrenceAt.Unix()] = true
+	}
+
+	times, ok := expandSeries(rule, updated.LocalStart(), now, kept)
+	if !ok {
+		sendSeriesTooLong(w)
+		return
+	}
+	occurrences := seriesOccurrences(updated, target, times)
+
+	// Подтвержденные вхождения, время которых не изменилось, остаются подтвержденными
+	for i := range occurrences {
+		if status, ok := replacedStatus[occurrences[i].StartAt.Unix()]; ok {
+			occurrences[i].Status = status
+		}
+	}
+
+	var next *models.AppointmentSeries
+	if split {
+		next = updated
+	}
+
+	h.commitSeries(w, r, updated, target, occurrences, replaced, data.seriesOptions, http.StatusOK,
+		func(occurrences []models.Appointment) error {
+			return h.series.Replace(r.Context(), series, next, from, occurrences)
+		})
+}
+
+// Отмена вхождения appointmentId и следующих, без него - всех будущих
+// вхождений. Правило серии заканчивается перед первым отмененным вхождением
+func (h *AppointmentHandlers) cancelSeries(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		AppointmentID uint `json:"appointmentId"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	series, ok := h.getSeries(w, r)
+	if !ok {
+		return
+	}
+
+	from := time.Now()
+	if data.AppointmentID != 0 {
+		occurrence, err := h.repository.GetById(r.Context(), data.AppointmentID)
+		if err != nil {
+			httputils.SendError(w, "Ошибка при получении записи", http.StatusInternalServerError)
+			return
+		}
+		if occurrence == nil || occurrence.SeriesID == nil || *occurrence.SeriesID != series.ID || occurrence.OccurrenceAt == nil {
+			httputils.SendError(w, "Запись не относится к серии", http.StatusBadRequest)
+			return
+		}
+		// Иначе правило обрезалось бы в прошлом, а отменены были бы только будущие вхождения
+		if occurrence.OccurrenceAt.Before(from) {
+			httputils.SendError(w, "Прошедшие вхождения серии отменить нельзя", http.StatusBadRequest)
+			return
+		}
+		from = *occurrence.OccurrenceAt
+	}
+
+	rule, err := rrule.Parse(series.RRule)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при разборе правила серии", http.StatusInternalServerError)
+		return
+	}
+
+	if from.After(series.StartAt) {
+		series.RRule = rule.EndingAt(from.Add(-time.Second)).String()
+	}
+
+	occurrences, err := h.series.GetOccurrences(r.Context(), series.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении записей серии", http.StatusInternalServerError)
+		return
+	}
+
+	cancelled, err := h.series.Cancel(r.Context(), series, from)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при отмене серии", http.StatusInternalServerError)
+		return
+	}
+
+	for _, occurrence := range occurrences {
+		if occurrence.Status.IsActive() && occurrence.OccurrenceAt != nil && !occurrence.OccurrenceAt.Before(from) {
+			h.waitlist.Released(occurrence)
+			h.reminders.Cancel(r.Context(), occurrence.ID)
+		}
+	}
+
+	h.logger.Info().Msgf("В серии %d отменено вхождений: %d", series.ID, cancelled)
+
+	httputils.SendJSONResponse(w, map[string]any{
+		"series":    series,
+		"cancelled": cancelled,
+	})
+}
+
+// Проверяет вхождения на конфликты и сохраняет свободные через save.
+// При dryRun и при конфликтах без skipConflicts только отправляет расчет.
+// excludeIDs - заменяемые вхождения
+func (h *AppointmentHandlers) commitSeries(w http.ResponseWriter, r *http.Request, series *models.AppointmentSeries, target *bookingTarget, occurrences []models.Appointment, excludeIDs []uint, options seriesOptions, status int, save func([]models.Appointment) error) {
+	if len(occurrences) == 0 {
+		httputils.SendError(w, "В серии нет будущих вхождений", http.StatusBadRequest)
+		return
+	}
+
+	intervals := make([]availability.Interval, len(occurrences))
+	for i, occurrence := range occurrences {
+		intervals[i] = availability.Interval{Start: occurrence.StartAt, End: occurrence.EndAt}
+	}
+
+	reasons, err := h.engine.Conflicts(r.Context(), target.employee.UserID, target.employee.ID, intervals, target.terms, excludeIDs)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при проверке занятости сотрудника", http.StatusInternalServerError)
+		return
+	}
+
+	free := make([]models.Appointment, 0, len(occurrences))
+	conflicts := make([]seriesConflict, 0, len(reasons))
+	for i, occurrence := range occurrences {
+		if reason, ok := reasons[i]; ok {
+			conflicts = append(conflicts, seriesConflict{StartAt: occurrence.StartAt, EndAt: occurrence.EndAt, Reason: reason})
+			continue
+		}
+		free = append(free, occurrence)
+	}
+
+	response := seriesResponse{Series: series, Appointments: toResponses(free), Conflicts: conflicts}
+
+	if options.DryRun {
+		httputils.SendJSONResponse(w, response)
+		return
+	}
+
+	if len(conflicts) > 0 && (!options.SkipConflicts || len(free) == 0) {
+		httputils.SendJSONWithStatus(w, response, http.StatusConflict)
+		return
+	}
+
+	if err := save(free); err != nil {
+		h.sendSaveError(w, err, "Ошибка при сохранении серии")
+		return
+	}
+
+	// Напоминания заменяемых вхождений отменяются, новым - планируются
+	for _, id := range excludeIDs {
+		h.reminders.Cancel(r.Context(), id)
+	}
+	h.reminders.ScheduleAll(r.Context(), free)
+
+	response.Appointments = toResponses(free)
+	httputils.SendJSONWithStatus(w, response, status)
+}
+
+// Серия по {id} из пути, доступная текущему пользователю.
+// При ошибке сам отправляет ответ
+func (h *AppointmentHandlers) getSeries(w http.ResponseWriter, r *http.Request) (*models.AppointmentSeries, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	series, err := h.series.GetById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении серии", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if series == nil || !claims.CanAccess(series.UserID) {
+		httputils.SendError(w, "Серия не найдена", http.StatusNotFound)
+		return nil, false
+	}
+
+	return series, true
+}
+
+// Вхождения серии сохраняются как обычные записи, мест в групповых
+// занятиях они не занимают. При ошибке сам отправляет ответ
+func seriesAllowed(w http.ResponseWriter, target *bookingTarget) bool {
+	if target.section.IsGroup() {
+		httputils.SendError(w, "Серии записей на групповые услуги не поддерживаются", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// Бесконечное правило развернуть заранее нельзя. При ошибке сам отправляет ответ
+func seriesRuleAllowed(w http.ResponseWriter, rule *rrule.Rule) bool {
+	if !rule.IsFinite() {
+		httputils.SendError(w, "Правило серии должно содержать COUNT или UNTIL", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func sendSeriesTooLong(w http.ResponseWriter) {
+	httputils.SendError(w, fmt.Sprintf("Серия должна заканчиваться не позже чем через год и содержать не больше %d будущих вхождений",
+		maxSeriesOccurrences), http.StatusBadRequest)
+}
+
+// Время вхождений конечного правила с первым вхождением start, которые
+// еще не начались к now и не заняты сохраненными вхождениями kept.
+// Прошедшие вхождения в ограничение не входят. false - правило
+// не укладывается в горизонт или ограничение числа вхождений
+func expandSeries(rule *rrule.Rule, start, now time.Time, kept map[int64]bool) ([]time.Time, bool) {
+	limit := now.Add(seriesHorizon)
+	if !rule.IsFinite() || rule.Until.After(limit) {
+		return nil, false
+	}
+
+	maxCount := rule.Count
+	if maxCount == 0 {
+		maxCount = math.MaxInt
+	}
+	expanded := rule.Expand(start, limit, maxCount)
+	// Правило с COUNT, оборванное горизонтом
+	if rule.Count > 0 && len(expanded) < rule.Count {
+		return nil, false
+	}
+
+	times := make([]time.Time, 0)
+	for _, occurrence := range expanded {
+		if occurrence.Before(now) || kept[occurrence.Unix()] {
+			continue
+		}
+		if len(times) == maxSeriesOccurrences {
+			return nil, false
+		}
+		times = append(times, occurrence)
+	}
+	return times, true
+}
+
+func seriesOccurrences(series *models.AppointmentSeries, target *bookingTarget, times []time.Time) []models.Appointment {
+	occurrences := make([]models.Appointment, len(times))
+	for i := range times {
+		start := times[i].UTC()
+		occurrence := &occurrences[i]
+		occurrence.Status = models.AppointmentStatusPending
+		occurrence.ClientID = series.ClientID
+		occurrence.ClientName = series.ClientName
+		occurrence.ClientPhone = series.ClientPhone
+		occurrence.ClientEmail = series.ClientEmail
+		occurrence.StartAt = start
+		occurrence.EndAt = start.Add(series.Duration())
+		occurrence.OccurrenceAt = &start
+		occurrence.Comment = series.Comment
+		target.apply(occurrence)
+	}
+	return occurrences
+}
+
+func applySeriesData(series *models.AppointmentSeries, target *bookingTarget, data *appointmentData, clientID *uint, rule *rrule.Rule) {
+	series.UserID = target.employee.UserID
+	series.EmployeeID = target.employee.ID
+	series.SectionID = target.section.ID
+	series.ClientID = clientID
+	series.ClientName = data.ClientName
+	series.ClientPhone = data.ClientPhone
+	series.ClientEmail = data.ClientEmail
+	series.RRule = rule.String()
+	series.StartAt = data.StartAt.UTC()
+	series.TimeZone = target.location.String()
+	series.DurationMinutes = int(data.EndAt.Sub(data.StartAt) / time.Minute)
+	series.Comment = data.Comment
+}
+
+func toResponses(appointments []models.Appointment) []models.AppointmentResponse {
+	responses := make([]models.AppointmentResponse, len(appointments))
+	for i := range appointments {
+		responses[i] = appointments[i].ToResponse()
+	}
+	return responses
+}
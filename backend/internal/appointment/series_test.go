@@ -0,0 +1,78 @@
+package appointment
+
+import (
+	"record-services/pkg/rrule"
+	"testing"
+	"time"
+)
+
+func TestExpandSeries(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 9, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		value string
+		start time.Time
+		kept  map[int64]bool
+		// Дни марта будущих вхождений, nil - правило отклоняется
+		want []int
+	}{
+		{"будущие вхождения", "FREQ=DAILY;COUNT=3", day(11), nil, []int{11, 12, 13}},
+		{"прошедшие пропускаются и входят в COUNT", "FREQ=DAILY;COUNT=4", day(8), nil, []int{11}},
+		{"сохраненные пропускаются", "FREQ=DAILY;UNTIL=20260314T235959Z", day(11),
+			map[int64]bool{day(12).Unix(): true}, []int{11, 13, 14}},
+		{"бесконечное правило", "FREQ=DAILY", day(11), nil, nil},
+		{"UNTIL дальше горизонта", "FREQ=WEEKLY;UNTIL=20270601T000000Z", day(11), nil, nil},
+		{"COUNT дальше горизонта", "FREQ=WEEKLY;COUNT=60", day(11), nil, nil},
+		{"больше ограничения", "FREQ=DAILY;COUNT=201", day(11), nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := rrule.Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			times, ok := expandSeries(rule, tt.start, now, tt.kept)
+			if tt.want == nil {
+				if ok {
+					t.Fatalf("правило принято, получено %d вхождений", len(times))
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("правило отклонено")
+			}
+
+			if len(times) != len(tt.want) {
+				t.Fatalf("получено %v, ожидались дни %v", times, tt.want)
+			}
+			for i, d := range tt.want {
+				if !times[i].Equal(day(d)) {
+					t.Fatalf("получено %v, ожидались дни %v", times, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandSeriesPastDoNotCount(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	// Серия началась год назад: прошедшие вхождения не расходуют ограничение
+	start := now.AddDate(-1, 0, 0)
+
+	rule, err := rrule.Parse("FREQ=DAILY;UNTIL=20260319T000000Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	times, ok := expandSeries(rule, start, now, nil)
+	if !ok {
+		t.Fatal("правило отклонено")
+	}
+	if len(times) != 9 {
+		t.Fatalf("получено %d вхождений, ожидалось 9", len(times))
+	}
+}
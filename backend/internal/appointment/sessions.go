@@ -0,0 +1,150 @@
+package appointment
+
+import (
+	"errors"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"time"
+)
+
+// Период списка занятий по умолчанию и наибольший
+const sessionsRange = 31 * 24 * time.Hour
+
+// Групповые занятия. Фильтры: section_id, employee_id, from/to в RFC3339 -
+// начало занятия, по умолчанию ближайший месяц
+func (h *AppointmentHandlers) listSessions(w http.ResponseWriter, r *http.Request) {
+	from, err := httputils.QueryTime(r, "from")
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата from", http.StatusBadRequest)
+		return
+	}
+
+	to, err := httputils.QueryTime(r, "to")
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата to", http.StatusBadRequest)
+		return
+	}
+
+	if from == nil {
+		now := time.Now()
+		from = &now
+	}
+	if to == nil {
+		end := from.Add(sessionsRange)
+		to = &end
+	}
+
+	if !to.After(*from) || to.Sub(*from) > sessionsRange {
+		httputils.SendError(w, "Некорректный период", http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	filter := models.GroupSessionFilter{
+		UserID:    claims.OwnerScope(),
+		SectionID: uint(max(httputils.QueryInt(r, "section_id", 0), 0)),
+		From:      from,
+		To:        to,
+	}
+	if employeeID := httputils.QueryInt(r, "employee_id", 0); employeeID > 0 {
+		filter.EmployeeIDs = []uint{uint(employeeID)}
+	}
+
+	sessions, err := h.sessions.GetAll(r.Context(), filter)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении занятий", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]models.GroupSessionResponse, len(sessions))
+	for i := range sessions {
+		responses[i] = sessions[i].ToResponse()
+	}
+
+	httputils.SendJSONResponse(w, responses)
+}
+
+// Занятие со списком записавшихся
+func (h *AppointmentHandlers) getSessionWithAttendees(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.getSession(w, r)
+	if !ok {
+		return
+	}
+
+	h.sendSession(w, r, session)
+}
+
+// Изменение числа мест в занятии. Меньше, чем уже записано, нельзя
+func (h *AppointmentHandlers) updateSession(w http.ResponseWriter, r *http.Request) {
+	var sessionData struct {
+		Capacity int `json:"capacity" validate:"required,min=1,max=500"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &sessionData) {
+		return
+	}
+
+	session, ok := h.getSession(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.sessions.UpdateCapacity(r.Context(), session, sessionData.Capacity); err != nil {
+		if errors.Is(err, consts.ErrConflict) {
+			httputils.SendError(w, "На занятие записано больше клиентов, чем мест", http.StatusConflict)
+			return
+		}
+		httputils.SendError(w, "Ошибка при изменении занятия", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Вместимость занятия %d изменена на %d", session.ID, session.Capacity)
+
+	h.sendSession(w, r, session)
+}
+
+func (h *AppointmentHandlers) sendSession(w http.ResponseWriter, r *http.Request, session *models.GroupSession) {
+	attendees, err := h.sessions.GetAttendees(r.Context(), session.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении записей занятия", http.StatusInternalServerError)
+		return
+	}
+
+	response := session.ToResponse()
+	response.Attendees = make([]models.AppointmentResponse, len(attendees))
+	for i := range attendees {
+		attendees[i].Employee = session.Employee
+		attendees[i].Section = session.Section
+		response.Attendees[i] = attendees[i].ToResponse()
+	}
+
+	httputils.SendJSONResponse(w, response)
+}
+
+// Занятие по {id} из пути, доступное текущему пользователю.
+// При ошибке сам отправляет ответ
+func (h *AppointmentHandlers) getSession(w http.ResponseWriter, r *http.Request) (*models.GroupSession, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	session, err := h.sessions.GetById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении занятия", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if session == nil || !claims.CanAccess(session.UserID) {
+		httputils.SendError(w, "Занятие не найдено", http.StatusNotFound)
+		return nil, false
+	}
+
+	return session, true
+}
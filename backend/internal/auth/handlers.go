@@ -4,44 +4,83 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"net/http"
+	"record-services/internal/config"
 	"record-services/internal/models"
+	"record-services/internal/repositories/recovery_code_repository"
+	"record-services/internal/repositories/refresh_token_repository"
+	"record-services/internal/repositories/revocation_repository"
 	"record-services/internal/repositories/user_repository"
+	"record-services/internal/repositories/user_token_repository"
 	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/mailer"
+	"record-services/pkg/password"
+	"record-services/pkg/ratelimit"
 	"record-services/pkg/utils"
-	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog"
 )
 
-// Константы для кодов ошибок и сообщений
-const (
-	tokenExpiration = 24 * time.Hour
-	cookieMaxAge    = 3600
-)
+// Refresh cookie отправляется браузером только на эндпоинты авторизации
+const refreshCookiePath = "/api/auth"
 
 type AuthHandlers struct {
-	mux        *http.ServeMux
-	logger     *zerolog.Logger
-	repository user_repository.UserRepository
-	validator  *validator.Validate
-	hashSecret string
-	JwtSecret  string
+	mux           *http.ServeMux
+	logger        *zerolog.Logger
+	repository    user_repository.UserRepository
+	refreshTokens refresh_token_repository.RefreshTokenRepository
+	revocations   revocation_repository.RevocationRepository
+	userTokens    user_token_repository.UserTokenRepository
+	recoveryCodes recovery_code_repository.RecoveryCodeRepository
+	validator     *validator.Validate
+	passwords     *password.Service
+	mailer        mailer.Mailer
+	tokenConfig   config.TokenConfig
+	appURL        string
+	JwtSecret     string
 }
 
-func NewAuthHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository user_repository.UserRepository, validator *validator.Validate, hashSecret string, jwtSecret string) *AuthHandlers {
+func NewAuthHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository user_repository.UserRepository, refreshTokens refresh_token_repository.RefreshTokenRepository, revocations revocation_repository.RevocationRepository, userTokens user_token_repository.UserTokenRepository, recoveryCodes recovery_code_repository.RecoveryCodeRepository, validator *validator.Validate, passwords *password.Service, mailer mailer.Mailer, limiterStore ratelimit.Store, tokenConfig config.TokenConfig, appURL string, jwtSecret string) *AuthHandlers {
 	authHandlers := &AuthHandlers{
-		mux:        mux,
-		logger:     logger,
-		repository: repository,
-		validator:  validator,
-		hashSecret: hashSecret,
-		JwtSecret:  jwtSecret,
+		mux:           mux,
+		logger:        logger,
+		repository:    repository,
+		refreshTokens: refreshTokens,
+		revocations:   revocations,
+		userTokens:    userTokens,
+		recoveryCodes: recoveryCodes,
+		validator:     validator,
+		passwords:     passwords,
+		mailer:        mailer,
+		tokenConfig:   tokenConfig,
+		appURL:        appURL,
+		JwtSecret:     jwtSecret,
 	}
 
-	authHandlers.mux.HandleFunc("POST /api/auth/register", authHandlers.register)
-	authHandlers.mux.HandleFunc("POST /api/auth/login", authHandlers.login)
+	limits := newAuthLimits(limiterStore)
+
+	authHandlers.mux.Handle("POST /api/auth/register", limits.register(http.HandlerFunc(authHandlers.register)))
+	authHandlers.mux.Handle("POST /api/auth/login", limits.login(http.HandlerFunc(authHandlers.login)))
+	authHandlers.mux.Handle("POST /api/auth/verify-email", limits.token(http.HandlerFunc(authHandlers.verifyEmail)))
+	authHandlers.mux.Handle("POST /api/auth/resend-verification", limits.email(http.HandlerFunc(authHandlers.resendVerification)))
+	authHandlers.mux.Handle("POST /api/auth/forgot-password", limits.email(http.HandlerFunc(authHandlers.forgotPassword)))
+	authHandlers.mux.Handle("POST /api/auth/reset-password", limits.token(http.HandlerFunc(authHandlers.resetPassword)))
+	authHandlers.mux.Handle("POST /api/auth/change-password", limits.token(http.HandlerFunc(authHandlers.changePassword)))
+	authHandlers.mux.Handle("POST /api/auth/login/mfa", limits.mfa(http.HandlerFunc(authHandlers.loginMFA)))
+	authHandlers.mux.HandleFunc("GET /api/auth/mfa", authHandlers.mfaStatus)
+	authHandlers.mux.HandleFunc("POST /api/auth/mfa/setup", authHandlers.mfaSetup)
+	authHandlers.mux.HandleFunc("POST /api/auth/mfa/enable", authHandlers.mfaEnable)
+	authHandlers.mux.HandleFunc("POST /api/auth/mfa/disable", authHandlers.mfaDisable)
+	authHandlers.mux.HandleFunc("POST /api/auth/mfa/recovery-codes", authHandlers.mfaRegenerateRecoveryCodes)
+	authHandlers.mux.Handle("POST /api/auth/confirm-email", limits.token(http.HandlerFunc(authHandlers.confirmEmailChange)))
+	authHandlers.mux.HandleFunc("GET /api/auth/me", authHandlers.getMe)
+	authHandlers.mux.HandleFunc("PATCH /api/auth/me", authHandlers.updateMe)
+	authHandlers.mux.HandleFunc("POST /api/auth/refresh", authHandlers.refresh)
 	authHandlers.mux.HandleFunc("POST /api/auth/logout", authHandlers.logout)
+	authHandlers.mux.HandleFunc("POST /api/auth/logout-all", authHandlers.logoutAll)
+	authHandlers.mux.HandleFunc("GET /api/auth/sessions", authHandlers.listSessions)
+	authHandlers.mux.HandleFunc("DELETE /api/auth/sessions/{id}", authHandlers.revokeSession)
 
 	return authHandlers
 }
@@ -52,6 +91,8 @@ func (h *AuthHandlers) register(w http.ResponseWriter, r *http.Request) {
 		Email          string `json:"email" validate:"required,email,max=255"`
 		Password       string `json:"password" validate:"required,min=6,max=15"`
 		PasswordRepeat string `json:"passwordRepeat" validate:"required,min=6,max=15"`
+		// Не указан - UTC, меняется в профиле
+		TimeZone string `json:"timeZone" validate:"omitempty,timezone,max=64"`
 	}
 
 	if !h.decodeAndValidate(w, r, &registerData) {
@@ -77,21 +118,41 @@ func (h *AuthHandlers) register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	passwordHash, err := h.passwords.Hash(registerData.Password)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при хешировании пароля: %s", registerData.Email)
+		h.sendError(w, "Ошибка при регистрации", http.StatusInternalServerError)
+		return
+	}
+
 	newUser := &models.User{
 		Name:         registerData.Name,
 		Email:        registerData.Email,
-		PasswordHash: utils.CreateHash(registerData.Password, h.hashSecret),
+		PasswordHash: passwordHash,
 		IsActive:     false,
 		IsAdmin:      false,
+		TimeZone:     registerData.TimeZone,
+		// Активирует администратор после подтверждения email
+		ApprovalStatus: models.ApprovalStatusPending,
 	}
 
-	if _, err := h.repository.Create(newUser); err != nil {
+	// Зарегистрировавшийся сам управляет своей организацией
+	if _, err := h.repository.Create(newUser, consts.RoleOwner); err != nil {
 		h.logger.Error().Err(err).Msgf("Ошибка при создании пользователя: %s", registerData.Email)
 		h.sendError(w, "Ошибка при регистрации", http.StatusInternalServerError)
 		return
 	}
 
-	h.sendJSONResponse(w, map[string]string{"status": "ok"})
+	// Пользователь создан, ошибку отправки письма не показываем:
+	// письмо можно запросить повторно
+	if err := h.sendVerificationEmail(r.Context(), newUser); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отправке письма подтверждения: %s", newUser.Email)
+	}
+
+	h.sendJSONResponse(w, map[string]string{
+		"status":  "ok",
+		"message": "На указанный email отправлено письмо для подтверждения",
+	})
 }
 
 func (h *AuthHandlers) login(w http.ResponseWriter, r *http.Request) {
@@ -111,23 +172,53 @@ func (h *AuthHandlers) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if user == nil || !utils.VerifyHash(loginData.Password, user.PasswordHash, h.hashSecret) {
+	if user == nil {
 		h.sendError(w, "Неверный логин или пароль", http.StatusUnauthorized)
 		return
 	}
 
+	if h.rejectLocked(w, user, "Неверный логин или пароль") {
+		return
+	}
+
+	passwordOk, needsRehash, err := h.passwords.Verify(loginData.Password, user.PasswordHash)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при проверке пароля: %s", loginData.Email)
+		h.sendError(w, "Ошибка при авторизации", http.StatusInternalServerError)
+		return
+	}
+
+	if !passwordOk {
+		h.registerFailedLogin(user)
+		h.sendError(w, "Неверный логин или пароль", http.StatusUnauthorized)
+		return
+	}
+
+	h.resetFailedLogins(user)
+
+	// Пароль верный - переводим хеш на текущий алгоритм
+	if needsRehash {
+		h.rehashPassword(user, loginData.Password)
+	}
+
 	if !user.IsActive {
-		h.sendError(w, "Пользователь не активный, обратитесь к администратору", http.StatusUnauthorized)
+		h.sendError(w, inactiveUserMessage(user), http.StatusUnauthorized)
 		return
 	}
 
-	// Создаем токен
-	token, err := utils.CreateToken(utils.UserClaims{
-		ID:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
-	}, []byte(h.JwtSecret))
+	// Включен второй фактор: токены выдадим после проверки кода
+	if user.TOTPEnabled {
+		h.sendMFAChallenge(w, user)
+		return
+	}
 
+	h.completeLogin(w, r, user)
+}
+
+// Завершает вход: выдает токены и устанавливает cookies
+func (h *AuthHandlers) completeLogin(w http.ResponseWriter, r *http.Request, user *models.User) {
+	// Новый логин - новое семейство refresh токенов
+	tokens, err := h.issueTokens(r, user, "")
 	if err != nil {
 		h.logger.Error().Err(err).Msgf("Ошибка при создании токена: %s", user.Email)
 		h.sendError(w, "Ошибка при авторизации", http.StatusInternalServerError)
@@ -135,63 +226,123 @@ func (h *AuthHandlers) login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Устанавливаем cookies
-	h.setAuthCookies(w, token, user)
+	h.setAuthCookies(w, tokens, user)
 
 	h.sendJSONResponse(w, map[string]string{
-		"status": "ok",
-		"token":  token,
-		"user":   user.Name, // Добавляем информацию о пользователе
+		"status":       "ok",
+		"token":        tokens.accessToken,
+		"refreshToken": tokens.refreshToken,
+		"user":         user.Name, // Добавляем информацию о пользователе
 	})
 }
 
+// Выход не требует действующего access токена: сессия находится по refresh токену
 func (h *AuthHandlers) logout(w http.ResponseWriter, r *http.Request) {
+	if refreshToken := h.extractRefreshToken(r); refreshToken != "" {
+		stored, err := h.refreshTokens.GetByHash(utils.HashToken(refreshToken))
+		if err != nil {
+			h.logger.Error().Err(err).Msg("Ошибка при получении refresh токена при выходе")
+		} else if stored != nil {
+			if err := h.revokeSessionByID(stored.FamilyID); err != nil {
+				h.logger.Error().Err(err).Msgf("Ошибка при отзыве сессии: %s", stored.FamilyID)
+			}
+		}
+	}
+
+	claims := h.accessClaims(r)
+	if claims != nil {
+		// Текущий access токен перестает работать сразу, а не по истечении срока
+		if claims.ExpiresAt != nil {
+			if err := h.revocations.RevokeToken(claims.RegisteredClaims.ID, claims.ExpiresAt.Time); err != nil {
+				h.logger.Error().Err(err).Msgf("Ошибка при отзыве токена пользователя: %d", claims.ID)
+			}
+		}
+
+		if err := h.revokeSessionByID(claims.SessionID); err != nil {
+			h.logger.Error().Err(err).Msgf("Ошибка при отзыве сессии: %s", claims.SessionID)
+		}
+	}
+
 	h.clearAuthCookies(w)
 	h.sendJSONResponse(w, map[string]string{"status": "ok"})
 }
 
+// Проверка access токена на отзыв, вызывается из AuthMiddleware
+func (h *AuthHandlers) IsTokenRevoked(claims *utils.UserClaims) bool {
+	return h.revocations.IsRevoked(claims)
+}
+
 // Вспомогательные методы
 
-func (h *AuthHandlers) decodeAndValidate(w http.ResponseWriter, r *http.Request, data interface{}) bool {
-	if err := json.NewDecoder(r.Body).Decode(data); err != nil {
-		h.sendError(w, "Невалидный запрос", http.StatusBadRequest)
-		return false
+func inactiveUserMessage(user *models.User) string {
+	switch {
+	case user.ApprovalStatus == models.ApprovalStatusPending && user.EmailVerifiedAt == nil:
+		return "Email не подтвержден, перейдите по ссылке из письма"
+	case user.ApprovalStatus == models.ApprovalStatusPending:
+		return "Регистрация ожидает одобрения администратором"
+	case user.ApprovalStatus == models.ApprovalStatusRejected:
+		return "Регистрация отклонена администратором"
+	default:
+		return "Пользователь не активный, обратитесь к администратору"
 	}
+}
 
-	if err := h.validator.Struct(data); err != nil {
-		h.sendError(w, "Невалидные данные", http.StatusBadRequest)
-		return false
+// Ошибка пересохранения хеша не мешает входу: попробуем при следующем логине
+func (h *AuthHandlers) rehashPassword(user *models.User, plainPassword string) {
+	passwordHash, err := h.passwords.Hash(plainPassword)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при пересоздании хеша пароля: %s", user.Email)
+		return
+	}
+
+	user.PasswordHash = passwordHash
+	if _, err := h.repository.Update(user); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при сохранении нового хеша пароля: %s", user.Email)
+		return
 	}
 
-	return true
+	h.logger.Info().Msgf("Хеш пароля пользователя %s обновлен", user.Email)
+}
+
+func (h *AuthHandlers) decodeAndValidate(w http.ResponseWriter, r *http.Request, data interface{}) bool {
+	return httputils.DecodeAndValidate(w, r, h.validator, data)
 }
 
 func (h *AuthHandlers) sendError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error":   http.StatusText(statusCode),
-		"message": message,
-	})
+	httputils.SendError(w, message, statusCode)
 }
 
 func (h *AuthHandlers) sendJSONResponse(w http.ResponseWriter, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(data)
+	httputils.SendJSONResponse(w, data)
 }
 
-func (h *AuthHandlers) setAuthCookies(w http.ResponseWriter, token string, user *models.User) {
-    // Токен cookie (без изменений)
+func (h *AuthHandlers) setAuthCookies(w http.ResponseWriter, tokens *issuedTokens, user *models.User) {
+    // Токен cookie живет столько же, сколько access токен
     http.SetCookie(w, &http.Cookie{
         Name:     string(consts.CookieTokenKey),
-        Value:    token,
+        Value:    tokens.accessToken,
         HttpOnly: true,
         Secure:   true,
         SameSite: http.SameSiteStrictMode,
         Path:     "/",
-        MaxAge:   cookieMaxAge,
+        MaxAge:   int(h.tokenConfig.AccessTTL.Seconds()),
+    })
+
+    http.SetCookie(w, &http.Cookie{
+        Name:     string(consts.CookieRefreshKey),
+        Value:    tokens.refreshToken,
+        HttpOnly: true,
+        Secure:   true,
+        SameSite: http.SameSiteStrictMode,
+        Path:     refreshCookiePath,
+        MaxAge:   int(h.tokenConfig.RefreshTTL.Seconds()),
     })
 
+    h.setUserDataCookie(w, user)
+}
+
+// Данные пользователя для отображения во frontend
+func (h *AuthHandlers) setUserDataCookie(w http.ResponseWriter, user *models.User) {
     // User data cookie - используем base64
     userData := map[string]string{
         "name":  user.Name,
@@ -213,7 +364,7 @@ func (h *AuthHandlers) setAuthCookies(w http.ResponseWriter, token string, user
         Secure:   true,
         SameSite: http.SameSiteStrictMode,
         Path:     "/",
-        MaxAge:   cookieMaxAge,
+        MaxAge:   int(h.tokenConfig.RefreshTTL.Seconds()),
     })
 }
 
@@ -234,4 +385,14 @@ func (h *AuthHandlers) clearAuthCookies(w http.ResponseWriter) {
 			MaxAge:   -1,
 		})
 	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     string(consts.CookieRefreshKey),
+		Value:    "",
+		Path:     refreshCookiePath,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   -1,
+	})
 }
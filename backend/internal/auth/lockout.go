@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"net/http"
+	"record-services/internal/models"
+	"time"
+)
+
+const (
+	// Каждые lockoutThreshold неудачных попыток подряд блокируют вход,
+	// каждая следующая блокировка вдвое длиннее предыдущей
+	lockoutThreshold    = 5
+	lockoutBaseDuration = time.Minute
+	lockoutMaxDuration  = 24 * time.Hour
+)
+
+// Отвечает 401 с message, если вход для пользователя заблокирован. Ответ
+// совпадает с ответом на неверные данные: иначе по блокировке можно было бы
+// узнать, что email зарегистрирован. Пароль и код при блокировке не проверяются
+func (h *AuthHandlers) rejectLocked(w http.ResponseWriter, user *models.User, message string) bool {
+	if !user.IsLocked(time.Now()) {
+		return false
+	}
+
+	h.sendError(w, message, http.StatusUnauthorized)
+	return true
+}
+
+// Счетчик увеличивается одним UPDATE: параллельные неверные попытки не
+// теряются и не перезаписывают остальные поля пользователя
+func (h *AuthHandlers) registerFailedLogin(user *models.User) {
+	attempts, err := h.repository.IncrementFailedLogins(user.ID)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при сохранении неудачной попытки входа: %s", user.Email)
+		return
+	}
+	user.FailedLoginAttempts = attempts
+
+	if attempts%lockoutThreshold != 0 {
+		return
+	}
+
+	lockouts := attempts/lockoutThreshold - 1
+	duration := lockoutMaxDuration
+	if lockouts < 12 {
+		duration = min(lockoutBaseDuration<<lockouts, lockoutMaxDuration)
+	}
+
+	lockedUntil := time.Now().Add(duration)
+	if err := h.repository.LockLogin(user.ID, attempts, lockedUntil); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при блокировке входа: %s", user.Email)
+		return
+	}
+	user.LockedUntil = &lockedUntil
+
+	h.logger.Warn().Msgf("Вход пользователя %s заблокирован до %s после %d неудачных попыток",
+		user.Email, lockedUntil.Format(time.RFC3339), attempts)
+}
+
+func (h *AuthHandlers) resetFailedLogins(user *models.User) {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return
+	}
+
+	if err := h.repository.ResetFailedLogins(user.ID); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при сбросе неудачных попыток входа: %s", user.Email)
+		return
+	}
+
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+}
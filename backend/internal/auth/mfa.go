@@ -0,0 +1,408 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/totp"
+	"record-services/pkg/utils"
+	"strings"
+	"time"
+)
+
+const (
+	mfaChallengeTTL   = 5 * time.Minute
+	totpIssuer        = "Record Services"
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 10
+)
+
+// Первый шаг входа пройден, ждем код второго фактора
+func (h *AuthHandlers) sendMFAChallenge(w http.ResponseWriter, user *models.User) {
+	mfaToken, err := utils.CreateMFAToken(user.ID, []byte(h.JwtSecret), mfaChallengeTTL)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при создании MFA токена: %s", user.Email)
+		h.sendError(w, "Ошибка при авторизации", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSONResponse(w, map[string]string{
+		"status":   "mfa_required",
+		"mfaToken": mfaToken,
+	})
+}
+
+func (h *AuthHandlers) loginMFA(w http.ResponseWriter, r *http.Request) {
+	var mfaData struct {
+		MFAToken     string `json:"mfaToken" validate:"required"`
+		Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+		RecoveryCode string `json:"recoveryCode" validate:"required_without=Code,omitempty,max=20"`
+	}
+
+	if !h.decodeAndValidate(w, r, &mfaData) {
+		return
+	}
+
+	claims, err := utils.ParseMFAToken(mfaData.MFAToken, []byte(h.JwtSecret))
+	if err != nil {
+		h.sendError(w, "Время на ввод кода истекло, войдите заново", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.repository.GetById(claims.UserID)
+	if err != nil {
+		h.sendError(w, "Ошибка при авторизации", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil || !user.IsActive || !user.TOTPEnabled {
+		h.sendError(w, "Время на ввод кода истекло, войдите заново", http.StatusUnauthorized)
+		return
+	}
+
+	if h.rejectLocked(w, user, "Неверный код") {
+		return
+	}
+
+	// Токен гасится до проверки кода: повторный или параллельный запрос
+	// с тем же токеном не расходует код восстановления и шаг TOTP
+	jti := claims.RegisteredClaims.ID
+	first, err := h.revocations.UseMFAChallenge(jti, claims.ExpiresAt.Time)
+	if err != nil {
+		h.sendError(w, "Ошибка при авторизации", http.StatusInternalServerError)
+		return
+	}
+
+	if !first {
+		h.sendError(w, "Время на ввод кода истекло, войдите заново", http.StatusUnauthorized)
+		return
+	}
+
+	ok, err := h.verifySecondFactor(user, mfaData.Code, mfaData.RecoveryCode)
+	if err != nil {
+		_ = h.revocations.ReleaseMFAChallenge(jti)
+		h.sendError(w, "Ошибка при авторизации", http.StatusInternalServerError)
+		return
+	}
+
+	// Подбор кода блокируется так же, как подбор пароля. При опечатке
+	// токен возвращается, и пароль вводить заново не нужно
+	if !ok {
+		h.registerFailedLogin(user)
+		_ = h.revocations.ReleaseMFAChallenge(jti)
+		h.sendError(w, "Неверный код", http.StatusUnauthorized)
+		return
+	}
+
+	h.completeLogin(w, r, user)
+}
+
+func (h *AuthHandlers) mfaStatus(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var left int64
+	if user.TOTPEnabled {
+		var err error
+		left, err = h.recoveryCodes.CountUnused(user.ID)
+		if err != nil {
+			h.sendError(w, "Ошибка при получении настроек", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.sendJSONResponse(w, map[string]interface{}{
+		"enabled":           user.TOTPEnabled,
+		"recoveryCodesLeft": left,
+	})
+}
+
+// Генерирует секрет. Второй фактор включится после ввода первого кода
+func (h *AuthHandlers) mfaSetup(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		h.sendError(w, "Двухфакторная аутентификация уже включена", http.StatusConflict)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		h.sendError(w, "Ошибка при настройке двухфакторной аутентификации", http.StatusInternalServerError)
+		return
+	}
+
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if _, err := h.repository.Update(user); err != nil {
+		h.sendError(w, "Ошибка при настройке двухфакторной аутентификации", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSONResponse(w, map[string]string{
+		"secret": secret,
+		"uri":    totp.ProvisioningURI(totpIssuer, user.Email, secret),
+	})
+}
+
+func (h *AuthHandlers) mfaEnable(w http.ResponseWriter, r *http.Request) {
+	var enableData struct {
+		Code string `json:"code" validate:"required,numeric,len=6"`
+	}
+
+	if !h.decodeAndValidate(w, r, &enableData) {
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if user.TOTPEnabled {
+		h.sendError(w, "Двухфакторная аутентификация уже включена", http.StatusConflict)
+		return
+	}
+
+	if user.TOTPSecret == "" {
+		h.sendError(w, "Сначала выполните настройку", http.StatusBadRequest)
+		return
+	}
+
+	step, valid := totp.Validate(user.TOTPSecret, enableData.Code, time.Now(), user.TOTPLastStep)
+	if !valid {
+		h.sendError(w, "Неверный код", http.StatusBadRequest)
+		return
+	}
+
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	if _, err := h.repository.Update(user); err != nil {
+		h.sendError(w, "Ошибка при включении двухфакторной аутентификации", http.StatusInternalServerError)
+		return
+	}
+
+	codes, err := h.generateRecoveryCodes(user.ID)
+	if err != nil {
+		h.sendError(w, "Ошибка при создании кодов восстановления", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Пользователь %s включил двухфакторную аутентификацию", user.Email)
+
+	h.sendJSONResponse(w, map[string]interface{}{
+		"status":        "ok",
+		"recoveryCodes": codes,
+	})
+}
+
+func (h *AuthHandlers) mfaDisable(w http.ResponseWriter, r *http.Request) {
+	var disableData struct {
+		Password     string `json:"password" validate:"required,max=100"`
+		Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,numeric,len=6"`
+		RecoveryCode string `json:"recoveryCode" validate:"required_without=Code,omitempty,max=20"`
+	}
+
+	if !h.decodeAndValidate(w, r, &disableData) {
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !user.TOTPEnabled {
+		h.sendError(w, "Двухфакторная аутентификация не включена", http.StatusConflict)
+		return
+	}
+
+	passwordOk, _, err := h.passwords.Verify(disableData.Password, user.PasswordHash)
+	if err != nil {
+		h.sendError(w, "Ошибка при отключении двухфакторной аутентификации", http.StatusInternalServerError)
+		return
+	}
+
+	if !passwordOk {
+		h.sendError(w, "Неверный пароль", http.StatusBadRequest)
+		return
+	}
+
+	valid, err := h.verifySecondFactor(user, disableData.Code, disableData.RecoveryCode)
+	if err != nil {
+		h.sendError(w, "Ошибка при отключении двухфакторной аутентификации", http.StatusInternalServerError)
+		return
+	}
+
+	if !valid {
+		h.sendError(w, "Неверный код", http.StatusBadRequest)
+		return
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if _, err := h.repository.Update(user); err != nil {
+		h.sendError(w, "Ошибка при отключении двухфакторной аутентификации", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.recoveryCodes.DeleteByUser(user.ID); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при удалении кодов восстановления: %d", user.ID)
+	}
+
+	h.logger.Info().Msgf("Пользователь %s отключил двухфакторную аутентификацию", user.Email)
+
+	h.sendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Новый набор кодов восстановления, старые перестают действовать
+func (h *AuthHandlers) mfaRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	var regenerateData struct {
+		Code string `json:"code" validate:"required,numeric,len=6"`
+	}
+
+	if !h.decodeAndValidate(w, r, &regenerateData) {
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	if !user.TOTPEnabled {
+		h.sendError(w, "Двухфакторная аутентификация не включена", http.StatusConflict)
+		return
+	}
+
+	valid, err := h.verifySecondFactor(user, regenerateData.Code, "")
+	if err != nil {
+		h.sendError(w, "Ошибка при создании кодов восстановления", http.StatusInternalServerError)
+		return
+	}
+
+	if !valid {
+		h.sendError(w, "Неверный код", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.generateRecoveryCodes(user.ID)
+	if err != nil {
+		h.sendError(w, "Ошибка при создании кодов восстановления", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendJSONResponse(w, map[string]interface{}{
+		"status":        "ok",
+		"recoveryCodes": codes,
+	})
+}
+
+// Проверяет TOTP код или гасит код восстановления
+func (h *AuthHandlers) verifySecondFactor(user *models.User, code string, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, valid := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !valid {
+			return false, nil
+		}
+
+		// Запоминаем шаг, чтобы тот же код нельзя было использовать повторно.
+		// Шаг сохраняется условно: параллельный запрос с тем же кодом,
+		// успевший раньше, делает этот код недействительным
+		used, err := h.repository.UseTOTPStep(user.ID, step)
+		if err != nil {
+			return false, err
+		}
+		if !used {
+			return false, nil
+		}
+		user.TOTPLastStep = step
+		return true, nil
+	}
+
+	if recoveryCode == "" {
+		return false, nil
+	}
+
+	err := h.recoveryCodes.Use(user.ID, utils.HashToken(normalizeRecoveryCode(recoveryCode)))
+	if err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	h.logger.Info().Msgf("Пользователь %s использовал код восстановления", user.Email)
+	return true, nil
+}
+
+// Возвращает открытые коды для показа пользователю, в БД сохраняются хеши
+func (h *AuthHandlers) generateRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		code, err := randomRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = utils.HashToken(normalizeRecoveryCode(codes[i]))
+	}
+
+	if err := h.recoveryCodes.ReplaceForUser(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Алфавит без похожих символов (0/o, 1/l/i), чтобы код было проще переписать
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+func randomRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	for i := range b {
+		b[i] = recoveryCodeAlphabet[int(b[i])%len(recoveryCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// Текущий пользователь из БД. При ошибке сам отправляет ответ
+func (h *AuthHandlers) currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	claims := utils.GetUserFromContext(r.Context())
+	if claims == nil {
+		h.sendError(w, "Неавторизован", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	user, err := h.repository.GetById(claims.ID)
+	if err != nil {
+		h.sendError(w, "Ошибка при получении пользователя", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if user == nil {
+		h.sendError(w, "Пользователь не найден", http.StatusNotFound)
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"record-services/internal/repositories/recovery_code_repository"
+	"record-services/pkg/utils"
+	"strings"
+	"testing"
+)
+
+// Запоминает сохраненные хеши кодов восстановления
+type fakeRecoveryCodes struct {
+	recovery_code_repository.RecoveryCodeRepository
+	userID uint
+	hashes []string
+}
+
+func (f *fakeRecoveryCodes) ReplaceForUser(userID uint, codeHashes []string) error {
+	f.userID, f.hashes = userID, codeHashes
+	return nil
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	recoveryCodes := &fakeRecoveryCodes{}
+	h := &AuthHandlers{recoveryCodes: recoveryCodes}
+
+	codes, err := h.generateRecoveryCodes(7)
+	if err != nil {
+		t.Fatalf("ошибка: %v", err)
+	}
+	if len(codes) != recoveryCodeCount || len(recoveryCodes.hashes) != recoveryCodeCount || recoveryCodes.userID != 7 {
+		t.Fatalf("сохранено %d хешей для пользователя %d, выдано %d кодов", len(recoveryCodes.hashes), recoveryCodes.userID, len(codes))
+	}
+
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		// Формат xxxxx-xxxxx из алфавита без похожих символов
+		parts := strings.Split(code, "-")
+		if len(parts) != 2 || len(parts[0]) != 5 || len(parts[1]) != recoveryCodeSize-5 {
+			t.Fatalf("код %q не в формате xxxxx-xxxxx", code)
+		}
+		for _, c := range parts[0] + parts[1] {
+			if !strings.ContainsRune(recoveryCodeAlphabet, c) {
+				t.Fatalf("код %q содержит символ %q вне алфавита", code, c)
+			}
+		}
+
+		if seen[code] {
+			t.Fatalf("код %q выдан дважды", code)
+		}
+		seen[code] = true
+
+		// В БД только хеш нормализованного кода, открытый код не сохраняется
+		if recoveryCodes.hashes[i] != utils.HashToken(normalizeRecoveryCode(code)) || recoveryCodes.hashes[i] == code {
+			t.Fatalf("для кода %q сохранен хеш %q", code, recoveryCodes.hashes[i])
+		}
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"как выдан", "abcde-fghjk", "abcdefghjk"},
+		{"без дефиса", "abcdefghjk", "abcdefghjk"},
+		{"верхний регистр", "ABCDE-FGHJK", "abcdefghjk"},
+		{"пробелы по краям", "  abcde-fghjk\n", "abcdefghjk"},
+		{"лишние дефисы", "ab-cde-fg-hjk", "abcdefghjk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeRecoveryCode(tt.code); got != tt.want {
+				t.Fatalf("normalizeRecoveryCode(%q) = %q, ожидалось %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"record-services/internal/models"
+	"record-services/pkg/mailer"
+	"record-services/pkg/utils"
+	"time"
+)
+
+const passwordResetTokenTTL = time.Hour
+
+func (h *AuthHandlers) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	var forgotData struct {
+		Email string `json:"email" validate:"required,email,max=255"`
+	}
+
+	if !h.decodeAndValidate(w, r, &forgotData) {
+		return
+	}
+
+	// Ответ одинаковый для любого email, чтобы не раскрывать наличие пользователей
+	response := map[string]string{
+		"status":  "ok",
+		"message": "Если email зарегистрирован, на него отправлена ссылка для сброса пароля",
+	}
+
+	user, err := h.repository.GetByEmail(forgotData.Email)
+	if err != nil {
+		h.sendError(w, "Ошибка при сбросе пароля", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil || user.ApprovalStatus == models.ApprovalStatusRejected {
+		h.sendJSONResponse(w, response)
+		return
+	}
+
+	if err := h.sendPasswordResetEmail(r.Context(), user); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отправке письма сброса пароля: %s", user.Email)
+	}
+
+	h.sendJSONResponse(w, response)
+}
+
+func (h *AuthHandlers) resetPassword(w http.ResponseWriter, r *http.Request) {
+	var resetData struct {
+		Token          string `json:"token" validate:"required,max=100"`
+		Password       string `json:"password" validate:"required,min=6,max=15"`
+		PasswordRepeat string `json:"passwordRepeat" validate:"required,min=6,max=15"`
+	}
+
+	if !h.decodeAndValidate(w, r, &resetData) {
+		return
+	}
+
+	if resetData.Password != resetData.PasswordRepeat {
+		h.sendError(w, "Пароли не совпадают", http.StatusBadRequest)
+		return
+	}
+
+	token, ok := h.useUserToken(w, resetData.Token, models.UserTokenPasswordReset)
+	if !ok {
+		return
+	}
+
+	user, err := h.repository.GetById(token.UserID)
+	if err != nil {
+		h.sendError(w, "Ошибка при сбросе пароля", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil {
+		h.sendError(w, "Ссылка недействительна или устарела", http.StatusBadRequest)
+		return
+	}
+
+	// Ссылка из письма подтверждает владение email
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+	// Владелец восстановил доступ - снимаем блокировку входа
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+
+	if !h.setPassword(w, user, resetData.Password) {
+		return
+	}
+
+	// Остальные ссылки на сброс больше не нужны
+	if err := h.userTokens.DeleteByUser(user.ID, models.UserTokenPasswordReset); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при удалении токенов сброса пароля: %d", user.ID)
+	}
+
+	// Пароль мог быть скомпрометирован - завершаем все сессии
+	if err := h.revokeUserSessions(user.ID, ""); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отзыве сессий пользователя: %d", user.ID)
+	}
+
+	h.notifyPasswordChanged(r.Context(), user)
+
+	h.sendJSONResponse(w, map[string]string{
+		"status":  "ok",
+		"message": "Пароль изменен, войдите с новым паролем",
+	})
+}
+
+func (h *AuthHandlers) changePassword(w http.ResponseWriter, r *http.Request) {
+	claims := utils.GetUserFromContext(r.Context())
+	if claims == nil {
+		h.sendError(w, "Неавторизован", http.StatusUnauthorized)
+		return
+	}
+
+	var changeData struct {
+		CurrentPassword   string `json:"currentPassword" validate:"required,max=100"`
+		NewPassword       string `json:"newPassword" validate:"required,min=6,max=15"`
+		NewPasswordRepeat string `json:"newPasswordRepeat" validate:"required,min=6,max=15"`
+	}
+
+	if !h.decodeAndValidate(w, r, &changeData) {
+		return
+	}
+
+	if changeData.NewPassword != changeData.NewPasswordRepeat {
+		h.sendError(w, "Пароли не совпадают", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.repository.GetById(claims.ID)
+	if err != nil {
+		h.sendError(w, "Ошибка при смене пароля", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil {
+		h.sendError(w, "Пользователь не найден", http.StatusNotFound)
+		return
+	}
+
+	passwordOk, _, err := h.passwords.Verify(changeData.CurrentPassword, user.PasswordHash)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при проверке пароля: %s", user.Email)
+		h.sendError(w, "Ошибка при смене пароля", http.StatusInternalServerError)
+		return
+	}
+
+	if !passwordOk {
+		h.sendError(w, "Неверный текущий пароль", http.StatusBadRequest)
+		return
+	}
+
+	if !h.setPassword(w, user, changeData.NewPassword) {
+		return
+	}
+
+	// Текущая сессия остается, остальные устройства придется авторизовать заново
+	if err := h.revokeUserSessions(user.ID, claims.SessionID); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отзыве сессий пользователя: %d", user.ID)
+	}
+
+	h.notifyPasswordChanged(r.Context(), user)
+
+	h.sendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Сохраняет новый пароль. При ошибке сам отправляет ответ
+func (h *AuthHandlers) setPassword(w http.ResponseWriter, user *models.User, plainPassword string) bool {
+	passwordHash, err := h.passwords.Hash(plainPassword)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при хешировании пароля: %s", user.Email)
+		h.sendError(w, "Ошибка при смене пароля", http.StatusInternalServerError)
+		return false
+	}
+
+	user.PasswordHash = passwordHash
+
+	if _, err := h.repository.Update(user); err != nil {
+		h.sendError(w, "Ошибка при смене пароля", http.StatusInternalServerError)
+		return false
+	}
+
+	return true
+}
+
+func (h *AuthHandlers) sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	// Действует только последняя отправленная ссылка
+	if err := h.userTokens.DeleteByUser(user.ID, models.UserTokenPasswordReset); err != nil {
+		return err
+	}
+
+	token, err := h.createUserToken(user.ID, models.UserTokenPasswordReset, passwordResetTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Сброс пароля",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nДля сброса пароля перейдите по ссылке:\n%s/reset-password?token=%s\n\nСсылка действует %d мин. Если вы не запрашивали сброс, проигнорируйте это письмо.",
+			user.Name, h.appURL, token, int(passwordResetTokenTTL.Minutes())),
+	})
+}
+
+func (h *AuthHandlers) notifyPasswordChanged(ctx context.Context, user *models.User) {
+	err := h.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Пароль изменен",
+		Body:    fmt.Sprintf("Здравствуйте, %s!\n\nПароль вашей учетной записи был изменен. Если это были не вы, восстановите доступ через сброс пароля.", user.Name),
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отправке уведомления о смене пароля: %s", user.Email)
+	}
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/mailer"
+	"record-services/pkg/utils"
+	"strings"
+	"time"
+)
+
+const emailChangeTokenTTL = 24 * time.Hour
+
+func (h *AuthHandlers) getMe(w http.ResponseWriter, r *http.Request) {
+	claims := utils.GetUserFromContext(r.Context())
+	if claims == nil {
+		h.sendError(w, "Неавторизован", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.repository.GetByIdWithOutPassword(claims.ID)
+	if err != nil {
+		h.sendError(w, "Ошибка при получении пользователя", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil {
+		h.sendError(w, "Пользователь не найден", http.StatusNotFound)
+		return
+	}
+
+	h.sendJSONResponse(w, user)
+}
+
+// Имя и часовой пояс меняются сразу. Новый email сохраняется как ожидающий и становится
+// основным только после перехода по ссылке, отправленной на него
+func (h *AuthHandlers) updateMe(w http.ResponseWriter, r *http.Request) {
+	var profileData struct {
+		Name            *string `json:"name" validate:"omitempty,min=2,max=100"`
+		Email           *string `json:"email" validate:"omitempty,email,max=255"`
+		TimeZone        *string `json:"timeZone" validate:"omitempty,timezone,max=64"`
+		CurrentPassword string  `json:"currentPassword" validate:"required_with=Email,max=100"`
+	}
+
+	if !h.decodeAndValidate(w, r, &profileData) {
+		return
+	}
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	emailChanged := profileData.Email != nil && !strings.EqualFold(*profileData.Email, user.Email)
+
+	if emailChanged {
+		passwordOk, _, err := h.passwords.Verify(profileData.CurrentPassword, user.PasswordHash)
+		if err != nil {
+			h.logger.Error().Err(err).Msgf("Ошибка при проверке пароля: %s", user.Email)
+			h.sendError(w, "Ошибка при обновлении профиля", http.StatusInternalServerError)
+			return
+		}
+
+		if !passwordOk {
+			h.sendError(w, "Неверный текущий пароль", http.StatusBadRequest)
+			return
+		}
+
+		existUser, err := h.repository.GetByEmail(*profileData.Email)
+		if err != nil {
+			h.sendError(w, "Ошибка при обновлении профиля", http.StatusInternalServerError)
+			return
+		}
+
+		if existUser != nil {
+			h.sendError(w, "Пользователь с таким email уже существует", http.StatusConflict)
+			return
+		}
+
+		user.PendingEmail = *profileData.Email
+	}
+
+	if profileData.Name != nil {
+		user.Name = *profileData.Name
+	}
+
+	if profileData.TimeZone != nil {
+		user.TimeZone = *profileData.TimeZone
+	}
+
+	if _, err := h.repository.Update(user); err != nil {
+		h.sendError(w, "Ошибка при обновлении профиля", http.StatusInternalServerError)
+		return
+	}
+
+	if emailChanged {
+		// Профиль уже сохранен, письмо можно запросить повторным изменением
+		if err := h.sendEmailChangeConfirmation(r.Context(), user); err != nil {
+			h.logger.Error().Err(err).Msgf("Ошибка при отправке письма подтверждения нового email: %s", user.PendingEmail)
+		}
+	}
+
+	h.setUserDataCookie(w, user)
+	h.sendJSONResponse(w, user.ToResponse())
+}
+
+func (h *AuthHandlers) confirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var confirmData struct {
+		Token string `json:"token" validate:"required,max=100"`
+	}
+
+	if !h.decodeAndValidate(w, r, &confirmData) {
+		return
+	}
+
+	token, ok := h.useUserToken(w, confirmData.Token, models.UserTokenEmailChange)
+	if !ok {
+		return
+	}
+
+	user, err := h.repository.GetById(token.UserID)
+	if err != nil {
+		h.sendError(w, "Ошибка при подтверждении email", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil || user.PendingEmail == "" {
+		h.sendError(w, "Ссылка недействительна или устарела", http.StatusBadRequest)
+		return
+	}
+
+	oldEmail := user.Email
+	now := time.Now()
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	user.EmailVerifiedAt = &now
+
+	if _, err := h.repository.Update(user); err != nil {
+		if errors.Is(err, consts.ErrAlreadyExists) {
+			h.sendError(w, "Пользователь с таким email уже существует", http.StatusConflict)
+			return
+		}
+		h.sendError(w, "Ошибка при подтверждении email", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Пользователь %d сменил email с %s на %s", user.ID, oldEmail, user.Email)
+
+	err = h.mailer.Send(r.Context(), mailer.Message{
+		To:      oldEmail,
+		Subject: "Email изменен",
+		Body:    fmt.Sprintf("Здравствуйте, %s!\n\nEmail вашей учетной записи изменен на %s. Если это были не вы, обратитесь к администратору.", user.Name, user.Email),
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отправке уведомления о смене email: %s", oldEmail)
+	}
+
+	h.sendJSONResponse(w, map[string]string{
+		"status":  "ok",
+		"message": "Email изменен",
+	})
+}
+
+func (h *AuthHandlers) sendEmailChangeConfirmation(ctx context.Context, user *models.User) error {
+	// Действует только ссылка на последний указанный email
+	if err := h.userTokens.DeleteByUser(user.ID, models.UserTokenEmailChange); err != nil {
+		return err
+	}
+
+	token, err := h.createUserToken(user.ID, models.UserTokenEmailChange, emailChangeTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      user.PendingEmail,
+		Subject: "Подтверждение нового email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nДля подтверждения нового email перейдите по ссылке:\n%s/confirm-email?token=%s\n\nСсылка действует %d ч.",
+			user.Name, h.appURL, token, int(emailChangeTokenTTL.Hours())),
+	})
+}
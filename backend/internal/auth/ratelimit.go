@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"net/http"
+	"record-services/pkg/ratelimit"
+	"time"
+)
+
+// Лимиты запросов к эндпоинтам авторизации
+type authLimits struct {
+	login    func(http.Handler) http.Handler
+	mfa      func(http.Handler) http.Handler
+	register func(http.Handler) http.Handler
+	email    func(http.Handler) http.Handler
+	token    func(http.Handler) http.Handler
+}
+
+func newAuthLimits(store ratelimit.Store) *authLimits {
+	byEmail := ratelimit.KeyByJSONField("email")
+
+	return &authLimits{
+		// По IP ограничиваем перебор разных аккаунтов, по email - одного аккаунта с разных IP
+		login: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("login-ip", store, 20, time.Minute, 20), Key: ratelimit.KeyByIP},
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("login-email", store, 5, time.Minute, 5), Key: byEmail},
+		),
+		mfa: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("mfa-ip", store, 10, time.Minute, 10), Key: ratelimit.KeyByIP},
+		),
+		register: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("register-ip", store, 5, time.Hour, 5), Key: ratelimit.KeyByIP},
+		),
+		// Эндпоинты, отправляющие письма
+		email: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("email-ip", store, 10, time.Hour, 5), Key: ratelimit.KeyByIP},
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("email-email", store, 3, time.Hour, 3), Key: byEmail},
+		),
+		// Эндпоинты с одноразовыми токенами и паролями
+		token: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("token-ip", store, 10, 15*time.Minute, 10), Key: ratelimit.KeyByIP},
+		),
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"net/http"
+	"record-services/pkg/utils"
+	"time"
+)
+
+type sessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+func (h *AuthHandlers) listSessions(w http.ResponseWriter, r *http.Request) {
+	claims := utils.GetUserFromContext(r.Context())
+	if claims == nil {
+		h.sendError(w, "Неавторизован", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.refreshTokens.ListActiveByUser(claims.ID)
+	if err != nil {
+		h.sendError(w, "Ошибка при получении сессий", http.StatusInternalServerError)
+		return
+	}
+
+	sessions := make([]sessionResponse, len(tokens))
+	for i, token := range tokens {
+		sessions[i] = sessionResponse{
+			ID:         token.FamilyID,
+			UserAgent:  token.UserAgent,
+			IP:         token.IP,
+			LastUsedAt: token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    token.FamilyID == claims.SessionID,
+		}
+	}
+
+	h.sendJSONResponse(w, sessions)
+}
+
+func (h *AuthHandlers) revokeSession(w http.ResponseWriter, r *http.Request) {
+	claims := utils.GetUserFromContext(r.Context())
+	if claims == nil {
+		h.sendError(w, "Неавторизован", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := r.PathValue("id")
+
+	// Отзывать можно только свои сессии
+	tokens, err := h.refreshTokens.ListActiveByUser(claims.ID)
+	if err != nil {
+		h.sendError(w, "Ошибка при отзыве сессии", http.StatusInternalServerError)
+		return
+	}
+
+	found := false
+	for _, token := range tokens {
+		if token.FamilyID == sessionID {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		h.sendError(w, "Сессия не найдена", http.StatusNotFound)
+		return
+	}
+
+	if err := h.revokeSessionByID(sessionID); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отзыве сессии: %s", sessionID)
+		h.sendError(w, "Ошибка при отзыве сессии", http.StatusInternalServerError)
+		return
+	}
+
+	if sessionID == claims.SessionID {
+		h.clearAuthCookies(w)
+	}
+
+	h.sendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Выход на всех устройствах, включая текущее
+func (h *AuthHandlers) logoutAll(w http.ResponseWriter, r *http.Request) {
+	claims := utils.GetUserFromContext(r.Context())
+	if claims == nil {
+		h.sendError(w, "Неавторизован", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.revokeUserSessions(claims.ID, ""); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отзыве сессий пользователя: %d", claims.ID)
+		h.sendError(w, "Ошибка при выходе", http.StatusInternalServerError)
+		return
+	}
+
+	h.clearAuthCookies(w)
+	h.sendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Отзывает refresh токены сессии и все выданные в ней access токены
+func (h *AuthHandlers) revokeSessionByID(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+
+	if err := h.refreshTokens.RevokeFamily(sessionID); err != nil {
+		return err
+	}
+
+	return h.revocations.RevokeSession(sessionID)
+}
+
+// Отзывает все сессии пользователя, кроме exceptSessionID
+func (h *AuthHandlers) revokeUserSessions(userID uint, exceptSessionID string) error {
+	tokens, err := h.refreshTokens.ListActiveByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if token.FamilyID == exceptSessionID {
+			continue
+		}
+		if err := h.revokeSessionByID(token.FamilyID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
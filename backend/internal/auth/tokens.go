@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/utils"
+	"strings"
+	"time"
+)
+
+const (
+	refreshTokenSize = 32
+	familyIDSize     = 16
+)
+
+type issuedTokens struct {
+	accessToken  string
+	refreshToken string
+	familyID     string
+}
+
+// Выпускает access токен и refresh токен. Пустой familyID начинает новую сессию,
+// иначе новый refresh токен продолжает существующее семейство (ротация)
+func (h *AuthHandlers) issueTokens(r *http.Request, user *models.User, familyID string) (*issuedTokens, error) {
+	if familyID == "" {
+		var err error
+		familyID, err = utils.GenerateRandomToken(familyIDSize)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(refreshTokenSize)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = h.refreshTokens.Create(&models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(h.tokenConfig.RefreshTTL),
+		UserAgent: httputils.UserAgent(r),
+		IP:        httputils.ClientIP(r),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.CreateToken(utils.UserClaims{
+		ID:          user.ID,
+		Name:        user.Name,
+		Email:       user.Email,
+		SessionID:   familyID,
+		OwnerID:     user.Owner(),
+		Roles:       user.RoleNames(),
+		Permissions: user.PermissionCodes(),
+	}, []byte(h.JwtSecret), h.tokenConfig.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &issuedTokens{
+		accessToken:  accessToken,
+		refreshToken: refreshToken,
+		familyID:     familyID,
+	}, nil
+}
+
+func (h *AuthHandlers) refresh(w http.ResponseWriter, r *http.Request) {
+	refreshToken := h.extractRefreshToken(r)
+	if refreshToken == "" {
+		h.sendError(w, "Отсутствует refresh токен", http.StatusUnauthorized)
+		return
+	}
+
+	stored, err := h.refreshTokens.GetByHash(utils.HashToken(refreshToken))
+	if err != nil {
+		h.sendError(w, "Ошибка при обновлении токена", http.StatusInternalServerError)
+		return
+	}
+
+	if stored == nil {
+		h.clearAuthCookies(w)
+		h.sendError(w, "Невалидный refresh токен", http.StatusUnauthorized)
+		return
+	}
+
+	// Повторное использование уже обмененного или отозванного токена -
+	// признак кражи: отзываем всю сессию
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		h.revokeReusedFamily(w, stored)
+		return
+	}
+
+	if !stored.IsActive(time.Now()) {
+		h.clearAuthCookies(w)
+		h.sendError(w, "Срок действия refresh токена истек", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.repository.GetById(stored.UserID)
+	if err != nil {
+		h.sendError(w, "Ошибка при обновлении токена", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil || !user.IsActive {
+		if err := h.revokeSessionByID(stored.FamilyID); err != nil {
+			h.logger.Error().Err(err).Msgf("Ошибка при отзыве сессии: %s", stored.FamilyID)
+		}
+		h.clearAuthCookies(w)
+		h.sendError(w, "Пользователь не активный, обратитесь к администратору", http.StatusUnauthorized)
+		return
+	}
+
+	// Параллельный запрос успел обменять этот же токен
+	if err := h.refreshTokens.MarkUsed(stored.ID); err != nil {
+		if errors.Is(err, consts.ErrNoRowsAffected) {
+			h.revokeReusedFamily(w, stored)
+			return
+		}
+		h.sendError(w, "Ошибка при обновлении токена", http.StatusInternalServerError)
+		return
+	}
+
+	tokens, err := h.issueTokens(r, user, stored.FamilyID)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при создании токена: %s", user.Email)
+		h.sendError(w, "Ошибка при обновлении токена", http.StatusInternalServerError)
+		return
+	}
+
+	h.setAuthCookies(w, tokens, user)
+
+	h.sendJSONResponse(w, map[string]string{
+		"status":       "ok",
+		"token":        tokens.accessToken,
+		"refreshToken": tokens.refreshToken,
+	})
+}
+
+func (h *AuthHandlers) revokeReusedFamily(w http.ResponseWriter, stored *models.RefreshToken) {
+	h.logger.Warn().Msgf("Повторное использование refresh токена пользователя %d, сессия %s отозвана", stored.UserID, stored.FamilyID)
+
+	if err := h.revokeSessionByID(stored.FamilyID); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отзыве сессии: %s", stored.FamilyID)
+	}
+
+	h.clearAuthCookies(w)
+	h.sendError(w, "Невалидный refresh токен", http.StatusUnauthorized)
+}
+
+// Refresh токен берем из cookie (браузер) или из тела запроса (api клиенты)
+func (h *AuthHandlers) extractRefreshToken(r *http.Request) string {
+	cookie, err := r.Cookie(string(consts.CookieRefreshKey))
+	if err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	var body struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if r.Body == nil || r.ContentLength == 0 {
+		return ""
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return ""
+	}
+	return body.RefreshToken
+}
+
+// Данные access токена из cookie или заголовка Authorization для маршрутов,
+// которые AuthMiddleware пропускает без проверки. nil - токена нет или он невалиден
+func (h *AuthHandlers) accessClaims(r *http.Request) *utils.UserClaims {
+	tokenString := ""
+	if cookie, err := r.Cookie(string(consts.CookieTokenKey)); err == nil && cookie.Value != "" {
+		tokenString = cookie.Value
+	} else {
+		tokenString = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if tokenString == "" {
+		return nil
+	}
+
+	claims, err := utils.ParseToken(tokenString, []byte(h.JwtSecret))
+	if err != nil {
+		return nil
+	}
+	return claims
+}
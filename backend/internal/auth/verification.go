@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/mailer"
+	"record-services/pkg/utils"
+	"time"
+)
+
+const (
+	userTokenSize             = 32
+	emailVerificationTokenTTL = 48 * time.Hour
+)
+
+func (h *AuthHandlers) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	var verifyData struct {
+		Token string `json:"token" validate:"required,max=100"`
+	}
+
+	if !h.decodeAndValidate(w, r, &verifyData) {
+		return
+	}
+
+	token, ok := h.useUserToken(w, verifyData.Token, models.UserTokenEmailVerification)
+	if !ok {
+		return
+	}
+
+	user, err := h.repository.GetById(token.UserID)
+	if err != nil {
+		h.sendError(w, "Ошибка при подтверждении email", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil {
+		h.sendError(w, "Ссылка недействительна или устарела", http.StatusBadRequest)
+		return
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if _, err := h.repository.Update(user); err != nil {
+			h.sendError(w, "Ошибка при подтверждении email", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	h.sendJSONResponse(w, map[string]string{
+		"status":  "ok",
+		"message": "Email подтвержден, регистрация ожидает одобрения администратором",
+	})
+}
+
+func (h *AuthHandlers) resendVerification(w http.ResponseWriter, r *http.Request) {
+	var resendData struct {
+		Email string `json:"email" validate:"required,email,max=255"`
+	}
+
+	if !h.decodeAndValidate(w, r, &resendData) {
+		return
+	}
+
+	// Ответ одинаковый для любого email, чтобы не раскрывать наличие пользователей
+	response := map[string]string{
+		"status":  "ok",
+		"message": "Если email зарегистрирован и не подтвержден, письмо отправлено повторно",
+	}
+
+	user, err := h.repository.GetByEmail(resendData.Email)
+	if err != nil {
+		h.sendError(w, "Ошибка при отправке письма", http.StatusInternalServerError)
+		return
+	}
+
+	if user == nil || user.EmailVerifiedAt != nil {
+		h.sendJSONResponse(w, response)
+		return
+	}
+
+	if err := h.sendVerificationEmail(r.Context(), user); err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отправке письма подтверждения: %s", user.Email)
+	}
+
+	h.sendJSONResponse(w, response)
+}
+
+func (h *AuthHandlers) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	// Действует только последняя отправленная ссылка
+	if err := h.userTokens.DeleteByUser(user.ID, models.UserTokenEmailVerification); err != nil {
+		return err
+	}
+
+	token, err := h.createUserToken(user.ID, models.UserTokenEmailVerification, emailVerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	return h.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Подтверждение email",
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nДля подтверждения email перейдите по ссылке:\n%s/verify-email?token=%s\n\nСсылка действует %d ч.",
+			user.Name, h.appURL, token, int(emailVerificationTokenTTL.Hours())),
+	})
+}
+
+// Создает одноразовый токен и возвращает его открытое значение для письма
+func (h *AuthHandlers) createUserToken(userID uint, purpose models.UserTokenPurpose, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateRandomToken(userTokenSize)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = h.userTokens.Create(&models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Проверяет и гасит одноразовый токен. При ошибке сам отправляет ответ
+func (h *AuthHandlers) useUserToken(w http.ResponseWriter, rawToken string, purpose models.UserTokenPurpose) (*models.UserToken, bool) {
+	token, err := h.userTokens.GetByHash(utils.HashToken(rawToken))
+	if err != nil {
+		h.sendError(w, "Ошибка при проверке ссылки", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if token == nil || !token.IsValid(purpose, time.Now()) {
+		h.sendError(w, "Ссылка недействительна или устарела", http.StatusBadRequest)
+		return nil, false
+	}
+
+	if err := h.userTokens.MarkUsed(token.ID); err != nil {
+		if errors.Is(err, consts.ErrNoRowsAffected) {
+			h.sendError(w, "Ссылка недействительна или устарела", http.StatusBadRequest)
+			return nil, false
+		}
+		h.sendError(w, "Ошибка при проверке ссылки", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return token, true
+}
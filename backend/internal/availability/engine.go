@@ -0,0 +1,600 @@
+package availability
+
+import (
+	"context"
+	"record-services/internal/models"
+	"record-services/internal/repositories/absence_repository"
+	"record-services/internal/repositories/appointment_repository"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/holiday_repository"
+	"record-services/internal/repositories/schedule_repository"
+	"record-services/internal/repositories/session_repository"
+	"record-services/internal/repositories/user_repository"
+	"record-services/pkg/timezone"
+	"slices"
+	"time"
+)
+
+// Верхняя граница буферов услуги, совпадает с ограничением при сохранении секции
+const maxBuffer = 4 * time.Hour
+
+// Расчет свободного времени сотрудников: рабочие интервалы графика
+// за вычетом перерывов, отсутствий, праздников и активных записей с их
+// буферами, а также времени, когда заняты нужные услуге ресурсы.
+// График, праздники и шаг слотов считаются по часам в часовом поясе
+// сотрудника, поэтому переход на летнее время не сдвигает рабочие часы
+type Engine struct {
+	users        user_repository.UserRepository
+	employees    employee_repository.EmployeeRepository
+	schedules    schedule_repository.ScheduleRepository
+	appointments appointment_repository.AppointmentRepository
+	absences     absence_repository.AbsenceRepository
+	holidays     holiday_repository.HolidayRepository
+	sessions     session_repository.SessionRepository
+}
+
+func NewEngine(users user_repository.UserRepository, employees employee_repository.EmployeeRepository, schedules schedule_repository.ScheduleRepository, appointments appointment_repository.AppointmentRepository, absences absence_repository.AbsenceRepository, holidays holiday_repository.HolidayRepository, sessions session_repository.SessionRepository) *Engine {
+	return &Engine{
+		users:        users,
+		employees:    employees,
+		schedules:    schedules,
+		appointments: appointments,
+		absences:     absences,
+		holidays:     holidays,
+		sessions:     sessions,
+	}
+}
+
+type Query struct {
+	// Владелец сотрудников, его календари праздников применяются ко всем
+	OwnerID     uint
+	EmployeeIDs []uint
+	// Даты начала и окончания (включительно) в часовом поясе Location.
+	// Время слотов возвращается в нем же
+	From     time.Time
+	To       time.Time
+	Location *time.Location
+	Duration time.Duration
+	// Время до и после слота, которое не должно пересекаться с другими записями
+	BufferBefore time.Duration
+	BufferAfter  time.Duration
+	// Шаг начала слотов от полуночи
+	Step time.Duration
+	// Предлагаются слоты, начинающиеся в интервале [NotBefore, NotAfter].
+	// Нулевой NotAfter - без ограничения
+	NotBefore time.Time
+	NotAfter  time.Time
+	// Переносимая запись не считается занятостью
+	ExcludeAppointmentID uint
+	// Ресурсы, которые должны быть свободны на время слота
+	Resources []uint
+}
+
+// Запрос свободного времени услуги: у каждого сотрудника свои
+// длительность, буферы и окно записи
+type SectionQuery struct {
+	Section     *models.Section
+	EmployeeIDs []uint
+	// Переопределения параметров секции по сотрудникам
+	Overrides map[uint]*models.EmployeeSection
+	From      time.Time
+	To        time.Time
+	Location  *time.Location
+	// 0 - длительность услуги у сотрудника
+	Duration             time.Duration
+	Step                 time.Duration
+	Now                  time.Time
+	ExcludeAppointmentID uint
+}
+
+func OverridesByEmployee(assignments []models.EmployeeSection) map[uint]*models.EmployeeSection {
+	overrides := make(map[uint]*models.EmployeeSection, len(assignments))
+	for i := range assignments {
+		overrides[assignments[i].EmployeeID] = &assignments[i]
+	}
+	return overrides
+}
+
+// Свободные слоты услуги. Условия сотрудников различаются,
+// поэтому слоты считаются по одному сотруднику. Для групповой услуги
+// к ним добавляются занятия со свободными местами
+func (e *Engine) SectionSlots(ctx context.Context, query SectionQuery) ([]models.Slot, error) {
+	slots := make([]models.Slot, 0)
+
+	for _, employeeID := range query.EmployeeIDs {
+		terms := query.Section.Terms(query.Overrides[employeeID])
+		if query.Duration > 0 {
+			terms.Duration = query.Duration
+		}
+		notBefore, notAfter := terms.BookingWindow(query.Now)
+
+		employeeSlots, err := e.FreeSlots(ctx, Query{
+			OwnerID:              query.Section.UserID,
+			EmployeeIDs:          []uint{employeeID},
+			From:                 query.From,
+			To:                   query.To,
+			Location:             query.Location,
+			Duration:             terms.Duration,
+			BufferBefore:         terms.BufferBefore,
+			BufferAfter:          terms.BufferAfter,
+			Step:                 query.Step,
+			NotBefore:            notBefore,
+			NotAfter:             notAfter,
+			ExcludeAppointmentID: query.ExcludeAppointmentID,
+			Resources:            terms.Resources,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if query.Section.IsGroup() {
+			employeeSlots, err = e.groupSlots(ctx, query, employeeID, terms, employeeSlots, notBefore, notAfter)
+			if err != nil {
+				return nil, err
+			}
+		}
+		slots = append(slots, employeeSlots...)
+	}
+
+	return slots, nil
+}
+
+// Слоты групповой услуги сотрудника: занятия со свободными местами
+// и новые занятия в свободное время free. Свободный слот в начало
+// занятия без активных записей заменяется этим занятием
+func (e *Engine) groupSlots(ctx context.Context, query SectionQuery, employeeID uint, terms models.ServiceTerms, free []models.Slot, notBefore, notAfter time.Time) ([]models.Slot, error) {
+	from, to := dayRange(query.From, query.To, query.Location)
+
+	sessions, err := e.sessions.GetAll(ctx, models.GroupSessionFilter{
+		SectionID:   query.Section.ID,
+		EmployeeIDs: []uint{employeeID},
+		From:        &from,
+		To:          &to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := e.Location(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := e.blockedIntervals(ctx, query.Section.UserID, employeeID, from, to, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]models.Slot, 0, len(free)+len(sessions))
+	sessionStarts := make(map[time.Time]bool, len(sessions))
+
+	for i := range sessions {
+		session := &sessions[i]
+		sessionStarts[session.StartAt.UTC()] = true
+
+		if session.Available() == 0 || session.StartAt.Before(notBefore) ||
+			(!notAfter.IsZero() && session.StartAt.After(notAfter)) ||
+			overlapsAny(blocked, Interval{Start: session.StartAt, End: session.EndAt}) {
+			continue
+		}
+
+		slots = append(slots, models.Slot{
+			EmployeeID: employeeID,
+			Start:      session.StartAt.In(query.Location),
+			End:        session.EndAt.In(query.Location),
+			SessionID:  &session.ID,
+			Capacity:   session.Capacity,
+			Available:  session.Available(),
+		})
+	}
+
+	for _, slot := range free {
+		if sessionStarts[slot.Start.UTC()] {
+			continue
+		}
+		slot.Capacity = terms.Capacity
+		slot.Available = terms.Capacity
+		slots = append(slots, slot)
+	}
+
+	slices.SortFunc(slots, func(a, b models.Slot) int {
+		return a.Start.Compare(b.Start)
+	})
+	return slots, nil
+}
+
+func (e *Engine) FreeSlots(ctx context.Context, query Query) ([]models.Slot, error) {
+	slots := make([]models.Slot, 0)
+
+	for _, employeeID := range query.EmployeeIDs {
+		loc, err := e.Location(ctx, employeeID)
+		if err != nil {
+			return nil, err
+		}
+
+		free, err := e.freeIntervals(ctx, query, employeeID, loc)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, interval := range free {
+			for _, slot := range splitIntoSlots(interval, query.Duration, query.Step, loc) {
+				if slot.Start.Before(query.NotBefore) {
+					continue
+				}
+				if !query.NotAfter.IsZero() && slot.Start.After(query.NotAfter) {
+					continue
+				}
+				slots = append(slots, models.Slot{
+					EmployeeID: employeeID,
+					Start:      slot.Start.In(query.Location),
+					End:        slot.End.In(query.Location),
+					Capacity:   1,
+					Available:  1,
+				})
+			}
+		}
+	}
+
+	return slots, nil
+}
+
+// Интервалы сотрудника с даты query.From по query.To включительно, в которых
+// может находиться слот: записи расширены на буферы запроса, так что буферы
+// нового слота не заденут их. Ресурсы заняты без учета буферов
+func (e *Engine) FreeIntervals(ctx context.Context, query Query, employeeID uint) ([]Interval, error) {
+	loc, err := e.Location(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.freeIntervals(ctx, query, employeeID, loc)
+}
+
+// Дни запроса в поясе query.Location могут не совпадать с днями графика
+// в поясе сотрудника loc: берутся все дни графика, которые их задевают,
+// и рабочее время обрезается по границам запроса
+func (e *Engine) freeIntervals(ctx context.Context, query Query, employeeID uint, loc *time.Location) ([]Interval, error) {
+	from, to := dayRange(query.From, query.To, query.Location)
+
+	working, err := e.WorkingIntervals(ctx, employeeID, from, to.Add(-time.Nanosecond), loc)
+	if err != nil {
+		return nil, err
+	}
+
+	working = clip(working, from, to)
+	if len(working) == 0 {
+		return working, nil
+	}
+
+	rangeStart, rangeEnd := working[0].Start, working[len(working)-1].End
+
+	blocked, err := e.blockedIntervals(ctx, query.OwnerID, employeeID, rangeStart, rangeEnd, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	busy, err := e.busyIntervals(ctx, employeeID, rangeStart.Add(-query.BufferAfter-maxBuffer), rangeEnd.Add(query.BufferBefore+maxBuffer), 0, query.ExcludeAppointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range busy {
+		busy[i].Start = busy[i].Start.Add(-query.BufferAfter)
+		busy[i].End = busy[i].End.Add(query.BufferBefore)
+	}
+
+	occupied, err := e.resourceIntervals(ctx, query.Resources, rangeStart, rangeEnd, 0, query.ExcludeAppointmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return subtract(working, slices.Concat(blocked, busy, occupied)), nil
+}
+
+// Запись [start, end) с буферами terms пересекается с другими активными
+// записями сотрудника с учетом их буферов. excludeID - переносимая запись,
+// sessionID - групповое занятие, места в котором не считаются занятостью
+func (e *Engine) IsBusy(ctx context.Context, employeeID uint, start, end time.Time, terms models.ServiceTerms, excludeID, sessionID uint) (bool, error) {
+	interval := Interval{Start: start.Add(-terms.BufferBefore), End: end.Add(terms.BufferAfter)}
+
+	busy, err := e.busyIntervals(ctx, employeeID, interval.Start.Add(-maxBuffer), interval.End.Add(maxBuffer), sessionID, excludeID)
+	if err != nil {
+		return false, err
+	}
+
+	return overlapsAny(busy, interval), nil
+}
+
+// Один из ресурсов terms занят другой активной записью в [start, end).
+// excludeID и sessionID - как в IsBusy
+func (e *Engine) IsResourceBusy(ctx context.Context, start, end time.Time, terms models.ServiceTerms, excludeID, sessionID uint) (bool, error) {
+	occupied, err := e.resourceIntervals(ctx, terms.Resources, start, end, sessionID, excludeID)
+	if err != nil {
+		return false, err
+	}
+
+	return overlapsAny(occupied, Interval{Start: start, End: end}), nil
+}
+
+type ConflictReason string
+
+const (
+	// Отсутствие сотрудника или праздник
+	ConflictBlocked ConflictReason = "blocked"
+	// Пересечение с другой записью с учетом буферов
+	ConflictBusy ConflictReason = "busy"
+	// Нужный услуге ресурс занят другой записью
+	ConflictResource ConflictReason = "resource"
+)
+
+// Проверка набора записей сотрудника, например вхождений серии: записи
+// не должны попадать на отсутствия и праздники и пересекаться с другими
+// записями и между собой. Возвращает причины конфликтов по индексам
+// intervals. excludeIDs - заменяемые записи, они не считаются занятостью
+func (e *Engine) Conflicts(ctx context.Context, ownerID, employeeID uint, intervals []Interval, terms models.ServiceTerms, excludeIDs []uint) (map[int]ConflictReason, error) {
+	conflicts := make(map[int]ConflictReason)
+	if len(intervals) == 0 {
+		return conflicts, nil
+	}
+
+	from, to := intervals[0].Start, intervals[0].End
+	for _, interval := range intervals {
+		if interval.Start.Before(from) {
+			from = interval.Start
+		}
+		if interval.End.After(to) {
+			to = interval.End
+		}
+	}
+
+	loc, err := e.Location(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := e.blockedIntervals(ctx, ownerID, employeeID, from, to, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	busy, err := e.busyIntervals(ctx, employeeID, from.Add(-terms.BufferBefore-maxBuffer), to.Add(terms.BufferAfter+maxBuffer), 0, excludeIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	occupied, err := e.resourceIntervals(ctx, terms.Resources, from, to, 0, excludeIDs...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, interval := range intervals {
+		if overlapsAny(blocked, interval) {
+			conflicts[i] = ConflictBlocked
+			continue
+		}
+
+		withBuffers := Interval{Start: interval.Start.Add(-terms.BufferBefore), End: interval.End.Add(terms.BufferAfter)}
+		if overlapsAny(busy, withBuffers) {
+			conflicts[i] = ConflictBusy
+			continue
+		}
+
+		if overlapsAny(occupied, interval) {
+			conflicts[i] = ConflictResource
+			continue
+		}
+		busy = append(busy, withBuffers)
+	}
+
+	return conflicts, nil
+}
+
+// Интервал [start, end) пересекается с отсутствием сотрудника или праздником
+func (e *Engine) IsBlocked(ctx context.Context, ownerID, employeeID uint, start, end time.Time) (bool, error) {
+	loc, err := e.Location(ctx, employeeID)
+	if err != nil {
+		return false, err
+	}
+
+	blocked, err := e.blockedIntervals(ctx, ownerID, employeeID, start, end, loc)
+	if err != nil {
+		return false, err
+	}
+
+	interval := Interval{Start: start, End: end}
+	for _, b := range blocked {
+		if b.Overlaps(interval) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Часовой пояс графика сотрудника: его собственный или владельца
+func (e *Engine) Location(ctx context.Context, employeeID uint) (*time.Location, error) {
+	name, err := e.employees.GetTimeZone(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+	return timezone.LoadOrUTC(name), nil
+}
+
+// Часовой пояс владельца. По нему по умолчанию задаются даты
+// и показывается время, если клиент не указал свой пояс
+func (e *Engine) OwnerLocation(ownerID uint) (*time.Location, error) {
+	owner, err := e.users.GetById(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner == nil {
+		return time.UTC, nil
+	}
+	return timezone.LoadOrUTC(owner.TimeZone), nil
+}
+
+// Рабочее время сотрудника по графику с учетом исключений на даты
+// с from по to включительно в часовом поясе loc
+func (e *Engine) WorkingIntervals(ctx context.Context, employeeID uint, from, to time.Time, loc *time.Location) ([]Interval, error) {
+	weekly, err := e.schedules.GetWeekly(ctx, employeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := e.schedules.GetOverrides(ctx, employeeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	overridesByDate := make(map[string][]models.ScheduleOverride)
+	for _, override := range overrides {
+		key := override.Date.Format(time.DateOnly)
+		overridesByDate[key] = append(overridesByDate[key], override)
+	}
+
+	result := make([]Interval, 0)
+	for date := startOfDay(from, loc); !date.After(startOfDay(to, loc)); date = date.AddDate(0, 0, 1) {
+		var work, breaks []Interval
+
+		if dayOverrides, ok := overridesByDate[date.Format(time.DateOnly)]; ok {
+			for _, override := range dayOverrides {
+				if override.IsDayOff {
+					work, breaks = nil, nil
+					break
+				}
+				appendInterval(&work, &breaks, date, override.Start, override.End, override.IsBreak)
+			}
+		} else {
+			for _, entry := range weekly {
+				if entry.Weekday == date.Weekday() {
+					appendInterval(&work, &breaks, date, entry.Start, entry.End, entry.IsBreak)
+				}
+			}
+		}
+
+		result = append(result, subtract(work, breaks)...)
+	}
+
+	return normalize(result), nil
+}
+
+// Отсутствия сотрудника и праздничные дни владельца в интервале [from, to)
+func (e *Engine) blockedIntervals(ctx context.Context, ownerID, employeeID uint, from, to time.Time, loc *time.Location) ([]Interval, error) {
+	absences, err := e.absences.GetByEmployee(ctx, employeeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := make([]Interval, 0, len(absences))
+	for _, absence := range absences {
+		blocked = append(blocked, Interval{Start: absence.StartAt, End: absence.EndAt})
+	}
+
+	holidays, err := e.holidays.GetHolidaysByOwner(ctx, ownerID, startOfDay(from, loc), startOfDay(to, loc))
+	if err != nil {
+		return nil, err
+	}
+
+	// Праздник занимает календарный день в часовом поясе сотрудника
+	for _, holiday := range holidays {
+		year, month, day := holiday.Date.Date()
+		dayStart := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		blocked = append(blocked, Interval{Start: dayStart, End: dayStart.AddDate(0, 0, 1)})
+	}
+
+	return blocked, nil
+}
+
+// Время активных записей сотрудника вместе с их буферами. Места
+// занятия sessionID и записи excludeIDs не учитываются
+func (e *Engine) busyIntervals(ctx context.Context, employeeID uint, from, to time.Time, sessionID uint, excludeIDs ...uint) ([]Interval, error) {
+	appointments, err := e.appointments.GetActiveByEmployee(ctx, employeeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	busy := make([]Interval, 0, len(appointments))
+	for _, appointment := range appointments {
+		if slices.Contains(excludeIDs, appointment.ID) {
+			continue
+		}
+		if sessionID != 0 && appointment.SessionID != nil && *appointment.SessionID == sessionID {
+			continue
+		}
+		start, end := appointment.OccupiedInterval()
+		busy = append(busy, Interval{Start: start, End: end})
+	}
+	return busy, nil
+}
+
+// Время активных записей, занимающих хотя бы один из ресурсов, без буферов.
+// Места занятия sessionID и записи excludeIDs не учитываются
+func (e *Engine) resourceIntervals(ctx context.Context, resourceIDs []uint, from, to time.Time, sessionID uint, excludeIDs ...uint) ([]Interval, error) {
+	if len(resourceIDs) == 0 {
+		return nil, nil
+	}
+
+	appointments, err := e.appointments.GetActiveByResources(ctx, resourceIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	occupied := make([]Interval, 0, len(appointments))
+	for _, appointment := range appointments {
+		if slices.Contains(excludeIDs, appointment.ID) {
+			continue
+		}
+		if sessionID != 0 && appointment.SessionID != nil && *appointment.SessionID == sessionID {
+			continue
+		}
+		occupied = append(occupied, Interval{Start: appointment.StartAt, End: appointment.EndAt})
+	}
+	return occupied, nil
+}
+
+func appendInterval(work, breaks *[]Interval, date time.Time, start, end models.ClockTime, isBreak bool) {
+	interval := Interval{Start: start.On(date), End: end.On(date)}
+	if isBreak {
+		*breaks = append(*breaks, interval)
+	} else {
+		*work = append(*work, interval)
+	}
+}
+
+// Нарезает интервал на слоты длительностью duration, начала которых
+// кратны step от полуночи по часам пояса loc. В день перехода на летнее
+// время несуществующие начала сдвигаются вперед и повторы пропускаются,
+// при переходе на зимнее повторный час не дает вторых слотов
+func splitIntoSlots(interval Interval, duration, step time.Duration, loc *time.Location) []Interval {
+	slots := make([]Interval, 0)
+	if duration <= 0 || step <= 0 {
+		return slots
+	}
+
+	var last time.Time
+	for day := startOfDay(interval.Start, loc); day.Before(interval.End); day = day.AddDate(0, 0, 1) {
+		year, month, date := day.Date()
+		for offset := time.Duration(0); offset < 24*time.Hour; offset += step {
+			start := time.Date(year, month, date, 0, 0, 0, int(offset), loc)
+			if start.Before(interval.Start) || (!last.IsZero() && !start.After(last)) {
+				continue
+			}
+			if start.Add(duration).After(interval.End) {
+				return slots
+			}
+			slots = append(slots, Interval{Start: start, End: start.Add(duration)})
+			last = start
+		}
+	}
+	return slots
+}
+
+// Дни с from по to включительно в поясе loc: [начало from, конец to)
+func dayRange(from, to time.Time, loc *time.Location) (time.Time, time.Time) {
+	return startOfDay(from, loc), startOfDay(to, loc).AddDate(0, 0, 1)
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,250 @@
+package availability
+
+import (
+	"context"
+	"record-services/internal/models"
+	"record-services/internal/repositories/absence_repository"
+	"record-services/internal/repositories/appointment_repository"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/holiday_repository"
+	"record-services/internal/repositories/session_repository"
+	"testing"
+	"time"
+)
+
+// Заглушки репозиториев для расчета занятости.
+// Остальные методы интерфейсов не вызываются
+
+type fakeEmployees struct {
+	employee_repository.EmployeeRepository
+}
+
+func (f *fakeEmployees) GetTimeZone(ctx context.Context, employeeID uint) (string, error) {
+	return "UTC", nil
+}
+
+type fakeSessions struct {
+	session_repository.SessionRepository
+	sessions []models.GroupSession
+}
+
+func (f *fakeSessions) GetAll(ctx context.Context, filter models.GroupSessionFilter) ([]models.GroupSession, error) {
+	return f.sessions, nil
+}
+
+type fakeAbsences struct {
+	absence_repository.AbsenceRepository
+	absences []models.Absence
+}
+
+func (f *fakeAbsences) GetByEmployee(ctx context.Context, employeeID uint, from, to time.Time) ([]models.Absence, error) {
+	return f.absences, nil
+}
+
+// Записи сотрудника и записи с ресурсами. Отбор по ресурсам и периоду
+// выполняет БД, заглушка возвращает список как есть
+type fakeAppointments struct {
+	appointment_repository.AppointmentRepository
+	byEmployee  []models.Appointment
+	byResources []models.Appointment
+	// Ресурсы последнего запроса GetActiveByResources
+	resourceIDs []uint
+}
+
+func (f *fakeAppointments) GetActiveByEmployee(ctx context.Context, employeeID uint, from, to time.Time) ([]models.Appointment, error) {
+	return f.byEmployee, nil
+}
+
+func (f *fakeAppointments) GetActiveByResources(ctx context.Context, resourceIDs []uint, from, to time.Time) ([]models.Appointment, error) {
+	f.resourceIDs = resourceIDs
+	return f.byResources, nil
+}
+
+type fakeHolidays struct {
+	holiday_repository.HolidayRepository
+}
+
+func (f *fakeHolidays) GetHolidaysByOwner(ctx context.Context, userID uint, from, to time.Time) ([]models.Holiday, error) {
+	return nil, nil
+}
+
+func TestGroupSlots(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2040, 3, 15, h, 0, 0, 0, time.UTC) }
+	free := func(hours ...int) []models.Slot {
+		slots := make([]models.Slot, len(hours))
+		for i, h := range hours {
+			slots[i] = models.Slot{EmployeeID: 1, Start: at(h), End: at(h + 1)}
+		}
+		return slots
+	}
+	session := func(id uint, h, capacity, booked int) models.GroupSession {
+		s := models.GroupSession{StartAt: at(h), EndAt: at(h + 1), Capacity: capacity, Booked: booked}
+		s.ID = id
+		return s
+	}
+
+	type want struct {
+		hour      int
+		sessionID uint
+		capacity  int
+		available int
+	}
+
+	tests := []struct {
+		name      string
+		free      []models.Slot
+		sessions  []models.GroupSession
+		absences  []models.Absence
+		notBefore time.Time
+		notAfter  time.Time
+		want      []want
+	}{
+		{"новые занятия на все места", free(9, 10), nil, nil, at(0), time.Time{},
+			[]want{{9, 0, 4, 4}, {10, 0, 4, 4}}},
+		{"занятие заменяет свободный слот", free(9, 10, 11), []models.GroupSession{session(5, 10, 4, 1)}, nil, at(0), time.Time{},
+			[]want{{9, 0, 4, 4}, {10, 5, 4, 3}, {11, 0, 4, 4}}},
+		{"вместимость занятия, а не секции", nil, []models.GroupSession{session(5, 13, 6, 2)}, nil, at(0), time.Time{},
+			[]want{{13, 5, 6, 4}}},
+		{"занятие без мест", free(9), []models.GroupSession{session(5, 12, 3, 3)}, nil, at(0), time.Time{},
+			[]want{{9, 0, 4, 4}}},
+		{"мест меньше, чем занято", nil, []models.GroupSession{session(5, 12, 2, 3)}, nil, at(0), time.Time{},
+			[]want{}},
+		{"занятие раньше окна записи", nil, []models.GroupSession{session(5, 8, 4, 1)}, nil, at(9), time.Time{},
+			[]want{}},
+		{"занятие позже окна записи", nil, []models.GroupSession{session(5, 15, 4, 1)}, nil, at(0), at(14),
+			[]want{}},
+		{"занятие в отсутствие сотрудника", nil, []models.GroupSession{session(5, 14, 4, 1)},
+			[]models.Absence{{StartAt: at(13), EndAt: at(16)}}, at(0), time.Time{},
+			[]want{}},
+		{"сортировка по времени", free(11), []models.GroupSession{session(6, 12, 4, 0), session(5, 9, 4, 2)}, nil, at(0), time.Time{},
+			[]want{{9, 5, 4, 2}, {11, 0, 4, 4}, {12, 6, 4, 4}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(nil, &fakeEmployees{}, nil, nil, &fakeAbsences{absences: tt.absences}, &fakeHolidays{},
+				&fakeSessions{sessions: tt.sessions})
+
+			section := &models.Section{UserID: 1, DurationMinutes: 60, Capacity: 4}
+			section.ID = 7
+			query := SectionQuery{Section: section, From: at(0), To: at(0), Location: time.UTC}
+
+			slots, err := engine.groupSlots(context.Background(), query, 1, section.Terms(nil), tt.free, tt.notBefore, tt.notAfter)
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+
+			if len(slots) != len(tt.want) {
+				t.Fatalf("получено %d слотов %+v, ожидалось %d", len(slots), slots, len(tt.want))
+			}
+			for i, w := range tt.want {
+				slot := slots[i]
+				var sessionID uint
+				if slot.SessionID != nil {
+					sessionID = *slot.SessionID
+				}
+				if !slot.Start.Equal(at(w.hour)) || sessionID != w.sessionID || slot.Capacity != w.capacity || slot.Available != w.available {
+					t.Fatalf("слот %d: %s, занятие %d, мест %d/%d, ожидалось %d:00, занятие %d, мест %d/%d",
+						i, slot.Start.Format("15:04"), sessionID, slot.Available, slot.Capacity, w.hour, w.sessionID, w.available, w.capacity)
+				}
+			}
+		})
+	}
+}
+
+func TestResourceIntervals(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2040, 3, 15, h, 0, 0, 0, time.UTC) }
+	sessionID := uint(9)
+	appointment := func(id uint, start, end int, session *uint) models.Appointment {
+		a := models.Appointment{StartAt: at(start), EndAt: at(end), SessionID: session, BufferBeforeMinutes: 30}
+		a.ID = id
+		return a
+	}
+
+	appointments := []models.Appointment{
+		appointment(1, 9, 10, nil),
+		appointment(2, 11, 12, &sessionID),
+		appointment(3, 13, 14, nil),
+	}
+
+	tests := []struct {
+		name       string
+		resources  []uint
+		sessionID  uint
+		excludeIDs []uint
+		want       []Interval
+	}{
+		{"без ресурсов", nil, 0, nil, nil},
+		{"все записи без буферов", []uint{3}, 0, nil, []Interval{{at(9), at(10)}, {at(11), at(12)}, {at(13), at(14)}}},
+		{"места своего занятия", []uint{3}, sessionID, nil, []Interval{{at(9), at(10)}, {at(13), at(14)}}},
+		{"заменяемые записи", []uint{3}, 0, []uint{1, 3}, []Interval{{at(11), at(12)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeAppointments{byResources: appointments}
+			engine := NewEngine(nil, nil, nil, fake, nil, nil, nil)
+
+			got, err := engine.resourceIntervals(context.Background(), tt.resources, at(0), at(24), tt.sessionID, tt.excludeIDs...)
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if tt.resources == nil {
+				if got != nil || fake.resourceIDs != nil {
+					t.Fatalf("без ресурсов запрошены записи: %v", got)
+				}
+				return
+			}
+			equalIntervals(t, got, tt.want)
+		})
+	}
+}
+
+func TestConflictsResource(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2040, 3, 15, h, m, 0, 0, time.UTC) }
+	occupied := func(id uint, start, end time.Time) models.Appointment {
+		a := models.Appointment{StartAt: start, EndAt: end}
+		a.ID = id
+		return a
+	}
+
+	tests := []struct {
+		name        string
+		resources   []uint
+		byResources []models.Appointment
+		excludeIDs  []uint
+		want        map[int]ConflictReason
+	}{
+		{"ресурс свободен", []uint{3}, nil, nil, map[int]ConflictReason{}},
+		{"ресурс занят", []uint{3}, []models.Appointment{occupied(7, at(10, 30), at(11, 30))}, nil,
+			map[int]ConflictReason{0: ConflictResource}},
+		{"смежные записи", []uint{3}, []models.Appointment{occupied(7, at(11, 0), at(12, 0))}, nil, map[int]ConflictReason{}},
+		// Буферы относятся к сотруднику, ресурс занят только на время записи
+		{"пересечение только с буфером", []uint{3}, []models.Appointment{occupied(7, at(11, 0), at(11, 10))}, nil, map[int]ConflictReason{}},
+		{"заменяемая запись", []uint{3}, []models.Appointment{occupied(7, at(10, 30), at(11, 30))}, []uint{7}, map[int]ConflictReason{}},
+		{"услуга без ресурсов", nil, []models.Appointment{occupied(7, at(10, 30), at(11, 30))}, nil, map[int]ConflictReason{}},
+		{"второе вхождение", []uint{3}, []models.Appointment{occupied(7, at(14, 0), at(15, 0))}, nil,
+			map[int]ConflictReason{1: ConflictResource}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewEngine(nil, &fakeEmployees{}, nil, &fakeAppointments{byResources: tt.byResources}, &fakeAbsences{}, &fakeHolidays{}, nil)
+			terms := models.ServiceTerms{Duration: time.Hour, BufferAfter: 15 * time.Minute, Resources: tt.resources}
+			intervals := []Interval{{at(10, 0), at(11, 0)}, {at(14, 0), at(15, 0)}}
+
+			conflicts, err := engine.Conflicts(context.Background(), 1, 1, intervals, terms, tt.excludeIDs)
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if len(conflicts) != len(tt.want) {
+				t.Fatalf("конфликты %v, ожидались %v", conflicts, tt.want)
+			}
+			for i, reason := range tt.want {
+				if conflicts[i] != reason {
+					t.Fatalf("конфликты %v, ожидались %v", conflicts, tt.want)
+				}
+			}
+		})
+	}
+}
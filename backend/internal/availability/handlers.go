@@ -0,0 +1,176 @@
+package availability
+
+import (
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/section_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/types"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultStep  = 15 * time.Minute
+	maxRangeDays = 31
+	// Сотрудников секции, для которых считается расписание
+	maxEmployees = 100
+)
+
+type AvailabilityHandlers struct {
+	mux       *http.ServeMux
+	logger    *zerolog.Logger
+	engine    *Engine
+	employees employee_repository.EmployeeRepository
+	sections  section_repository.SectionRepository
+}
+
+func NewAvailabilityHandlers(mux *http.ServeMux, logger *zerolog.Logger, engine *Engine, employees employee_repository.EmployeeRepository, sections section_repository.SectionRepository) *AvailabilityHandlers {
+	availabilityHandlers := &AvailabilityHandlers{
+		mux:       mux,
+		logger:    logger,
+		engine:    engine,
+		employees: employees,
+		sections:  sections,
+	}
+
+	read := middleware.RequirePermission(consts.PermAppointmentsRead)
+
+	availabilityHandlers.mux.Handle("GET /api/availability", read(http.HandlerFunc(availabilityHandlers.availability)))
+
+	return availabilityHandlers
+}
+
+// Свободные слоты секции. Параметры: section (обязательный), employee,
+// from и to - даты YYYY-MM-DD включительно, step - в минутах, duration -
+// в минутах, по умолчанию длительность услуги у каждого сотрудника,
+// tz - часовой пояс IANA дат и времени слотов, по умолчанию пояс владельца.
+// Буферы и окно записи берутся из параметров секции и сотрудника
+func (h *AvailabilityHandlers) availability(w http.ResponseWriter, r *http.Request) {
+	sectionID := httputils.QueryInt(r, "section", 0)
+	if sectionID <= 0 {
+		httputils.SendError(w, "Не указана секция", http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	section, err := h.sections.GetById(r.Context(), uint(sectionID))
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении секции", http.StatusInternalServerError)
+		return
+	}
+
+	if section == nil || !claims.CanAccess(section.UserID) {
+		httputils.SendError(w, "Секция не найдена", http.StatusNotFound)
+		return
+	}
+
+	ownerLoc, err := h.engine.OwnerLocation(section.UserID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении часового пояса", http.StatusInternalServerError)
+		return
+	}
+
+	loc, err := httputils.QueryLocation(r, "tz", ownerLoc)
+	if err != nil {
+		httputils.SendError(w, "Некорректный часовой пояс", http.StatusBadRequest)
+		return
+	}
+
+	from, err := httputils.QueryDate(r, "from", loc)
+	if err != nil || from == nil {
+		httputils.SendError(w, "Некорректная дата from", http.StatusBadRequest)
+		return
+	}
+
+	to, err := httputils.QueryDate(r, "to", loc)
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата to", http.StatusBadRequest)
+		return
+	}
+	if to == nil {
+		to = from
+	}
+
+	if to.Before(*from) || to.After(from.AddDate(0, 0, maxRangeDays)) {
+		httputils.SendError(w, "Некорректный период", http.StatusBadRequest)
+		return
+	}
+
+	duration := time.Duration(httputils.QueryInt(r, "duration", 0)) * time.Minute
+	step := time.Duration(httputils.QueryInt(r, "step", int(defaultStep/time.Minute))) * time.Minute
+	if duration < 0 || step <= 0 {
+		httputils.SendError(w, "Некорректная длительность", http.StatusBadRequest)
+		return
+	}
+
+	employeeIDs, ok := h.sectionEmployees(w, r, section)
+	if !ok {
+		return
+	}
+
+	assignments, err := h.employees.GetSectionAssignments(r.Context(), section.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении сотрудников секции", http.StatusInternalServerError)
+		return
+	}
+
+	slots, err := h.engine.SectionSlots(r.Context(), SectionQuery{
+		Section:     section,
+		EmployeeIDs: employeeIDs,
+		Overrides:   OverridesByEmployee(assignments),
+		From:        *from,
+		To:          *to,
+		Location:    loc,
+		Duration:    duration,
+		Step:        step,
+		Now:         time.Now(),
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при расчете свободного времени секции: %d", section.ID)
+		httputils.SendError(w, "Ошибка при расчете свободного времени", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, slots)
+}
+
+// Сотрудник из параметра employee или все активные сотрудники секции.
+// При ошибке сам отправляет ответ
+func (h *AvailabilityHandlers) sectionEmployees(w http.ResponseWriter, r *http.Request, section *models.Section) ([]uint, bool) {
+	if employeeID := httputils.QueryInt(r, "employee", 0); employeeID > 0 {
+		employee, err := h.employees.GetById(r.Context(), uint(employeeID))
+		if err != nil {
+			httputils.SendError(w, "Ошибка при получении сотрудника", http.StatusInternalServerError)
+			return nil, false
+		}
+
+		if employee == nil || employee.UserID != section.UserID || !employee.IsActive || !employee.HasSection(section.ID) {
+			httputils.SendError(w, "Сотрудник не найден", http.StatusNotFound)
+			return nil, false
+		}
+
+		return []uint{employee.ID}, true
+	}
+
+	employees, err := h.employees.GetAllWithPagination(r.Context(), maxEmployees, 1, models.EmployeeFilter{
+		UserID:    section.UserID,
+		SectionID: section.ID,
+		IsActive:  types.CheckValueTrue,
+	})
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении сотрудников", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	ids := make([]uint, len(employees.Employees))
+	for i, employee := range employees.Employees {
+		ids[i] = employee.ID
+	}
+	return ids, true
+}
@@ -0,0 +1,103 @@
+package availability
+
+import (
+	"sort"
+	"time"
+)
+
+// Полуоткрытый интервал времени [Start, End)
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (i Interval) Contains(other Interval) bool {
+	return !other.Start.Before(i.Start) && !other.End.After(i.End)
+}
+
+func (i Interval) Overlaps(other Interval) bool {
+	return i.Start.Before(other.End) && other.Start.Before(i.End)
+}
+
+func overlapsAny(intervals []Interval, other Interval) bool {
+	for _, interval := range intervals {
+		if interval.Overlaps(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// Части интервалов, попадающие в [from, to)
+func clip(intervals []Interval, from, to time.Time) []Interval {
+	result := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if interval.Start.Before(from) {
+			interval.Start = from
+		}
+		if interval.End.After(to) {
+			interval.End = to
+		}
+		if interval.End.After(interval.Start) {
+			result = append(result, interval)
+		}
+	}
+	return result
+}
+
+// Сортирует интервалы и объединяет пересекающиеся и смежные
+func normalize(intervals []Interval) []Interval {
+	result := make([]Interval, 0, len(intervals))
+	for _, interval := range intervals {
+		if interval.End.After(interval.Start) {
+			result = append(result, interval)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+
+	merged := result[:0]
+	for _, interval := range result {
+		last := len(merged) - 1
+		if last >= 0 && !interval.Start.After(merged[last].End) {
+			if interval.End.After(merged[last].End) {
+				merged[last].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
+
+// Вычитает из base все интервалы cut
+func subtract(base []Interval, cut []Interval) []Interval {
+	base = normalize(base)
+	cut = normalize(cut)
+
+	result := make([]Interval, 0, len(base))
+	for _, interval := range base {
+		current := interval
+		for _, c := range cut {
+			if !c.End.After(current.Start) {
+				continue
+			}
+			if !c.Start.Before(current.End) {
+				break
+			}
+			if c.Start.After(current.Start) {
+				result = append(result, Interval{Start: current.Start, End: c.Start})
+			}
+			current.Start = c.End
+			if !current.End.After(current.Start) {
+				break
+			}
+		}
+		if current.End.After(current.Start) {
+			result = append(result, current)
+		}
+	}
+	return result
+}
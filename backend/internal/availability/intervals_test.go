@@ -0,0 +1,124 @@
+package availability
+
+import (
+	"testing"
+	"time"
+)
+
+var base = time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+
+// Интервал в часах от base
+func hours(from, to float64) Interval {
+	return Interval{
+		Start: base.Add(time.Duration(from * float64(time.Hour))),
+		End:   base.Add(time.Duration(to * float64(time.Hour))),
+	}
+}
+
+func equalIntervals(t *testing.T, got, want []Interval) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("получено %d интервалов %v, ожидалось %d %v", len(got), got, len(want), want)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) {
+			t.Fatalf("интервал %d: получено [%s, %s), ожидалось [%s, %s)", i,
+				got[i].Start, got[i].End, want[i].Start, want[i].End)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []Interval
+		want      []Interval
+	}{
+		{"пусто", nil, []Interval{}},
+		{"пустые и обратные отбрасываются", []Interval{hours(1, 1), hours(3, 2)}, []Interval{}},
+		{"сортировка", []Interval{hours(5, 6), hours(1, 2)}, []Interval{hours(1, 2), hours(5, 6)}},
+		{"пересекающиеся", []Interval{hours(1, 3), hours(2, 4)}, []Interval{hours(1, 4)}},
+		{"смежные", []Interval{hours(1, 2), hours(2, 3)}, []Interval{hours(1, 3)}},
+		{"вложенный", []Interval{hours(1, 5), hours(2, 3)}, []Interval{hours(1, 5)}},
+		{"цепочка", []Interval{hours(4, 6), hours(1, 2), hours(2, 4), hours(8, 9)}, []Interval{hours(1, 6), hours(8, 9)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equalIntervals(t, normalize(tt.intervals), tt.want)
+		})
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	tests := []struct {
+		name string
+		base []Interval
+		cut  []Interval
+		want []Interval
+	}{
+		{"без вычитаемых", []Interval{hours(9, 18)}, nil, []Interval{hours(9, 18)}},
+		{"в середине", []Interval{hours(9, 18)}, []Interval{hours(12, 13)}, []Interval{hours(9, 12), hours(13, 18)}},
+		{"с начала", []Interval{hours(9, 18)}, []Interval{hours(8, 10)}, []Interval{hours(10, 18)}},
+		{"до конца", []Interval{hours(9, 18)}, []Interval{hours(17, 20)}, []Interval{hours(9, 17)}},
+		{"целиком", []Interval{hours(9, 18)}, []Interval{hours(8, 19)}, []Interval{}},
+		{"смежный не режет", []Interval{hours(9, 18)}, []Interval{hours(18, 19), hours(7, 9)}, []Interval{hours(9, 18)}},
+		{"несколько", []Interval{hours(9, 18)}, []Interval{hours(10, 11), hours(14, 15)},
+			[]Interval{hours(9, 10), hours(11, 14), hours(15, 18)}},
+		{"несколько базовых", []Interval{hours(9, 12), hours(13, 18)}, []Interval{hours(11, 14)},
+			[]Interval{hours(9, 11), hours(14, 18)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			equalIntervals(t, subtract(tt.base, tt.cut), tt.want)
+		})
+	}
+}
+
+func TestClip(t *testing.T) {
+	tests := []struct {
+		name      string
+		intervals []Interval
+		from, to  float64
+		want      []Interval
+	}{
+		{"внутри", []Interval{hours(10, 12)}, 0, 24, []Interval{hours(10, 12)}},
+		{"обрезка с двух сторон", []Interval{hours(-2, 2), hours(22, 26)}, 0, 24, []Interval{hours(0, 2), hours(22, 24)}},
+		{"вне диапазона", []Interval{hours(-5, -1), hours(24, 30)}, 0, 24, []Interval{}},
+		{"через границу суток", []Interval{hours(20, 28)}, 24, 48, []Interval{hours(24, 28)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := base.Add(time.Duration(tt.from * float64(time.Hour)))
+			to := base.Add(time.Duration(tt.to * float64(time.Hour)))
+			equalIntervals(t, clip(tt.intervals, from, to), tt.want)
+		})
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Interval
+		want bool
+	}{
+		{"пересекаются", hours(1, 3), hours(2, 4), true},
+		{"вложенный", hours(1, 5), hours(2, 3), true},
+		{"смежные", hours(1, 2), hours(2, 3), false},
+		{"раздельные", hours(1, 2), hours(3, 4), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Overlaps(tt.b); got != tt.want {
+				t.Fatalf("Overlaps = %v, ожидалось %v", got, tt.want)
+			}
+			if got := tt.b.Overlaps(tt.a); got != tt.want {
+				t.Fatalf("Overlaps в обратном порядке = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package availability
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("пояс %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestSplitIntoSlots(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	newYork := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		interval Interval
+		duration time.Duration
+		step     time.Duration
+		// Начала слотов по местному времени
+		want []string
+	}{
+		{
+			name:     "шаг по границам",
+			loc:      time.UTC,
+			interval: Interval{time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), time.Date(2025, 3, 10, 11, 0, 0, 0, time.UTC)},
+			duration: time.Hour,
+			step:     30 * time.Minute,
+			want:     []string{"09:00", "09:30", "10:00"},
+		},
+		{
+			name:     "начало выравнивается по шагу",
+			loc:      time.UTC,
+			interval: Interval{time.Date(2025, 3, 10, 9, 10, 0, 0, time.UTC), time.Date(2025, 3, 10, 10, 30, 0, 0, time.UTC)},
+			duration: 30 * time.Minute,
+			step:     15 * time.Minute,
+			want:     []string{"09:15", "09:30", "09:45", "10:00"},
+		},
+		{
+			name:     "не помещается",
+			loc:      time.UTC,
+			interval: Interval{time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), time.Date(2025, 3, 10, 9, 20, 0, 0, time.UTC)},
+			duration: 30 * time.Minute,
+			step:     15 * time.Minute,
+			want:     []string{},
+		},
+		{
+			name:     "через полночь",
+			loc:      time.UTC,
+			interval: Interval{time.Date(2025, 3, 10, 23, 0, 0, 0, time.UTC), time.Date(2025, 3, 11, 1, 0, 0, 0, time.UTC)},
+			duration: time.Hour,
+			step:     time.Hour,
+			want:     []string{"23:00", "00:00"},
+		},
+		{
+			// 30 марта 2025 в 02:00 часы переводятся на 03:00
+			name:     "переход на летнее время",
+			loc:      berlin,
+			interval: Interval{time.Date(2025, 3, 30, 1, 0, 0, 0, berlin), time.Date(2025, 3, 30, 4, 0, 0, 0, berlin)},
+			duration: time.Hour,
+			step:     time.Hour,
+			want:     []string{"01:00", "03:00"},
+		},
+		{
+			// 2 ноября 2025 в 02:00 часы переводятся на 01:00: местное
+			// 01:00 повторяется, слот по нему один
+			name:     "переход на зимнее время",
+			loc:      newYork,
+			interval: Interval{time.Date(2025, 11, 2, 0, 0, 0, 0, newYork), time.Date(2025, 11, 2, 4, 0, 0, 0, newYork)},
+			duration: time.Hour,
+			step:     time.Hour,
+			want:     []string{"00:00", "01:00", "02:00", "03:00"},
+		},
+		{
+			name:     "пустой шаг",
+			loc:      time.UTC,
+			interval: Interval{time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC), time.Date(2025, 3, 10, 11, 0, 0, 0, time.UTC)},
+			duration: time.Hour,
+			step:     0,
+			want:     []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slots := splitIntoSlots(tt.interval, tt.duration, tt.step, tt.loc)
+
+			got := make([]string, len(slots))
+			for i, slot := range slots {
+				got[i] = slot.Start.In(tt.loc).Format("15:04")
+				if slot.End.Sub(slot.Start) != tt.duration {
+					t.Fatalf("слот %s длится %s, ожидалось %s", got[i], slot.End.Sub(slot.Start), tt.duration)
+				}
+				if slot.Start.Before(tt.interval.Start) || slot.End.After(tt.interval.End) {
+					t.Fatalf("слот %s выходит за интервал", got[i])
+				}
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("получены слоты %v, ожидались %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("получены слоты %v, ожидались %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDayRange(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+
+	// День перехода на летнее время длится 23 часа
+	from := time.Date(2025, 3, 30, 12, 0, 0, 0, berlin)
+	start, end := dayRange(from, from, berlin)
+
+	if !start.Equal(time.Date(2025, 3, 30, 0, 0, 0, 0, berlin)) {
+		t.Fatalf("начало дня %s", start)
+	}
+	if end.Sub(start) != 23*time.Hour {
+		t.Fatalf("день длится %s, ожидалось 23h", end.Sub(start))
+	}
+}
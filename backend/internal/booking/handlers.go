@@ -0,0 +1,559 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"record-services/internal/availability"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/reminder"
+	"record-services/internal/repositories/appointment_repository"
+	"record-services/internal/repositories/booking_repository"
+	"record-services/internal/repositories/client_repository"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/section_repository"
+	"record-services/internal/repositories/session_repository"
+	"record-services/internal/waitlist"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/mailer"
+	"record-services/pkg/phone"
+	"record-services/pkg/ratelimit"
+	"record-services/pkg/timezone"
+	"record-services/pkg/types"
+	"record-services/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+const (
+	slotStep     = 15 * time.Minute
+	maxRangeDays = 31
+	// Услуг и сотрудников, показываемых на странице записи
+	maxItems = 100
+)
+
+// Онлайн-запись клиентов без аккаунта. Страница владельца находится
+// по адресу {slug}, запись создается в статусе pending, а управлять ею
+// клиент может по ссылке с токеном
+type BookingHandlers struct {
+	mux          *http.ServeMux
+	logger       *zerolog.Logger
+	repository   booking_repository.BookingRepository
+	sections     section_repository.SectionRepository
+	employees    employee_repository.EmployeeRepository
+	appointments appointment_repository.AppointmentRepository
+	clients      client_repository.ClientRepository
+	sessions     session_repository.SessionRepository
+	engine       *availability.Engine
+	waitlist     *waitlist.Worker
+	reminders    *reminder.Scheduler
+	validator    *validator.Validate
+	mailer       mailer.Mailer
+	appURL       string
+}
+
+func NewBookingHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository booking_repository.BookingRepository, sections section_repository.SectionRepository, employees employee_repository.EmployeeRepository, appointments appointment_repository.AppointmentRepository, clients client_repository.ClientRepository, sessions session_repository.SessionRepository, engine *availability.Engine, waitlist *waitlist.Worker, reminders *reminder.Scheduler, validator *validator.Validate, mailer mailer.Mailer, limiterStore ratelimit.Store, appURL string) *BookingHandlers {
+	bookingHandlers := &BookingHandlers{
+		mux:          mux,
+		logger:       logger,
+		repository:   repository,
+		sections:     sections,
+		employees:    employees,
+		appointments: appointments,
+		clients:      clients,
+		sessions:     sessions,
+		engine:       engine,
+		waitlist:     waitlist,
+		reminders:    reminders,
+		validator:    validator,
+		mailer:       mailer,
+		appURL:       appURL,
+	}
+
+	read := middleware.RequirePermission(consts.PermSectionsRead)
+	write := middleware.RequirePermission(consts.PermSectionsWrite)
+	limits := newBookingLimits(limiterStore)
+
+	// Настройка страницы владельцем
+	bookingHandlers.mux.Handle("GET /api/booking-page", read(http.HandlerFunc(bookingHandlers.getPage)))
+	bookingHandlers.mux.Handle("PUT /api/booking-page", write(http.HandlerFunc(bookingHandlers.savePage)))
+
+	// Публичная часть, авторизация не требуется
+	bookingHandlers.mux.Handle("GET /api/public/{slug}", limits.read(http.HandlerFunc(bookingHandlers.publicPage)))
+	bookingHandlers.mux.Handle("GET /api/public/{slug}/employees", limits.read(http.HandlerFunc(bookingHandlers.publicEmployees)))
+	bookingHandlers.mux.Handle("GET /api/public/{slug}/availability", limits.read(http.HandlerFunc(bookingHandlers.publicAvailability)))
+	bookingHandlers.mux.Handle("POST /api/public/{slug}/appointments", limits.create(http.HandlerFunc(bookingHandlers.createAppointment)))
+	bookingHandlers.mux.Handle("GET /api/public/appointments/manage/{token}", limits.manage(http.HandlerFunc(bookingHandlers.getManaged)))
+	bookingHandlers.mux.Handle("POST /api/public/appointments/manage/{token}/cancel", limits.manage(http.HandlerFunc(bookingHandlers.cancelManaged)))
+	bookingHandlers.mux.Handle("POST /api/public/appointments/manage/{token}/reschedule", limits.manage(http.HandlerFunc(bookingHandlers.rescheduleManaged)))
+
+	return bookingHandlers
+}
+
+type sectionResponse struct {
+	ID              uint   `json:"id"`
+	Name            string `json:"name"`
+	Comment         string `json:"comment"`
+	DurationMinutes int    `json:"duration_minutes"`
+	Price           int64  `json:"price"`
+	Currency        string `json:"currency"`
+	Capacity        int    `json:"capacity"`
+}
+
+type employeeResponse struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type appointmentResponse struct {
+	Status       models.AppointmentStatus `json:"status"`
+	StartAt      time.Time                `json:"start_at"`
+	EndAt        time.Time                `json:"end_at"`
+	TimeZone     string                   `json:"time_zone"`
+	SectionID    uint                     `json:"section_id"`
+	SectionName  string                   `json:"section_name"`
+	EmployeeID   uint                     `json:"employee_id"`
+	EmployeeName string                   `json:"employee_name"`
+	ClientName   string                   `json:"client_name"`
+	Price        int64                    `json:"price"`
+	Currency     string                   `json:"currency"`
+	// Только в ответе на создание записи, действует до окончания записи
+	ManageToken string `json:"manage_token,omitempty"`
+}
+
+// Время - в часовом поясе записи
+func toAppointmentResponse(appointment *models.Appointment) appointmentResponse {
+	loc := appointment.Location()
+
+	return appointmentResponse{
+		Status:       appointment.Status,
+		StartAt:      appointment.StartAt.In(loc),
+		EndAt:        appointment.EndAt.In(loc),
+		TimeZone:     appointment.TimeZone,
+		SectionID:    appointment.SectionID,
+		SectionName:  appointment.Section.Name,
+		EmployeeID:   appointment.EmployeeID,
+		EmployeeName: appointment.Employee.Name,
+		ClientName:   appointment.ClientName,
+		Price:        appointment.Price,
+		Currency:     appointment.Currency,
+	}
+}
+
+// Описание страницы, часовой пояс владельца и список услуг
+func (h *BookingHandlers) publicPage(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.resolvePage(w, r)
+	if !ok {
+		return
+	}
+
+	loc, err := h.engine.OwnerLocation(page.UserID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении часового пояса", http.StatusInternalServerError)
+		return
+	}
+
+	sections, err := h.sections.GetAllWithPagination(r.Context(), maxItems, 1, models.SectionFilter{UserID: page.UserID})
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении услуг", http.StatusInternalServerError)
+		return
+	}
+
+	items := make([]sectionResponse, len(sections.Sections))
+	for i, section := range sections.Sections {
+		items[i] = sectionResponse{
+			ID:              section.ID,
+			Name:            section.Name,
+			Comment:         section.Comment,
+			DurationMinutes: section.DurationMinutes,
+			Price:           section.Price,
+			Currency:        section.Currency,
+			Capacity:        section.Capacity,
+		}
+	}
+
+	httputils.SendJSONResponse(w, map[string]any{
+		"slug":        page.Slug,
+		"title":       page.Title,
+		"description": page.Description,
+		"time_zone":   loc.String(),
+		"sections":    items,
+	})
+}
+
+// Активные сотрудники услуги section
+func (h *BookingHandlers) publicEmployees(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.resolvePage(w, r)
+	if !ok {
+		return
+	}
+
+	section, ok := h.resolveSection(w, r, page, uint(max(httputils.QueryInt(r, "section", 0), 0)))
+	if !ok {
+		return
+	}
+
+	employees, ok := h.candidates(w, r, section, 0)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, employees)
+}
+
+// Свободные слоты услуги. Параметры: section (обязательный), employee,
+// from и to - даты YYYY-MM-DD включительно, tz - часовой пояс IANA дат
+// и времени слотов, по умолчанию пояс владельца
+func (h *BookingHandlers) publicAvailability(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.resolvePage(w, r)
+	if !ok {
+		return
+	}
+
+	ownerLoc, err := h.engine.OwnerLocation(page.UserID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении часового пояса", http.StatusInternalServerError)
+		return
+	}
+
+	loc, err := httputils.QueryLocation(r, "tz", ownerLoc)
+	if err != nil {
+		httputils.SendError(w, "Некорректный часовой пояс", http.StatusBadRequest)
+		return
+	}
+
+	from, err := httputils.QueryDate(r, "from", loc)
+	if err != nil || from == nil {
+		httputils.SendError(w, "Некорректная дата from", http.StatusBadRequest)
+		return
+	}
+
+	to, err := httputils.QueryDate(r, "to", loc)
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата to", http.StatusBadRequest)
+		return
+	}
+	if to == nil {
+		to = from
+	}
+
+	if to.Before(*from) || to.After(from.AddDate(0, 0, maxRangeDays)) {
+		httputils.SendError(w, "Некорректный период", http.StatusBadRequest)
+		return
+	}
+
+	section, ok := h.resolveSection(w, r, page, uint(max(httputils.QueryInt(r, "section", 0), 0)))
+	if !ok {
+		return
+	}
+
+	employees, ok := h.candidates(w, r, section, uint(max(httputils.QueryInt(r, "employee", 0), 0)))
+	if !ok {
+		return
+	}
+
+	slots, _, err := h.sectionSlots(r.Context(), section, employeeIDs(employees), *from, *to, loc, 0)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при расчете свободного времени секции: %d", section.ID)
+		httputils.SendError(w, "Ошибка при расчете свободного времени", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, slots)
+}
+
+// Запись на свободный слот. Без employeeId выбирается первый сотрудник,
+// свободный в это время. На групповую услугу запись занимает место
+// в занятии. Клиент находится по телефону или создается
+func (h *BookingHandlers) createAppointment(w http.ResponseWriter, r *http.Request) {
+	var bookingData struct {
+		SectionID        uint      `json:"sectionId" validate:"required"`
+		EmployeeID       uint      `json:"employeeId"`
+		StartAt          time.Time `json:"startAt" validate:"required"`
+		ClientName       string    `json:"clientName" validate:"required,min=1,max=255"`
+		ClientPhone      string    `json:"clientPhone" validate:"required,max=32"`
+		ClientEmail      string    `json:"clientEmail" validate:"omitempty,email,max=255"`
+		Comment          string    `json:"comment" validate:"max=1000"`
+		RemindersConsent bool      `json:"remindersConsent"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &bookingData) {
+		return
+	}
+
+	clientPhone, err := phone.Normalize(bookingData.ClientPhone)
+	if err != nil {
+		httputils.SendError(w, "Некорректный номер телефона", http.StatusBadRequest)
+		return
+	}
+	clientEmail := strings.ToLower(strings.TrimSpace(bookingData.ClientEmail))
+
+	page, ok := h.resolvePage(w, r)
+	if !ok {
+		return
+	}
+
+	section, ok := h.resolveSection(w, r, page, bookingData.SectionID)
+	if !ok {
+		return
+	}
+
+	employees, ok := h.candidates(w, r, section, bookingData.EmployeeID)
+	if !ok {
+		return
+	}
+
+	start := bookingData.StartAt.UTC()
+	slot, overrides, ok := h.findSlot(w, r, section, employeeIDs(employees), start, 0)
+	if !ok {
+		return
+	}
+
+	// Контакты не подтверждены: запись привязывается только к новой
+	// карточке, с существующей ее свяжет сотрудник
+	client, err := h.clients.CreateIfUnknown(r.Context(), &models.Client{
+		UserID:           page.UserID,
+		Name:             bookingData.ClientName,
+		Phone:            clientPhone,
+		Email:            clientEmail,
+		RemindersConsent: bookingData.RemindersConsent,
+	})
+	if err != nil {
+		httputils.SendError(w, "Ошибка при сохранении клиента", http.StatusInternalServerError)
+		return
+	}
+
+	var clientID *uint
+	if client != nil {
+		clientID = &client.ID
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Ошибка при генерации токена управления записью")
+		httputils.SendError(w, "Ошибка при создании записи", http.StatusInternalServerError)
+		return
+	}
+	tokenHash := utils.HashToken(token)
+
+	location, err := h.engine.Location(r.Context(), slot.EmployeeID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при создании записи", http.StatusInternalServerError)
+		return
+	}
+
+	terms := section.Terms(overrides[slot.EmployeeID])
+	appointment := &models.Appointment{
+		UserID:              page.UserID,
+		EmployeeID:          slot.EmployeeID,
+		SectionID:           section.ID,
+		Section:             *section,
+		ClientID:            clientID,
+		ClientName:          bookingData.ClientName,
+		ClientPhone:         clientPhone,
+		ClientEmail:         clientEmail,
+		RemindersConsent:    bookingData.RemindersConsent,
+		StartAt:             slot.Start.UTC(),
+		EndAt:               slot.End.UTC(),
+		Status:              models.AppointmentStatusPending,
+		Comment:             bookingData.Comment,
+		TimeZone:            location.String(),
+		Price:               terms.Price,
+		Currency:            terms.Currency,
+		BufferBeforeMinutes: int(terms.BufferBefore / time.Minute),
+		BufferAfterMinutes:  int(terms.BufferAfter / time.Minute),
+		ResourceIDs:         types.IDList(terms.Resources),
+		ManageTokenHash:     &tokenHash,
+	}
+	for _, employee := range employees {
+		if employee.ID == slot.EmployeeID {
+			appointment.Employee.Name = employee.Name
+		}
+	}
+
+	if section.IsGroup() {
+		err = h.sessions.BookSeat(r.Context(), appointment, terms.Capacity)
+	} else {
+		_, err = h.appointments.Create(r.Context(), appointment)
+	}
+	if err != nil {
+		sendSaveError(w, err, "Ошибка при создании записи")
+		return
+	}
+
+	h.logger.Info().Msgf("Онлайн-запись %d к сотруднику %d на %s", appointment.ID, appointment.EmployeeID, appointment.StartAt.Format(time.RFC3339))
+
+	h.reminders.Schedule(r.Context(), appointment)
+
+	if appointment.ClientEmail != "" {
+		h.sendManageLink(r.Context(), page, appointment, token)
+	}
+
+	response := toAppointmentResponse(appointment)
+	response.ManageToken = token
+
+	httputils.SendJSONWithStatus(w, response, http.StatusCreated)
+}
+
+// Страница владельца по {slug} из пути. Выключенные страницы неотличимы
+// от несуществующих. При ошибке сам отправляет ответ
+func (h *BookingHandlers) resolvePage(w http.ResponseWriter, r *http.Request) (*models.BookingPage, bool) {
+	page, err := h.repository.GetBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении страницы записи", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if page == nil || !page.IsEnabled {
+		httputils.SendError(w, "Страница записи не найдена", http.StatusNotFound)
+		return nil, false
+	}
+
+	return page, true
+}
+
+// Услуга владельца страницы. При ошибке сам отправляет ответ
+func (h *BookingHandlers) resolveSection(w http.ResponseWriter, r *http.Request, page *models.BookingPage, sectionID uint) (*models.Section, bool) {
+	if sectionID == 0 {
+		httputils.SendError(w, "Не указана услуга", http.StatusBadRequest)
+		return nil, false
+	}
+
+	section, err := h.sections.GetById(r.Context(), sectionID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении услуги", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if section == nil || section.UserID != page.UserID {
+		httputils.SendError(w, "Услуга не найдена", http.StatusNotFound)
+		return nil, false
+	}
+
+	return section, true
+}
+
+// Сотрудник employeeID или все активные сотрудники услуги.
+// При ошибке сам отправляет ответ
+func (h *BookingHandlers) candidates(w http.ResponseWriter, r *http.Request, section *models.Section, employeeID uint) ([]employeeResponse, bool) {
+	if employeeID != 0 {
+		employee, err := h.employees.GetById(r.Context(), employeeID)
+		if err != nil {
+			httputils.SendError(w, "Ошибка при получении сотрудника", http.StatusInternalServerError)
+			return nil, false
+		}
+
+		if employee == nil || employee.UserID != section.UserID || !employee.IsActive || !employee.HasSection(section.ID) {
+			httputils.SendError(w, "Сотрудник не найден", http.StatusNotFound)
+			return nil, false
+		}
+
+		return []employeeResponse{{ID: employee.ID, Name: employee.Name}}, true
+	}
+
+	employees, err := h.employees.GetAllWithPagination(r.Context(), maxItems, 1, models.EmployeeFilter{
+		UserID:    section.UserID,
+		SectionID: section.ID,
+		IsActive:  types.CheckValueTrue,
+	})
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении сотрудников", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	items := make([]employeeResponse, len(employees.Employees))
+	for i, employee := range employees.Employees {
+		items[i] = employeeResponse{ID: employee.ID, Name: employee.Name}
+	}
+	return items, true
+}
+
+// Свободные слоты услуги и переопределения ее параметров по сотрудникам
+func (h *BookingHandlers) sectionSlots(ctx context.Context, section *models.Section, employeeIDs []uint, from, to time.Time, loc *time.Location, excludeID uint) ([]models.Slot, map[uint]*models.EmployeeSection, error) {
+	assignments, err := h.employees.GetSectionAssignments(ctx, section.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	overrides := availability.OverridesByEmployee(assignments)
+
+	slots, err := h.engine.SectionSlots(ctx, availability.SectionQuery{
+		Section:              section,
+		EmployeeIDs:          employeeIDs,
+		Overrides:            overrides,
+		From:                 from,
+		To:                   to,
+		Location:             loc,
+		Step:                 slotStep,
+		Now:                  time.Now(),
+		ExcludeAppointmentID: excludeID,
+	})
+	return slots, overrides, err
+}
+
+// Свободный слот, начинающийся в start, у первого из сотрудников.
+// Клиент может записаться только на предложенное время, так что
+// все ограничения графика и услуги проверяются расчетом слотов.
+// Слоты считаются за сутки UTC, содержащие start, и соседние с ними:
+// расчет обрезает рабочее время по границам запрошенных суток, поэтому
+// слот, переходящий через полночь UTC, за одни сутки не нашелся бы.
+// При ошибке сам отправляет ответ
+func (h *BookingHandlers) findSlot(w http.ResponseWriter, r *http.Request, section *models.Section, employeeIDs []uint, start time.Time, excludeID uint) (*models.Slot, map[uint]*models.EmployeeSection, bool) {
+	loc := time.UTC
+	day := time.Date(start.In(loc).Year(), start.In(loc).Month(), start.In(loc).Day(), 0, 0, 0, 0, loc)
+
+	slots, overrides, err := h.sectionSlots(r.Context(), section, employeeIDs, day.AddDate(0, 0, -1), day.AddDate(0, 0, 1), loc, excludeID)
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при расчете свободного времени секции: %d", section.ID)
+		httputils.SendError(w, "Ошибка при расчете свободного времени", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	for i := range slots {
+		if slots[i].Start.Equal(start) {
+			return &slots[i], overrides, true
+		}
+	}
+
+	httputils.SendError(w, "Это время недоступно для записи", http.StatusConflict)
+	return nil, nil, false
+}
+
+func (h *BookingHandlers) sendManageLink(ctx context.Context, page *models.BookingPage, appointment *models.Appointment, token string) {
+	err := h.mailer.Send(ctx, mailer.Message{
+		To:      appointment.ClientEmail,
+		Subject: "Запись: " + page.Title,
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nВы записаны на «%s» %s. Запись ожидает подтверждения.\n\nОтменить или перенести запись можно по ссылке:\n%s/booking/manage?token=%s",
+			appointment.ClientName, appointment.Section.Name, timezone.Format(appointment.StartAt, appointment.Location()), h.appURL, token),
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отправке ссылки управления записью: %d", appointment.ID)
+	}
+}
+
+func employeeIDs(employees []employeeResponse) []uint {
+	ids := make([]uint, len(employees))
+	for i, employee := range employees {
+		ids[i] = employee.ID
+	}
+	return ids
+}
+
+// Пересечение с другой записью или нет мест в занятии - 409,
+// остальные ошибки - 500
+func sendSaveError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, consts.ErrConflict) {
+		httputils.SendError(w, "Это время уже занято", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, consts.ErrNoSeats) {
+		httputils.SendError(w, "В занятии нет свободных мест", http.StatusConflict)
+		return
+	}
+	httputils.SendError(w, message, http.StatusInternalServerError)
+}
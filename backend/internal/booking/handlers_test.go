@@ -0,0 +1,165 @@
+package booking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"record-services/internal/availability"
+	"record-services/internal/models"
+	"record-services/internal/repositories/absence_repository"
+	"record-services/internal/repositories/appointment_repository"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/holiday_repository"
+	"record-services/internal/repositories/schedule_repository"
+	"record-services/pkg/consts"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Заглушки репозиториев, которые нужны расчету слотов.
+// Остальные методы интерфейсов не вызываются
+
+type fakeEmployees struct {
+	employee_repository.EmployeeRepository
+	timeZone string
+}
+
+func (f *fakeEmployees) GetTimeZone(ctx context.Context, employeeID uint) (string, error) {
+	return f.timeZone, nil
+}
+
+func (f *fakeEmployees) GetSectionAssignments(ctx context.Context, sectionID uint) ([]models.EmployeeSection, error) {
+	return nil, nil
+}
+
+// Каждый день с start по end по местному времени
+type fakeSchedules struct {
+	schedule_repository.ScheduleRepository
+	start, end models.ClockTime
+}
+
+func (f *fakeSchedules) GetWeekly(ctx context.Context, employeeID uint) ([]models.WeeklySchedule, error) {
+	weekly := make([]models.WeeklySchedule, 7)
+	for day := range weekly {
+		weekly[day] = models.WeeklySchedule{EmployeeID: employeeID, Weekday: time.Weekday(day), Start: f.start, End: f.end}
+	}
+	return weekly, nil
+}
+
+func (f *fakeSchedules) GetOverrides(ctx context.Context, employeeID uint, from, to time.Time) ([]models.ScheduleOverride, error) {
+	return nil, nil
+}
+
+type fakeAbsences struct {
+	absence_repository.AbsenceRepository
+}
+
+func (f *fakeAbsences) GetByEmployee(ctx context.Context, employeeID uint, from, to time.Time) ([]models.Absence, error) {
+	return nil, nil
+}
+
+type fakeHolidays struct {
+	holiday_repository.HolidayRepository
+}
+
+func (f *fakeHolidays) GetHolidaysByOwner(ctx context.Context, userID uint, from, to time.Time) ([]models.Holiday, error) {
+	return nil, nil
+}
+
+type fakeAppointments struct {
+	appointment_repository.AppointmentRepository
+}
+
+func (f *fakeAppointments) GetActiveByEmployee(ctx context.Context, employeeID uint, from, to time.Time) ([]models.Appointment, error) {
+	return nil, nil
+}
+
+func TestFindSlot(t *testing.T) {
+	hour := func(h int) models.ClockTime { return models.ClockTime(h * 60) }
+	// Даты заведомо в будущем, чтобы слоты не отсекались текущим временем
+	at := func(name string, month time.Month, h, m int) time.Time {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Fatalf("пояс %s: %v", name, err)
+		}
+		return time.Date(2040, month, 15, h, m, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name       string
+		timeZone   string
+		start, end models.ClockTime
+		slotStart  time.Time
+		wantStatus int
+	}{
+		// Слоты 23:30-00:30 UTC пересекают полночь UTC
+		{"зимний вечер в Нью-Йорке", "America/New_York", hour(17), hour(22), at("America/New_York", time.January, 18, 30), 0},
+		{"летний вечер в Нью-Йорке", "America/New_York", hour(17), hour(22), at("America/New_York", time.July, 19, 30), 0},
+		{"утро в Токио", "Asia/Tokyo", hour(8), hour(12), at("Asia/Tokyo", time.March, 8, 30), 0},
+		{"после полуночи UTC", "America/New_York", hour(17), hour(23), at("America/New_York", time.July, 20, 30), 0},
+		{"время в UTC", "UTC", hour(9), hour(18), at("UTC", time.March, 9, 0), 0},
+		{"вне графика", "Asia/Tokyo", hour(8), hour(12), at("Asia/Tokyo", time.March, 12, 30), http.StatusConflict},
+		{"не по шагу слотов", "UTC", hour(9), hour(18), at("UTC", time.March, 9, 5), http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zerolog.Nop()
+			employees := &fakeEmployees{timeZone: tt.timeZone}
+			h := &BookingHandlers{
+				logger:    &logger,
+				employees: employees,
+				engine: availability.NewEngine(nil, employees, &fakeSchedules{start: tt.start, end: tt.end},
+					&fakeAppointments{}, &fakeAbsences{}, &fakeHolidays{}, nil),
+			}
+
+			section := &models.Section{UserID: 1, DurationMinutes: 60}
+			section.ID = 1
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			slot, _, ok := h.findSlot(w, r, section, []uint{1}, tt.slotStart.UTC(), 0)
+
+			if tt.wantStatus != 0 {
+				if ok || w.Code != tt.wantStatus {
+					t.Fatalf("ответ %d, ожидался %d", w.Code, tt.wantStatus)
+				}
+				return
+			}
+
+			if !ok {
+				t.Fatalf("слот не найден: %d %s", w.Code, w.Body.String())
+			}
+			if !slot.Start.Equal(tt.slotStart) || slot.End.Sub(slot.Start) != time.Hour {
+				t.Fatalf("найден слот %s - %s", slot.Start, slot.End)
+			}
+		})
+	}
+}
+
+func TestSendSaveError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"время занято", consts.ErrConflict, http.StatusConflict},
+		{"нет мест в занятии", consts.ErrNoSeats, http.StatusConflict},
+		{"обернутая ошибка", fmt.Errorf("запись на место: %w", consts.ErrNoSeats), http.StatusConflict},
+		{"ошибка БД", errors.New("соединение закрыто"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			sendSaveError(w, tt.err, "Ошибка при записи")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ответ %d, ожидался %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package booking
+
+import (
+	"net/http"
+	"record-services/internal/models"
+	"record-services/pkg/httputils"
+	"record-services/pkg/utils"
+	"time"
+)
+
+func (h *BookingHandlers) getManaged(w http.ResponseWriter, r *http.Request) {
+	appointment, ok := h.resolveManaged(w, r)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, toAppointmentResponse(appointment))
+}
+
+// Отмена клиентом. Прошедшие записи не отменяются
+func (h *BookingHandlers) cancelManaged(w http.ResponseWriter, r *http.Request) {
+	appointment, ok := h.resolveManaged(w, r)
+	if !ok {
+		return
+	}
+
+	if !appointment.Status.CanTransitionTo(models.AppointmentStatusCancelled) || !appointment.StartAt.After(time.Now()) {
+		httputils.SendError(w, "Запись нельзя отменить", http.StatusConflict)
+		return
+	}
+
+	appointment.Status = models.AppointmentStatusCancelled
+
+	if _, err := h.appointments.Update(r.Context(), appointment); err != nil {
+		sendSaveError(w, err, "Ошибка при отмене записи")
+		return
+	}
+
+	h.logger.Info().Msgf("Клиент отменил запись %d", appointment.ID)
+
+	h.waitlist.Released(*appointment)
+	h.reminders.Cancel(r.Context(), appointment.ID)
+
+	httputils.SendJSONResponse(w, toAppointmentResponse(appointment))
+}
+
+// Перенос клиентом на другой свободный слот того же сотрудника.
+// Подтвержденная запись снова ожидает подтверждения. Место в групповом
+// занятии не переносится: клиент отменяет запись и записывается заново
+func (h *BookingHandlers) rescheduleManaged(w http.ResponseWriter, r *http.Request) {
+	var rescheduleData struct {
+		StartAt time.Time `json:"startAt" validate:"required"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &rescheduleData) {
+		return
+	}
+
+	appointment, ok := h.resolveManaged(w, r)
+	if !ok {
+		return
+	}
+
+	if !appointment.Status.IsActive() || !appointment.StartAt.After(time.Now()) || appointment.SessionID != nil {
+		httputils.SendError(w, "Запись нельзя перенести", http.StatusConflict)
+		return
+	}
+
+	// Удаленная услуга или уволенный сотрудник не загружаются
+	if appointment.Section.ID == 0 || appointment.Employee.ID == 0 || !appointment.Employee.IsActive {
+		httputils.SendError(w, "Запись нельзя перенести", http.StatusConflict)
+		return
+	}
+
+	slot, _, ok := h.findSlot(w, r, &appointment.Section, []uint{appointment.EmployeeID}, rescheduleData.StartAt.UTC(), appointment.ID)
+	if !ok {
+		return
+	}
+
+	previous := *appointment
+	appointment.StartAt = slot.Start.UTC()
+	appointment.EndAt = slot.End.UTC()
+	appointment.Status = models.AppointmentStatusPending
+
+	if _, err := h.appointments.Update(r.Context(), appointment); err != nil {
+		sendSaveError(w, err, "Ошибка при переносе записи")
+		return
+	}
+
+	h.logger.Info().Msgf("Клиент перенес запись %d на %s", appointment.ID, appointment.StartAt.Format(time.RFC3339))
+
+	if !previous.StartAt.Equal(appointment.StartAt) {
+		h.waitlist.Released(previous)
+	}
+
+	h.reminders.Schedule(r.Context(), appointment)
+
+	httputils.SendJSONResponse(w, toAppointmentResponse(appointment))
+}
+
+// Запись по токену {token} из пути. При ошибке сам отправляет ответ
+func (h *BookingHandlers) resolveManaged(w http.ResponseWriter, r *http.Request) (*models.Appointment, bool) {
+	token := r.PathValue("token")
+	if token == "" {
+		httputils.SendError(w, "Запись не найдена", http.StatusNotFound)
+		return nil, false
+	}
+
+	appointment, err := h.appointments.GetByManageTokenHash(r.Context(), utils.HashToken(token))
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении записи", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if appointment == nil {
+		httputils.SendError(w, "Запись не найдена", http.StatusNotFound)
+		return nil, false
+	}
+
+	// Ссылка действует до окончания записи, перенос продлевает ее
+	if !time.Now().Before(appointment.EndAt) {
+		httputils.SendError(w, "Ссылка на запись больше не действует", http.StatusGone)
+		return nil, false
+	}
+
+	return appointment, true
+}
@@ -0,0 +1,84 @@
+package booking
+
+import (
+	"errors"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"regexp"
+)
+
+// Адрес страницы: латиница в нижнем регистре, цифры и дефисы внутри
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,48}[a-z0-9]$`)
+
+// Адреса, совпадающие с разделами публичного API
+var reservedSlugs = map[string]bool{
+	"appointments": true,
+	"waitlist":     true,
+}
+
+// Страница записи организации текущего пользователя
+func (h *BookingHandlers) getPage(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	page, err := h.repository.GetByUser(r.Context(), claims.Owner())
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении страницы записи", http.StatusInternalServerError)
+		return
+	}
+
+	if page == nil {
+		httputils.SendError(w, "Страница записи не настроена", http.StatusNotFound)
+		return
+	}
+
+	httputils.SendJSONResponse(w, page.ToResponse())
+}
+
+func (h *BookingHandlers) savePage(w http.ResponseWriter, r *http.Request) {
+	var pageData struct {
+		Slug        string `json:"slug" validate:"required,max=50"`
+		Title       string `json:"title" validate:"required,min=1,max=255"`
+		Description string `json:"description" validate:"max=5000"`
+		IsEnabled   bool   `json:"isEnabled"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &pageData) {
+		return
+	}
+
+	if !slugPattern.MatchString(pageData.Slug) || reservedSlugs[pageData.Slug] {
+		httputils.SendError(w, "Некорректный адрес страницы", http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	page, err := h.repository.GetByUser(r.Context(), claims.Owner())
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении страницы записи", http.StatusInternalServerError)
+		return
+	}
+
+	if page == nil {
+		page = &models.BookingPage{UserID: claims.Owner()}
+	}
+
+	page.Slug = pageData.Slug
+	page.Title = pageData.Title
+	page.Description = pageData.Description
+	page.IsEnabled = pageData.IsEnabled
+
+	if _, err := h.repository.Save(r.Context(), page); err != nil {
+		if errors.Is(err, consts.ErrAlreadyExists) {
+			httputils.SendError(w, "Адрес страницы уже занят", http.StatusConflict)
+			return
+		}
+		httputils.SendError(w, "Ошибка при сохранении страницы записи", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, page.ToResponse())
+}
@@ -0,0 +1,30 @@
+package booking
+
+import (
+	"net/http"
+	"record-services/pkg/ratelimit"
+	"time"
+)
+
+type bookingLimits struct {
+	read   func(http.Handler) http.Handler
+	create func(http.Handler) http.Handler
+	manage func(http.Handler) http.Handler
+}
+
+func newBookingLimits(store ratelimit.Store) *bookingLimits {
+	return &bookingLimits{
+		// Расчет свободного времени дорогой, ограничиваем сбор расписания
+		read: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("booking-read-ip", store, 120, time.Minute, 60), Key: ratelimit.KeyByIP},
+		),
+		create: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("booking-create-ip", store, 10, time.Hour, 5), Key: ratelimit.KeyByIP},
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("booking-create-phone", store, 5, time.Hour, 3), Key: ratelimit.KeyByJSONField("clientPhone")},
+		),
+		// Защита от перебора токенов ссылок управления
+		manage: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("booking-manage-ip", store, 30, time.Minute, 10), Key: ratelimit.KeyByIP},
+		),
+	}
+}
@@ -0,0 +1,272 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/repositories/appointment_repository"
+	"record-services/internal/repositories/client_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/phone"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+type ClientHandlers struct {
+	mux          *http.ServeMux
+	logger       *zerolog.Logger
+	repository   client_repository.ClientRepository
+	appointments appointment_repository.AppointmentRepository
+	validator    *validator.Validate
+}
+
+func NewClientHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository client_repository.ClientRepository, appointments appointment_repository.AppointmentRepository, validator *validator.Validate) *ClientHandlers {
+	clientHandlers := &ClientHandlers{
+		mux:          mux,
+		logger:       logger,
+		repository:   repository,
+		appointments: appointments,
+		validator:    validator,
+	}
+
+	read := middleware.RequirePermission(consts.PermClientsRead)
+	write := middleware.RequirePermission(consts.PermClientsWrite)
+	history := middleware.RequirePermission(consts.PermClientsRead, consts.PermAppointmentsRead)
+
+	clientHandlers.mux.Handle("GET /api/clients", read(http.HandlerFunc(clientHandlers.list)))
+	clientHandlers.mux.Handle("GET /api/clients/{id}", read(http.HandlerFunc(clientHandlers.get)))
+	clientHandlers.mux.Handle("POST /api/clients", write(http.HandlerFunc(clientHandlers.create)))
+	clientHandlers.mux.Handle("PUT /api/clients/{id}", write(http.HandlerFunc(clientHandlers.update)))
+	clientHandlers.mux.Handle("DELETE /api/clients/{id}", write(http.HandlerFunc(clientHandlers.delete)))
+	clientHandlers.mux.Handle("POST /api/clients/{id}/merge", write(http.HandlerFunc(clientHandlers.merge)))
+	clientHandlers.mux.Handle("GET /api/clients/{id}/appointments", history(http.HandlerFunc(clientHandlers.listAppointments)))
+
+	return clientHandlers
+}
+
+type clientData struct {
+	Name             string   `json:"name" validate:"required,min=1,max=255"`
+	Phone            string   `json:"phone" validate:"max=32"`
+	Email            string   `json:"email" validate:"omitempty,email,max=255"`
+	Notes            string   `json:"notes" validate:"max=5000"`
+	Tags             []string `json:"tags" validate:"max=50,dive,min=1,max=50"`
+	RemindersConsent bool     `json:"remindersConsent"`
+	MarketingConsent bool     `json:"marketingConsent"`
+}
+
+// Фильтры: search - по имени, телефону и email, tag - точное совпадение тега
+func (h *ClientHandlers) list(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	clients, err := h.repository.GetAllWithPagination(r.Context(),
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.ClientFilter{
+			UserID: claims.OwnerScope(),
+			Search: strings.TrimSpace(r.URL.Query().Get("search")),
+			Tag:    r.URL.Query().Get("tag"),
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении клиентов", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, clients)
+}
+
+// Карточка клиента со статистикой визитов
+func (h *ClientHandlers) get(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.getClient(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.appointments.GetClientStats(r.Context(), client.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении истории визитов", http.StatusInternalServerError)
+		return
+	}
+
+	response := client.ToResponse()
+	response.Stats = stats
+
+	httputils.SendJSONResponse(w, response)
+}
+
+func (h *ClientHandlers) create(w http.ResponseWriter, r *http.Request) {
+	var data clientData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	client := &models.Client{UserID: claims.Owner()}
+	if !applyData(w, client, &data) {
+		return
+	}
+
+	if _, err := h.repository.Create(r.Context(), client); err != nil {
+		sendSaveError(w, err, "Ошибка при создании клиента")
+		return
+	}
+
+	httputils.SendJSONWithStatus(w, client.ToResponse(), http.StatusCreated)
+}
+
+func (h *ClientHandlers) update(w http.ResponseWriter, r *http.Request) {
+	var data clientData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	client, ok := h.getClient(w, r)
+	if !ok {
+		return
+	}
+
+	if !applyData(w, client, &data) {
+		return
+	}
+
+	if _, err := h.repository.Update(r.Context(), client); err != nil {
+		sendSaveError(w, err, "Ошибка при обновлении клиента")
+		return
+	}
+
+	httputils.SendJSONResponse(w, client.ToResponse())
+}
+
+// Записи клиента остаются, в них сохранены имя и контакты
+func (h *ClientHandlers) delete(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.getClient(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repository.Delete(r.Context(), client.ID); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Клиент не найден", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при удалении клиента", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Объединяет дубликаты clientIds в клиента {id}
+func (h *ClientHandlers) merge(w http.ResponseWriter, r *http.Request) {
+	var mergeData struct {
+		ClientIDs []uint `json:"clientIds" validate:"required,min=1,max=50,dive,gt=0"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &mergeData) {
+		return
+	}
+
+	client, ok := h.getClient(w, r)
+	if !ok {
+		return
+	}
+
+	merged, err := h.repository.Merge(r.Context(), client.ID, mergeData.ClientIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, consts.ErrNotFound):
+			httputils.SendError(w, "Клиент не найден", http.StatusNotFound)
+		case errors.Is(err, consts.ErrBadData):
+			httputils.SendError(w, "Дубликаты не найдены", http.StatusBadRequest)
+		default:
+			httputils.SendError(w, "Ошибка при объединении клиентов", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info().Msgf("Клиенты %v объединены в клиента %d", mergeData.ClientIDs, merged.ID)
+
+	httputils.SendJSONResponse(w, merged.ToResponse())
+}
+
+// История визитов клиента, фильтр status
+func (h *ClientHandlers) listAppointments(w http.ResponseWriter, r *http.Request) {
+	client, ok := h.getClient(w, r)
+	if !ok {
+		return
+	}
+
+	appointments, err := h.appointments.GetAllWithPagination(r.Context(),
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.AppointmentFilter{
+			UserID:   client.UserID,
+			ClientID: client.ID,
+			Status:   models.AppointmentStatus(r.URL.Query().Get("status")),
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении истории визитов", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, appointments)
+}
+
+// Клиент по {id} из пути, доступный текущему пользователю.
+// Чужие клиенты неотличимы от несуществующих. При ошибке сам отправляет ответ
+func (h *ClientHandlers) getClient(w http.ResponseWriter, r *http.Request) (*models.Client, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	client, err := h.repository.GetById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении клиента", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if client == nil || !claims.CanAccess(client.UserID) {
+		httputils.SendError(w, "Клиент не найден", http.StatusNotFound)
+		return nil, false
+	}
+
+	return client, true
+}
+
+// Переносит данные запроса в клиента, нормализуя контакты.
+// При ошибке сам отправляет ответ
+func applyData(w http.ResponseWriter, client *models.Client, data *clientData) bool {
+	normalizedPhone, err := phone.Normalize(data.Phone)
+	if err != nil {
+		httputils.SendError(w, "Некорректный номер телефона", http.StatusBadRequest)
+		return false
+	}
+
+	client.Name = data.Name
+	client.Phone = normalizedPhone
+	client.Email = strings.ToLower(strings.TrimSpace(data.Email))
+	client.Notes = data.Notes
+	client.Tags = nil
+	client.AddTags(data.Tags)
+	client.RemindersConsent = data.RemindersConsent
+	client.MarketingConsent = data.MarketingConsent
+	return true
+}
+
+// Совпадение телефона или email с другим клиентом - 409, остальные ошибки - 500
+func sendSaveError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, consts.ErrAlreadyExists) {
+		httputils.SendError(w, "Клиент с таким телефоном или email уже существует", http.StatusConflict)
+		return
+	}
+	httputils.SendError(w, message, http.StatusInternalServerError)
+}
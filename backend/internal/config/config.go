@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,17 +16,40 @@ type DbConfig struct {
 
 type ServerConfig struct {
 	Listen string
+	// Адрес фронтенда для ссылок в письмах
+	AppURL string
 }
 
 type SecretConfig struct {
 	JwtSecret  string
-	HashSecret string
+	HashSecret string // нужен только для проверки паролей в старом HMAC формате
+}
+
+type TokenConfig struct {
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+type MailConfig struct {
+	Driver       string // log или smtp
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	From         string
+}
+
+type PasswordConfig struct {
+	Hasher string // argon2id или bcrypt
 }
 
 type Config struct {
 	Db       DbConfig
 	Server   ServerConfig
 	Secret   SecretConfig
+	Password PasswordConfig
+	Token    TokenConfig
+	Mail     MailConfig
 	LogLevel int
 }
 
@@ -56,10 +80,26 @@ func New() *Config {
 		},
 		Server: ServerConfig{
 			Listen: getEnv("SERVER_LISTEN", ":8080"),
+			AppURL: getEnv("APP_URL", "http://localhost:5173"),
 		},
 		Secret: SecretConfig{
 			JwtSecret:  getEnvRequired("JWT_SECRET"),
-			HashSecret: getEnvRequired("HASH_SECRET"),
+			HashSecret: getEnv("HASH_SECRET", ""),
+		},
+		Password: PasswordConfig{
+			Hasher: getEnv("PASSWORD_HASHER", "argon2id"),
+		},
+		Token: TokenConfig{
+			AccessTTL:  getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTTL: getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUser:     getEnv("SMTP_USER", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			From:         getEnv("MAIL_FROM", "noreply@localhost"),
 		},
 		LogLevel: logLevel,
 	}
@@ -80,3 +120,15 @@ func getEnvRequired(key string) string {
 	}
 	return value
 }
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatal("Некорректная длительность в ключе " + key)
+	}
+	return duration
+}
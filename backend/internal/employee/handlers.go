@@ -0,0 +1,296 @@
+package employee
+
+import (
+	"errors"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+type EmployeeHandlers struct {
+	mux        *http.ServeMux
+	logger     *zerolog.Logger
+	repository employee_repository.EmployeeRepository
+	validator  *validator.Validate
+}
+
+func NewEmployeeHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository employee_repository.EmployeeRepository, validator *validator.Validate) *EmployeeHandlers {
+	employeeHandlers := &EmployeeHandlers{
+		mux:        mux,
+		logger:     logger,
+		repository: repository,
+		validator:  validator,
+	}
+
+	read := middleware.RequirePermission(consts.PermEmployeesRead)
+	write := middleware.RequirePermission(consts.PermEmployeesWrite)
+
+	employeeHandlers.mux.Handle("GET /api/employees", read(http.HandlerFunc(employeeHandlers.list)))
+	employeeHandlers.mux.Handle("GET /api/employees/{id}", read(http.HandlerFunc(employeeHandlers.get)))
+	employeeHandlers.mux.Handle("POST /api/employees", write(http.HandlerFunc(employeeHandlers.create)))
+	employeeHandlers.mux.Handle("PUT /api/employees/{id}", write(http.HandlerFunc(employeeHandlers.update)))
+	employeeHandlers.mux.Handle("DELETE /api/employees/{id}", write(http.HandlerFunc(employeeHandlers.delete)))
+	employeeHandlers.mux.Handle("PUT /api/employees/{id}/sections", write(http.HandlerFunc(employeeHandlers.setSections)))
+	employeeHandlers.mux.Handle("GET /api/employees/{id}/sections/{sectionId}", read(http.HandlerFunc(employeeHandlers.getSectionSettings)))
+	employeeHandlers.mux.Handle("PUT /api/employees/{id}/sections/{sectionId}", write(http.HandlerFunc(employeeHandlers.setSectionSettings)))
+
+	return employeeHandlers
+}
+
+type employeeData struct {
+	Name  string `json:"name" validate:"required,min=1,max=255"`
+	Email string `json:"email" validate:"omitempty,email,max=255"`
+	Phone string `json:"phone" validate:"max=20"`
+	// Без поля: новый сотрудник активен, у существующего признак не меняется
+	IsActive *bool `json:"isActive"`
+	// Пустой - часовой пояс владельца. Без поля: у существующего сотрудника
+	// пояс не меняется
+	TimeZone *string `json:"timeZone" validate:"omitempty,timezone,max=64"`
+}
+
+// Фильтры: section_id, active=true|false|all, search - по имени и телефону
+func (h *EmployeeHandlers) list(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	employees, err := h.repository.GetAllWithPagination(r.Context(),
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.EmployeeFilter{
+			UserID:    claims.OwnerScope(),
+			SectionID: uint(max(httputils.QueryInt(r, "section_id", 0), 0)),
+			IsActive:  httputils.QueryCheckValue(r, "active"),
+			Search:    r.URL.Query().Get("search"),
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении сотрудников", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, employees)
+}
+
+func (h *EmployeeHandlers) get(w http.ResponseWriter, r *http.Request) {
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, employee.ToResponse())
+}
+
+func (h *EmployeeHandlers) create(w http.ResponseWriter, r *http.Request) {
+	var data employeeData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	employee := &models.Employee{
+		Name:     data.Name,
+		Email:    data.Email,
+		Phone:    data.Phone,
+		IsActive: data.IsActive == nil || *data.IsActive,
+		UserID:   claims.Owner(),
+	}
+	if data.TimeZone != nil {
+		employee.TimeZone = *data.TimeZone
+	}
+
+	if _, err := h.repository.Create(r.Context(), employee); err != nil {
+		httputils.SendError(w, "Ошибка при создании сотрудника", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONWithStatus(w, employee.ToResponse(), http.StatusCreated)
+}
+
+func (h *EmployeeHandlers) update(w http.ResponseWriter, r *http.Request) {
+	var data employeeData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	employee.Name = data.Name
+	employee.Email = data.Email
+	employee.Phone = data.Phone
+	if data.IsActive != nil {
+		employee.IsActive = *data.IsActive
+	}
+	if data.TimeZone != nil {
+		employee.TimeZone = *data.TimeZone
+	}
+
+	if _, err := h.repository.Update(r.Context(), employee); err != nil {
+		httputils.SendError(w, "Ошибка при обновлении сотрудника", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, employee.ToResponse())
+}
+
+func (h *EmployeeHandlers) delete(w http.ResponseWriter, r *http.Request) {
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repository.Delete(r.Context(), employee.ID); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Сотрудник не найден", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при удалении сотрудника", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Полностью заменяет секции сотрудника. Пустой список снимает все назначения
+func (h *EmployeeHandlers) setSections(w http.ResponseWriter, r *http.Request) {
+	var sectionsData struct {
+		SectionIDs []uint `json:"sectionIds" validate:"max=100,dive,gt=0"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &sectionsData) {
+		return
+	}
+
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repository.ReplaceSections(r.Context(), employee.ID, sectionsData.SectionIDs); err != nil {
+		switch {
+		case errors.Is(err, consts.ErrNotFound):
+			httputils.SendError(w, "Сотрудник не найден", http.StatusNotFound)
+		case errors.Is(err, consts.ErrBadData):
+			httputils.SendError(w, "Секция не найдена", http.StatusBadRequest)
+		default:
+			httputils.SendError(w, "Ошибка при назначении секций", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	employee, err := h.repository.GetById(r.Context(), employee.ID)
+	if err != nil || employee == nil {
+		httputils.SendError(w, "Ошибка при получении сотрудника", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, employee.ToResponse())
+}
+
+func (h *EmployeeHandlers) getSectionSettings(w http.ResponseWriter, r *http.Request) {
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	settings, ok := h.getSettings(w, r, employee)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, settings)
+}
+
+// Переопределяет параметры секции для сотрудника. null - как у секции
+func (h *EmployeeHandlers) setSectionSettings(w http.ResponseWriter, r *http.Request) {
+	var settingsData struct {
+		DurationMinutes     *int   `json:"durationMinutes" validate:"omitempty,min=5,max=720"`
+		Price               *int64 `json:"price" validate:"omitempty,min=0"`
+		BufferBeforeMinutes *int   `json:"bufferBeforeMinutes" validate:"omitempty,min=0,max=240"`
+		BufferAfterMinutes  *int   `json:"bufferAfterMinutes" validate:"omitempty,min=0,max=240"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &settingsData) {
+		return
+	}
+
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	settings, ok := h.getSettings(w, r, employee)
+	if !ok {
+		return
+	}
+
+	settings.DurationMinutes = settingsData.DurationMinutes
+	settings.Price = settingsData.Price
+	settings.BufferBeforeMinutes = settingsData.BufferBeforeMinutes
+	settings.BufferAfterMinutes = settingsData.BufferAfterMinutes
+
+	if err := h.repository.UpdateSectionSettings(r.Context(), settings); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Сотрудник не работает в этой секции", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при сохранении параметров секции", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, settings)
+}
+
+// Назначение сотрудника в секцию {sectionId} из пути. При ошибке сам отправляет ответ
+func (h *EmployeeHandlers) getSettings(w http.ResponseWriter, r *http.Request, employee *models.Employee) (*models.EmployeeSection, bool) {
+	sectionID, err := httputils.PathID(r, "sectionId")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	settings, err := h.repository.GetSectionSettings(r.Context(), employee.ID, sectionID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении параметров секции", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if settings == nil {
+		httputils.SendError(w, "Сотрудник не работает в этой секции", http.StatusNotFound)
+		return nil, false
+	}
+
+	return settings, true
+}
+
+// Сотрудник по {id} из пути, доступный текущему пользователю.
+// Чужие сотрудники неотличимы от несуществующих. При ошибке сам отправляет ответ
+func (h *EmployeeHandlers) getEmployee(w http.ResponseWriter, r *http.Request) (*models.Employee, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	employee, err := h.repository.GetById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении сотрудника", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if employee == nil || !claims.CanAccess(employee.UserID) {
+		httputils.SendError(w, "Сотрудник не найден", http.StatusNotFound)
+		return nil, false
+	}
+
+	return employee, true
+}
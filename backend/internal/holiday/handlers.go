@@ -0,0 +1,285 @@
+package holiday
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/repositories/holiday_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/ical"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Ограничение размера импортируемого файла календаря
+	maxImportSize = 1 << 20
+	// Праздничных дней в одном импорте, около десяти лет
+	maxImportDates = 3660
+)
+
+type HolidayHandlers struct {
+	mux        *http.ServeMux
+	logger     *zerolog.Logger
+	repository holiday_repository.HolidayRepository
+	validator  *validator.Validate
+}
+
+func NewHolidayHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository holiday_repository.HolidayRepository, validator *validator.Validate) *HolidayHandlers {
+	holidayHandlers := &HolidayHandlers{
+		mux:        mux,
+		logger:     logger,
+		repository: repository,
+		validator:  validator,
+	}
+
+	read := middleware.RequirePermission(consts.PermEmployeesRead)
+	write := middleware.RequirePermission(consts.PermEmployeesWrite)
+
+	holidayHandlers.mux.Handle("GET /api/holiday-calendars", read(http.HandlerFunc(holidayHandlers.list)))
+	holidayHandlers.mux.Handle("GET /api/holiday-calendars/{id}", read(http.HandlerFunc(holidayHandlers.get)))
+	holidayHandlers.mux.Handle("POST /api/holiday-calendars", write(http.HandlerFunc(holidayHandlers.create)))
+	holidayHandlers.mux.Handle("PUT /api/holiday-calendars/{id}", write(http.HandlerFunc(holidayHandlers.update)))
+	holidayHandlers.mux.Handle("DELETE /api/holiday-calendars/{id}", write(http.HandlerFunc(holidayHandlers.delete)))
+	holidayHandlers.mux.Handle("POST /api/holiday-calendars/{id}/holidays", write(http.HandlerFunc(holidayHandlers.addHolidays)))
+	holidayHandlers.mux.Handle("DELETE /api/holiday-calendars/{id}/holidays/{holidayId}", write(http.HandlerFunc(holidayHandlers.deleteHoliday)))
+	holidayHandlers.mux.Handle("POST /api/holiday-calendars/{id}/import", write(http.HandlerFunc(holidayHandlers.importICS)))
+
+	return holidayHandlers
+}
+
+type calendarData struct {
+	Name string `json:"name" validate:"required,min=1,max=255"`
+}
+
+func (h *HolidayHandlers) list(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	calendars, err := h.repository.GetCalendars(r.Context(), claims.OwnerScope())
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении календарей", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]models.HolidayCalendarResponse, len(calendars))
+	for i := range calendars {
+		responses[i] = calendars[i].ToResponse()
+	}
+
+	httputils.SendJSONResponse(w, responses)
+}
+
+// Календарь вместе с праздничными днями
+func (h *HolidayHandlers) get(w http.ResponseWriter, r *http.Request) {
+	calendar, ok := h.getCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	holidays, err := h.repository.GetHolidays(r.Context(), calendar.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении праздников", http.StatusInternalServerError)
+		return
+	}
+	calendar.Holidays = holidays
+
+	httputils.SendJSONResponse(w, calendar.ToResponse())
+}
+
+func (h *HolidayHandlers) create(w http.ResponseWriter, r *http.Request) {
+	var data calendarData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	calendar := &models.HolidayCalendar{
+		UserID: claims.Owner(),
+		Name:   data.Name,
+	}
+
+	if _, err := h.repository.CreateCalendar(r.Context(), calendar); err != nil {
+		httputils.SendError(w, "Ошибка при создании календаря", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONWithStatus(w, calendar.ToResponse(), http.StatusCreated)
+}
+
+func (h *HolidayHandlers) update(w http.ResponseWriter, r *http.Request) {
+	var data calendarData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	calendar, ok := h.getCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	calendar.Name = data.Name
+
+	if _, err := h.repository.UpdateCalendar(r.Context(), calendar); err != nil {
+		httputils.SendError(w, "Ошибка при обновлении календаря", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, calendar.ToResponse())
+}
+
+func (h *HolidayHandlers) delete(w http.ResponseWriter, r *http.Request) {
+	calendar, ok := h.getCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repository.DeleteCalendar(r.Context(), calendar.ID); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Календарь не найден", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при удалении календаря", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Добавляет праздничные дни вручную. Существующим датам обновляется название
+func (h *HolidayHandlers) addHolidays(w http.ResponseWriter, r *http.Request) {
+	var holidaysData struct {
+		Holidays []struct {
+			Date string `json:"date" validate:"required,datetime=2006-01-02"`
+			Name string `json:"name" validate:"max=255"`
+		} `json:"holidays" validate:"required,min=1,max=1000,dive"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &holidaysData) {
+		return
+	}
+
+	calendar, ok := h.getCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	holidays := make([]models.Holiday, 0, len(holidaysData.Holidays))
+	for _, item := range holidaysData.Holidays {
+		date, _ := time.Parse(time.DateOnly, item.Date)
+		holidays = append(holidays, models.Holiday{Date: date, Name: item.Name})
+	}
+
+	h.saveHolidays(w, r, calendar.ID, holidays)
+}
+
+// Импорт праздников из iCalendar (.ics), файл передается телом запроса.
+// Событие на несколько дней дает несколько праздничных дат
+func (h *HolidayHandlers) importICS(w http.ResponseWriter, r *http.Request) {
+	calendar, ok := h.getCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := ical.Parse(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			httputils.SendError(w, "Файл календаря слишком большой", http.StatusRequestEntityTooLarge)
+			return
+		}
+		httputils.SendError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var holidays []models.Holiday
+	for _, event := range events {
+		for _, date := range event.Dates() {
+			if len(holidays) == maxImportDates {
+				httputils.SendError(w, fmt.Sprintf("В файле больше %d праздничных дней", maxImportDates), http.StatusBadRequest)
+				return
+			}
+			holidays = append(holidays, models.Holiday{Date: date, Name: event.Summary})
+		}
+	}
+
+	if len(holidays) == 0 {
+		httputils.SendError(w, "В файле нет событий", http.StatusBadRequest)
+		return
+	}
+
+	h.saveHolidays(w, r, calendar.ID, holidays)
+}
+
+func (h *HolidayHandlers) deleteHoliday(w http.ResponseWriter, r *http.Request) {
+	holidayID, err := httputils.PathID(r, "holidayId")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return
+	}
+
+	calendar, ok := h.getCalendar(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repository.DeleteHoliday(r.Context(), calendar.ID, holidayID); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Праздник не найден", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при удалении праздника", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Сохраняет праздники и возвращает актуальный список дней календаря
+func (h *HolidayHandlers) saveHolidays(w http.ResponseWriter, r *http.Request, calendarID uint, holidays []models.Holiday) {
+	if err := h.repository.UpsertHolidays(r.Context(), calendarID, holidays); err != nil {
+		httputils.SendError(w, "Ошибка при сохранении праздников", http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := h.repository.GetHolidays(r.Context(), calendarID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении праздников", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]models.HolidayResponse, len(saved))
+	for i := range saved {
+		responses[i] = saved[i].ToResponse()
+	}
+
+	httputils.SendJSONResponse(w, responses)
+}
+
+// Календарь по {id} из пути, доступный текущему пользователю. При ошибке сам отправляет ответ
+func (h *HolidayHandlers) getCalendar(w http.ResponseWriter, r *http.Request) (*models.HolidayCalendar, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	calendar, err := h.repository.GetCalendarById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении календаря", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if calendar == nil || !claims.CanAccess(calendar.UserID) {
+		httputils.SendError(w, "Календарь не найден", http.StatusNotFound)
+		return nil, false
+	}
+
+	return calendar, true
+}
@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"record-services/internal/models"
+	"record-services/internal/repositories/job_repository"
+	"record-services/pkg/consts"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Проверка наступивших задач
+	pollInterval = 5 * time.Second
+	// Ограничение на выполнение одной задачи
+	jobTimeout = 30 * time.Second
+	// Блокировка взятой задачи. Задачи берутся по одной, поэтому
+	// блокировке достаточно быть больше jobTimeout, чтобы задачу
+	// не взял другой процесс, пока она выполняется
+	jobLease = 2 * time.Minute
+	// Попыток до перевода задачи в failed
+	maxAttempts = 5
+	// Удаление выполненных и отмененных задач старше jobRetention.
+	// Задачи с ошибкой остаются для разбора
+	purgeInterval = time.Hour
+	jobRetention  = 7 * 24 * time.Hour
+)
+
+// Обработчик задачи одного вида. Ошибка - задача повторяется позже
+type Handler func(ctx context.Context, job *models.Job) error
+
+// Выполнение фоновых задач из БД. Задачи сохраняются до выполнения,
+// поэтому перезапуск сервера их не теряет: наступившие за время остановки
+// задачи выполняются после запуска, а прерванные - по истечении блокировки
+type Runner struct {
+	logger     *zerolog.Logger
+	repository job_repository.JobRepository
+	handlers   map[string]Handler
+}
+
+func NewRunner(logger *zerolog.Logger, repository job_repository.JobRepository) *Runner {
+	return &Runner{
+		logger:     logger,
+		repository: repository,
+		handlers:   make(map[string]Handler),
+	}
+}
+
+// Регистрирует обработчик задач вида kind. Вызывается до Start
+func (r *Runner) Register(kind string, handler Handler) {
+	r.handlers[kind] = handler
+}
+
+// Запускает фоновую обработку
+func (r *Runner) Start() {
+	go r.run()
+}
+
+func (r *Runner) run() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var purgedAt time.Time
+	for now := range ticker.C {
+		// Пока есть наступившие задачи, следующая берется сразу
+		for r.poll() {
+		}
+
+		if now.Sub(purgedAt) >= purgeInterval {
+			purgedAt = now
+			r.purge(now)
+		}
+	}
+}
+
+// Выполняет одну наступившую задачу, false - таких задач нет
+func (r *Runner) poll() bool {
+	jobs, err := r.repository.Claim(context.Background(), time.Now(), jobLease, 1, maxAttempts)
+	if err != nil || len(jobs) == 0 {
+		return false
+	}
+
+	r.execute(&jobs[0])
+	return true
+}
+
+func (r *Runner) execute(job *models.Job) {
+	handler, ok := r.handlers[job.Kind]
+	if !ok {
+		r.logger.Error().Msgf("Нет обработчика фоновой задачи %d вида %s", job.ID, job.Kind)
+		r.save(job, r.repository.Fail(context.Background(), job.ID, job.Attempts, "нет обработчика", time.Time{}))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	err := r.call(ctx, handler, job)
+	cancel()
+
+	// Результат сохраняется и после истечения jobTimeout
+	if err != nil {
+		var retryAt time.Time
+		if job.Attempts < maxAttempts {
+			retryAt = time.Now().Add(backoff(job.Attempts))
+		}
+		r.logger.Warn().Err(err).Msgf("Ошибка фоновой задачи %d вида %s, попытка %d", job.ID, job.Kind, job.Attempts)
+		r.save(job, r.repository.Fail(context.Background(), job.ID, job.Attempts, err.Error(), retryAt))
+		return
+	}
+
+	r.save(job, r.repository.Complete(context.Background(), job.ID, job.Attempts))
+}
+
+// Задачу с истекшей блокировкой мог взять другой процесс: тогда
+// результат этой попытки не сохраняется
+func (r *Runner) save(job *models.Job, err error) {
+	if errors.Is(err, consts.ErrNotFound) {
+		r.logger.Warn().Msgf("Фоновая задача %d взята повторно, результат попытки %d не сохранен", job.ID, job.Attempts)
+	}
+}
+
+func (r *Runner) purge(now time.Time) {
+	purged, err := r.repository.Purge(context.Background(), now.Add(-jobRetention))
+	if err == nil && purged > 0 {
+		r.logger.Info().Msgf("Удалено завершенных фоновых задач: %d", purged)
+	}
+}
+
+// Паника обработчика считается ошибкой задачи и не останавливает обработку
+func (r *Runner) call(ctx context.Context, handler Handler, job *models.Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("паника: %v", recovered)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// Пауза перед повтором: 1, 4, 9, 16 минут
+func backoff(attempts int) time.Duration {
+	return time.Duration(attempts*attempts) * time.Minute
+}
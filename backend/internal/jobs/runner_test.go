@@ -0,0 +1,172 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Очередь в памяти: запоминает результат последней попытки
+type fakeRepository struct {
+	jobs []models.Job
+	// Ограничение попыток, переданное в Claim
+	maxAttempts int
+
+	completed bool
+	failed    bool
+	attempts  int
+	reason    string
+	retryAt   time.Time
+	// Результат Complete и Fail, например consts.ErrNotFound
+	saveErr error
+}
+
+func (f *fakeRepository) Replace(ctx context.Context, key string, jobs []models.Job) error {
+	return nil
+}
+
+func (f *fakeRepository) Cancel(ctx context.Context, key string) error {
+	return nil
+}
+
+func (f *fakeRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int, maxAttempts int) ([]models.Job, error) {
+	f.maxAttempts = maxAttempts
+	n := min(limit, len(f.jobs))
+	claimed := f.jobs[:n]
+	f.jobs = f.jobs[n:]
+	for i := range claimed {
+		claimed[i].Attempts++
+	}
+	return claimed, nil
+}
+
+func (f *fakeRepository) Complete(ctx context.Context, id uint, attempts int) error {
+	f.completed, f.attempts = true, attempts
+	return f.saveErr
+}
+
+func (f *fakeRepository) Fail(ctx context.Context, id uint, attempts int, reason string, retryAt time.Time) error {
+	f.failed, f.attempts, f.reason, f.retryAt = true, attempts, reason, retryAt
+	return f.saveErr
+}
+
+func (f *fakeRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 4 * time.Minute},
+		{3, 9 * time.Minute},
+		{4, 16 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Fatalf("backoff(%d) = %s, ожидалось %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	errHandler := errors.New("ошибка обработчика")
+
+	tests := []struct {
+		name     string
+		kind     string
+		attempts int
+		handler  Handler
+		saveErr  error
+
+		wantCompleted bool
+		// Ожидаемая пауза до повтора, 0 - задача переводится в failed
+		wantRetry time.Duration
+	}{
+		{"выполнена", "test", 0, func(ctx context.Context, job *models.Job) error { return nil }, nil, true, 0},
+		{"первая ошибка", "test", 0, func(ctx context.Context, job *models.Job) error { return errHandler }, nil, false, time.Minute},
+		{"повторная ошибка", "test", 2, func(ctx context.Context, job *models.Job) error { return errHandler }, nil, false, 9 * time.Minute},
+		{"последняя попытка", "test", maxAttempts - 1, func(ctx context.Context, job *models.Job) error { return errHandler }, nil, false, 0},
+		{"паника", "test", 0, func(ctx context.Context, job *models.Job) error { panic("сбой") }, nil, false, time.Minute},
+		{"нет обработчика", "unknown", 0, nil, nil, false, 0},
+		{"задачу взяли снова", "test", 0, func(ctx context.Context, job *models.Job) error { return nil }, consts.ErrNotFound, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := zerolog.Nop()
+			repository := &fakeRepository{
+				jobs:    []models.Job{{Kind: tt.kind, Attempts: tt.attempts}},
+				saveErr: tt.saveErr,
+			}
+			runner := NewRunner(&logger, repository)
+			if tt.handler != nil {
+				runner.Register("test", tt.handler)
+			}
+
+			before := time.Now()
+			if !runner.poll() {
+				t.Fatal("задача не взята")
+			}
+			if runner.poll() {
+				t.Fatal("взята лишняя задача")
+			}
+
+			if repository.maxAttempts != maxAttempts {
+				t.Fatalf("в Claim передано ограничение %d попыток, ожидалось %d", repository.maxAttempts, maxAttempts)
+			}
+			if repository.attempts != tt.attempts+1 {
+				t.Fatalf("сохранена попытка %d, ожидалась %d", repository.attempts, tt.attempts+1)
+			}
+
+			if tt.wantCompleted {
+				if !repository.completed || repository.failed {
+					t.Fatal("задача не завершена")
+				}
+				return
+			}
+
+			if !repository.failed || repository.completed || repository.reason == "" {
+				t.Fatalf("ошибка задачи не сохранена: %+v", repository)
+			}
+			if tt.wantRetry == 0 {
+				if !repository.retryAt.IsZero() {
+					t.Fatalf("задача возвращена в очередь к %s", repository.retryAt)
+				}
+				return
+			}
+			if retry := repository.retryAt.Sub(before); retry < tt.wantRetry || retry > tt.wantRetry+time.Second {
+				t.Fatalf("повтор через %s, ожидалось %s", retry, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestExecuteTimeout(t *testing.T) {
+	logger := zerolog.Nop()
+	repository := &fakeRepository{jobs: []models.Job{{Kind: "test"}}}
+	runner := NewRunner(&logger, repository)
+
+	var deadline time.Time
+	runner.Register("test", func(ctx context.Context, job *models.Job) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	})
+
+	runner.poll()
+
+	if deadline.IsZero() || deadline.After(time.Now().Add(jobTimeout)) {
+		t.Fatalf("обработчик без ограничения jobTimeout: %s", deadline)
+	}
+	if jobTimeout >= jobLease {
+		t.Fatalf("блокировка %s не больше времени выполнения задачи %s", jobLease, jobTimeout)
+	}
+}
@@ -19,12 +19,33 @@ const (
 )
 
 var publicPath = map[string]bool{
-	"/api/auth/login":    true,
-	"/api/auth/register": true,
+	"/api/auth/login":               true,
+	"/api/auth/login/mfa":           true,
+	"/api/auth/register":            true,
+	"/api/auth/refresh":             true,
+	"/api/auth/logout":              true,
+	"/api/auth/verify-email":        true,
+	"/api/auth/confirm-email":       true,
+	"/api/auth/resend-verification": true,
+	"/api/auth/forgot-password":     true,
+	"/api/auth/reset-password":      true,
+}
+
+// Разделы API без авторизации целиком
+var publicPrefixes = []string{
+	"/api/public/",
 }
 
 func isPublicPath(path string) bool {
-	return publicPath[path]
+	if publicPath[path] {
+		return true
+	}
+	for _, prefix := range publicPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
 }
 
 func AuthMiddleware(authService *auth.AuthHandlers) func(http.Handler) http.Handler {
@@ -50,6 +71,12 @@ func AuthMiddleware(authService *auth.AuthHandlers) func(http.Handler) http.Hand
 				return
 			}
 
+			// Токен отозван при выходе или деактивации пользователя
+			if authService.IsTokenRevoked(user) {
+				handleInvalidToken(w, reqType)
+				return
+			}
+
 			// Добавляем пользователя в контекст
 			ctx := context.WithValue(r.Context(), string(consts.ContextUserKey), user)
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -119,8 +146,5 @@ func clearAuthCookie(w http.ResponseWriter) {
 }
 
 func GetUserFromContext(ctx context.Context) *utils.UserClaims {
-	if user, ok := ctx.Value(string(consts.ContextUserKey)).(*utils.UserClaims); ok {
-		return user
-	}
-	return nil
+	return utils.GetUserFromContext(ctx)
 }
\ No newline at end of file
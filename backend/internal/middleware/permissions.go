@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net/http"
+	"record-services/pkg/consts"
+)
+
+// Пропускает запрос, только если у пользователя есть все перечисленные права.
+// Права берутся из токена: при их изменении токены пользователя отзываются
+func RequirePermission(permissions ...consts.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetUserFromContext(r.Context())
+			if claims == nil {
+				http.Error(w, "Неавторизован", http.StatusUnauthorized)
+				return
+			}
+
+			for _, permission := range permissions {
+				if !claims.HasPermission(permission) {
+					http.Error(w, "Недостаточно прав", http.StatusForbidden)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"record-services/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// Активные записи одного сотрудника не пересекаются по времени вместе
+// с буферами, кроме мест одного группового занятия. Проверяет БД, поэтому
+// параллельные запросы не создадут двойную запись и не займут буфер.
+// Ограничение без учета занятий или буферов пересоздается
+func appointmentConstraints(db *gorm.DB) error {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS btree_gist").Error; err != nil {
+		return err
+	}
+
+	// Записи, созданные до появления колонок, получают время с буферами
+	if err := db.Exec(`UPDATE appointments
+		SET occupied_from = start_at - buffer_before_minutes * INTERVAL '1 minute',
+			occupied_to = end_at + buffer_after_minutes * INTERVAL '1 minute'
+		WHERE occupied_from IS NULL OR occupied_to IS NULL`).Error; err != nil {
+		return err
+	}
+
+	return db.Exec(`
+		DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '` + models.AppointmentNoOverlapConstraint + `'
+				AND pg_get_constraintdef(oid) NOT LIKE '%occupied_from%') THEN
+				ALTER TABLE appointments DROP CONSTRAINT ` + models.AppointmentNoOverlapConstraint + `;
+			END IF;
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '` + models.AppointmentNoOverlapConstraint + `') THEN
+				ALTER TABLE appointments ADD CONSTRAINT ` + models.AppointmentNoOverlapConstraint + `
+				EXCLUDE USING gist (employee_id WITH =, tstzrange(occupied_from, occupied_to, '[)') WITH &&,
+					(COALESCE(session_id, -id)) WITH <>)
+				WHERE (status IN ('pending', 'confirmed') AND deleted_at IS NULL);
+			END IF;
+		END
+		$$`).Error
+}
+
+// Активные записи, занимающие один ресурс, не пересекаются по времени,
+// кроме мест одного группового занятия. Пересечение списков ресурсов
+// проверяет оператор && из intarray
+func resourceConstraints(db *gorm.DB) error {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS intarray").Error; err != nil {
+		return err
+	}
+
+	return db.Exec(`
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '` + models.AppointmentResourceOverlapConstraint + `') THEN
+				ALTER TABLE appointments ADD CONSTRAINT ` + models.AppointmentResourceOverlapConstraint + `
+				EXCLUDE USING gist (resource_ids gist__int_ops WITH &&, tstzrange(start_at, end_at, '[)') WITH &&,
+					(COALESCE(session_id, -id)) WITH <>)
+				WHERE (status IN ('pending', 'confirmed') AND deleted_at IS NULL AND cardinality(resource_ids) > 0);
+			END IF;
+		END
+		$$`).Error
+}
+
+// Занятие по услуге на одно время у сотрудника одно: параллельная запись
+// на первое место не создаст второе занятие
+func groupSessionConstraints(db *gorm.DB) error {
+	return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS " + models.GroupSessionSlotIndex +
+		" ON group_sessions (employee_id, section_id, start_at) WHERE deleted_at IS NULL").Error
+}
+
+// Телефон и email клиента уникальны в пределах владельца.
+// Пустые значения и удаленные клиенты не учитываются
+func clientConstraints(db *gorm.DB) error {
+	indexes := map[string]string{
+		models.ClientPhoneUniqueIndex: "phone",
+		models.ClientEmailUniqueIndex: "email",
+	}
+
+	for name, column := range indexes {
+		err := db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS " + name + " ON clients (user_id, " + column + ") " +
+			"WHERE " + column + " <> '' AND deleted_at IS NULL").Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -7,10 +7,65 @@ import (
 )
 
 func Migrate(db *gorm.DB) error {
+	// Роли появились позже пользователей: при первом создании таблицы
+	// существующим пользователям назначаются роли по признаку IsAdmin
+	rolesCreated := !db.Migrator().HasTable(&models.Role{})
+
+	// Переопределения параметров услуги хранятся в таблице связи
+	if err := db.SetupJoinTable(&models.Employee{}, "Sections", &models.EmployeeSection{}); err != nil {
+		return err
+	}
+	if err := db.SetupJoinTable(&models.Section{}, "Employees", &models.EmployeeSection{}); err != nil {
+		return err
+	}
+
 	err := db.AutoMigrate(
 		&models.User{},
 		&models.Section{},
+		&models.Resource{},
 		&models.Employee{},
+		&models.EmployeeSection{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.UserToken{},
+		&models.RecoveryCode{},
+		&models.Permission{},
+		&models.Role{},
+		&models.AuditLog{},
+		&models.Appointment{},
+		&models.AppointmentSeries{},
+		&models.GroupSession{},
+		&models.WeeklySchedule{},
+		&models.ScheduleOverride{},
+		&models.Absence{},
+		&models.HolidayCalendar{},
+		&models.Holiday{},
+		&models.Client{},
+		&models.BookingPage{},
+		&models.WaitlistEntry{},
+		&models.WaitlistOffer{},
+		&models.ReminderRule{},
+		&models.Job{},
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if err := appointmentConstraints(db); err != nil {
+		return err
+	}
+
+	if err := resourceConstraints(db); err != nil {
+		return err
+	}
+
+	if err := groupSessionConstraints(db); err != nil {
+		return err
+	}
+
+	if err := clientConstraints(db); err != nil {
+		return err
+	}
+
+	return seedRoles(db, rolesCreated)
 }
@@ -0,0 +1,135 @@
+package migrations
+
+import (
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var permissionDescriptions = map[consts.Permission]string{
+	consts.PermUsersManage:       "Управление пользователями",
+	consts.PermRolesManage:       "Назначение ролей",
+	consts.PermSectionsRead:      "Просмотр секций",
+	consts.PermSectionsWrite:     "Изменение секций",
+	consts.PermEmployeesRead:     "Просмотр сотрудников",
+	consts.PermEmployeesWrite:    "Изменение сотрудников",
+	consts.PermAppointmentsRead:  "Просмотр записей",
+	consts.PermAppointmentsWrite: "Создание и изменение записей",
+	consts.PermClientsRead:       "Просмотр клиентов",
+	consts.PermClientsWrite:      "Изменение и объединение клиентов",
+}
+
+type roleSeed struct {
+	description string
+	permissions []consts.Permission
+}
+
+// Набор прав ролей задается кодом: при каждом запуске роли приводятся к нему
+var roleSeeds = map[consts.RoleName]roleSeed{
+	consts.RoleAdmin: {
+		description: "Администратор системы",
+		permissions: []consts.Permission{
+			consts.PermUsersManage, consts.PermRolesManage,
+			consts.PermSectionsRead, consts.PermSectionsWrite,
+			consts.PermEmployeesRead, consts.PermEmployeesWrite,
+			consts.PermAppointmentsRead, consts.PermAppointmentsWrite,
+			consts.PermClientsRead, consts.PermClientsWrite,
+		},
+	},
+	consts.RoleOwner: {
+		description: "Владелец",
+		permissions: []consts.Permission{
+			consts.PermSectionsRead, consts.PermSectionsWrite,
+			consts.PermEmployeesRead, consts.PermEmployeesWrite,
+			consts.PermAppointmentsRead, consts.PermAppointmentsWrite,
+			consts.PermClientsRead, consts.PermClientsWrite,
+		},
+	},
+	consts.RoleManager: {
+		description: "Управляющий",
+		permissions: []consts.Permission{
+			consts.PermSectionsRead, consts.PermSectionsWrite,
+			consts.PermEmployeesRead, consts.PermEmployeesWrite,
+			consts.PermAppointmentsRead, consts.PermAppointmentsWrite,
+			consts.PermClientsRead, consts.PermClientsWrite,
+		},
+	},
+	consts.RoleReceptionist: {
+		description: "Администратор записи",
+		permissions: []consts.Permission{
+			consts.PermSectionsRead,
+			consts.PermEmployeesRead,
+			consts.PermAppointmentsRead, consts.PermAppointmentsWrite,
+			consts.PermClientsRead, consts.PermClientsWrite,
+		},
+	},
+	consts.RoleEmployee: {
+		description: "Сотрудник",
+		permissions: []consts.Permission{
+			consts.PermSectionsRead,
+			consts.PermEmployeesRead,
+			consts.PermAppointmentsRead,
+			consts.PermClientsRead,
+		},
+	},
+}
+
+// Заполняет справочники ролей и прав. Повторный запуск безопасен
+func seedRoles(db *gorm.DB, assignExisting bool) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		permissions := make(map[consts.Permission]models.Permission, len(permissionDescriptions))
+		for code, description := range permissionDescriptions {
+			permission := models.Permission{Code: string(code), Description: description}
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "code"}},
+				DoUpdates: clause.AssignmentColumns([]string{"description"}),
+			}).Create(&permission).Error
+			if err != nil {
+				return err
+			}
+			// При конфликте postgres не всегда возвращает id, перечитываем
+			if err := tx.First(&permission, "code = ?", permission.Code).Error; err != nil {
+				return err
+			}
+			permissions[code] = permission
+		}
+
+		for name, seed := range roleSeeds {
+			role := models.Role{Name: string(name)}
+			if err := tx.Where(role).Attrs(models.Role{Description: seed.description}).FirstOrCreate(&role).Error; err != nil {
+				return err
+			}
+
+			rolePermissions := make([]models.Permission, len(seed.permissions))
+			for i, code := range seed.permissions {
+				rolePermissions[i] = permissions[code]
+			}
+			if err := tx.Model(&role).Omit("Permissions.*").Association("Permissions").Replace(rolePermissions); err != nil {
+				return err
+			}
+		}
+
+		if !assignExisting {
+			return nil
+		}
+
+		// Пользователи, созданные до появления ролей: администраторы получают
+		// роль admin, остальные - owner
+		if err := assignMissingRoles(tx, consts.RoleAdmin, "is_admin"); err != nil {
+			return err
+		}
+		return assignMissingRoles(tx, consts.RoleOwner, "NOT is_admin")
+	})
+}
+
+func assignMissingRoles(tx *gorm.DB, role consts.RoleName, condition string) error {
+	return tx.Exec(`
+		INSERT INTO user_roles (user_id, role_id)
+		SELECT u.id, r.id FROM users u, roles r
+		WHERE r.name = ? AND `+condition+`
+		AND NOT EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = u.id)`,
+		string(role),
+	).Error
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AbsenceKind string
+
+const (
+	AbsenceKindVacation AbsenceKind = "vacation"
+	AbsenceKindSick     AbsenceKind = "sick"
+	AbsenceKindOther    AbsenceKind = "other"
+)
+
+func (k AbsenceKind) IsValid() bool {
+	return k == AbsenceKindVacation || k == AbsenceKindSick || k == AbsenceKindOther
+}
+
+// Отсутствие сотрудника: отпуск, больничный. Интервал [StartAt, EndAt) в UTC
+type Absence struct {
+	gorm.Model
+	EmployeeID uint        `gorm:"not null;index" json:"employee_id"`
+	Kind       AbsenceKind `gorm:"not null;size:20" json:"kind"`
+	StartAt    time.Time   `gorm:"not null;index" json:"start_at"`
+	EndAt      time.Time   `gorm:"not null;index" json:"end_at"`
+	Comment    string      `gorm:"type:text" json:"comment"`
+}
+
+func (a *Absence) TableName() string {
+	return "absences"
+}
+
+type AbsenceResponse struct {
+	ID         uint        `json:"id"`
+	EmployeeID uint        `json:"employee_id"`
+	Kind       AbsenceKind `json:"kind"`
+	StartAt    time.Time   `json:"start_at"`
+	EndAt      time.Time   `json:"end_at"`
+	Comment    string      `json:"comment"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+func (a *Absence) ToResponse() AbsenceResponse {
+	return AbsenceResponse{
+		ID:         a.ID,
+		EmployeeID: a.EmployeeID,
+		Kind:       a.Kind,
+		StartAt:    a.StartAt,
+		EndAt:      a.EndAt,
+		Comment:    a.Comment,
+		CreatedAt:  a.CreatedAt,
+		UpdatedAt:  a.UpdatedAt,
+	}
+}
+
+// Календарь нерабочих праздничных дней владельца.
+// Действует для всех его сотрудников
+type HolidayCalendar struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Name   string `gorm:"not null;size:255" json:"name"`
+
+	Holidays []Holiday `gorm:"foreignKey:CalendarID" json:"holidays,omitempty"`
+}
+
+func (c *HolidayCalendar) TableName() string {
+	return "holiday_calendars"
+}
+
+type HolidayCalendarResponse struct {
+	ID        uint              `json:"id"`
+	UserID    uint              `json:"user_id"`
+	Name      string            `json:"name"`
+	Holidays  []HolidayResponse `json:"holidays,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Праздничные дни заполняются, если они загружены
+func (c *HolidayCalendar) ToResponse() HolidayCalendarResponse {
+	var holidays []HolidayResponse
+	if c.Holidays != nil {
+		holidays = make([]HolidayResponse, len(c.Holidays))
+		for i := range c.Holidays {
+			holidays[i] = c.Holidays[i].ToResponse()
+		}
+	}
+
+	return HolidayCalendarResponse{
+		ID:        c.ID,
+		UserID:    c.UserID,
+		Name:      c.Name,
+		Holidays:  holidays,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+}
+
+// Нерабочий день. Date - полночь UTC календарной даты
+type Holiday struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CalendarID uint      `gorm:"not null;uniqueIndex:idx_holidays_calendar_date" json:"calendar_id"`
+	Date       time.Time `gorm:"not null;type:date;uniqueIndex:idx_holidays_calendar_date" json:"date"`
+	Name       string    `gorm:"size:255" json:"name"`
+}
+
+func (h *Holiday) TableName() string {
+	return "holidays"
+}
+
+type HolidayResponse struct {
+	ID         uint      `json:"id"`
+	CalendarID uint      `json:"calendar_id"`
+	Date       time.Time `json:"date"`
+	Name       string    `json:"name"`
+}
+
+func (h *Holiday) ToResponse() HolidayResponse {
+	return HolidayResponse{
+		ID:         h.ID,
+		CalendarID: h.CalendarID,
+		Date:       h.Date,
+		Name:       h.Name,
+	}
+}
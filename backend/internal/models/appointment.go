@@ -0,0 +1,230 @@
+package models
+
+import (
+	"record-services/pkg/timezone"
+	"record-services/pkg/types"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AppointmentStatus string
+
+const (
+	AppointmentStatusPending   AppointmentStatus = "pending"
+	AppointmentStatusConfirmed AppointmentStatus = "confirmed"
+	AppointmentStatusCompleted AppointmentStatus = "completed"
+	AppointmentStatusCancelled AppointmentStatus = "cancelled"
+	AppointmentStatusNoShow    AppointmentStatus = "no_show"
+)
+
+// Exclusion constraints, запрещающие пересечение активных записей сотрудника
+// с учетом буферов и активных записей, занимающих один ресурс. Места одного
+// группового занятия друг с другом не конфликтуют
+const (
+	AppointmentNoOverlapConstraint       = "appointments_no_overlap"
+	AppointmentResourceOverlapConstraint = "appointments_no_resource_overlap"
+)
+
+var AppointmentExclusionConstraints = []string{AppointmentNoOverlapConstraint, AppointmentResourceOverlapConstraint}
+
+// Допустимые переходы статусов. Завершенные, отмененные и неявки не меняются
+var appointmentTransitions = map[AppointmentStatus][]AppointmentStatus{
+	AppointmentStatusPending:   {AppointmentStatusConfirmed, AppointmentStatusCancelled},
+	AppointmentStatusConfirmed: {AppointmentStatusCompleted, AppointmentStatusCancelled, AppointmentStatusNoShow},
+}
+
+func (s AppointmentStatus) IsValid() bool {
+	switch s {
+	case AppointmentStatusPending, AppointmentStatusConfirmed, AppointmentStatusCompleted,
+		AppointmentStatusCancelled, AppointmentStatusNoShow:
+		return true
+	}
+	return false
+}
+
+func (s AppointmentStatus) CanTransitionTo(next AppointmentStatus) bool {
+	for _, allowed := range appointmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Запись занимает время сотрудника
+func (s AppointmentStatus) IsActive() bool {
+	return s == AppointmentStatusPending || s == AppointmentStatusConfirmed
+}
+
+// Запись клиента к сотруднику на услугу секции.
+// Время хранится в UTC, интервал полуоткрытый: [StartAt, EndAt).
+// В ответах время показывается со смещением пояса TimeZone
+type Appointment struct {
+	gorm.Model
+	// Владелец, к которому относятся сотрудник и секция
+	UserID uint `gorm:"not null;index" json:"user_id"`
+
+	EmployeeID uint     `gorm:"not null;index" json:"employee_id"`
+	Employee   Employee `gorm:"foreignKey:EmployeeID" json:"-"`
+	SectionID  uint     `gorm:"not null;index" json:"section_id"`
+	Section    Section  `gorm:"foreignKey:SectionID" json:"-"`
+
+	// Карточка клиента. Имя и контакты ниже - копия на момент записи
+	ClientID    *uint  `gorm:"index" json:"client_id"`
+	ClientName  string `gorm:"not null;size:255" json:"client_name"`
+	ClientPhone string `gorm:"size:20;index" json:"client_phone"`
+	ClientEmail string `gorm:"size:255" json:"client_email"`
+	// Согласие на напоминания, данное при онлайн-записи. Действует и для
+	// записи без карточки клиента
+	RemindersConsent bool `gorm:"not null;default:false" json:"reminders_consent"`
+
+	StartAt time.Time         `gorm:"not null;index" json:"start_at"`
+	EndAt   time.Time         `gorm:"not null" json:"end_at"`
+	Status  AppointmentStatus `gorm:"not null;size:20;default:pending;index" json:"status"`
+	Comment string            `gorm:"type:text" json:"comment"`
+	// Часовой пояс IANA сотрудника на момент записи
+	TimeZone string `gorm:"not null;size:64;default:UTC" json:"time_zone"`
+
+	// Условия услуги на момент записи: изменения секции не влияют
+	// на уже созданные записи
+	Price               int64  `gorm:"not null;default:0" json:"price"`
+	Currency            string `gorm:"not null;size:3;default:'RUB'" json:"currency"`
+	BufferBeforeMinutes int    `gorm:"not null;default:0" json:"buffer_before_minutes"`
+	BufferAfterMinutes  int    `gorm:"not null;default:0" json:"buffer_after_minutes"`
+	// Время вместе с буферами, заполняется при сохранении. По нему БД
+	// проверяет пересечения: exclusion constraint не может вычислить
+	// интервал сам, вычитание интервала из timestamptz не immutable
+	OccupiedFrom time.Time `json:"-"`
+	OccupiedTo   time.Time `json:"-"`
+
+	// Серия, к которой относится запись, и время вхождения по правилу серии.
+	// Измененное отдельно вхождение становится исключением: изменения
+	// серии его не затрагивают
+	SeriesID        *uint      `gorm:"index" json:"series_id"`
+	OccurrenceAt    *time.Time `json:"occurrence_at"`
+	SeriesException bool       `gorm:"not null;default:false" json:"series_exception"`
+
+	// Групповое занятие, место в котором занимает запись
+	SessionID *uint `gorm:"index" json:"session_id"`
+	// Ресурсы, которые нужны услуге на момент записи
+	ResourceIDs types.IDList `gorm:"not null;default:'{}'" json:"resource_ids"`
+
+	// SHA-256 токена ссылки, по которой клиент управляет записью без аккаунта
+	ManageTokenHash *string `gorm:"size:64;uniqueIndex" json:"-"`
+
+	// Когда напоминания были запланированы. Раньше UpdatedAt или пусто -
+	// напоминания по текущему времени записи еще не запланированы
+	RemindersScheduledAt *time.Time `json:"-"`
+}
+
+// Время, которое запись занимает у сотрудника вместе с буферами
+func (a *Appointment) OccupiedInterval() (time.Time, time.Time) {
+	return a.StartAt.Add(-time.Duration(a.BufferBeforeMinutes) * time.Minute),
+		a.EndAt.Add(time.Duration(a.BufferAfterMinutes) * time.Minute)
+}
+
+func (a *Appointment) BeforeSave(tx *gorm.DB) error {
+	a.OccupiedFrom, a.OccupiedTo = a.OccupiedInterval()
+	return nil
+}
+
+func (a *Appointment) TableName() string {
+	return "appointments"
+}
+
+func (a *Appointment) Location() *time.Location {
+	return timezone.LoadOrUTC(a.TimeZone)
+}
+
+type AppointmentResponse struct {
+	ID               uint              `json:"id"`
+	UserID           uint              `json:"user_id"`
+	EmployeeID       uint              `json:"employee_id"`
+	EmployeeName     string            `json:"employee_name"`
+	SectionID        uint              `json:"section_id"`
+	SectionName      string            `json:"section_name"`
+	ClientID         *uint             `json:"client_id"`
+	ClientName       string            `json:"client_name"`
+	ClientPhone      string            `json:"client_phone"`
+	ClientEmail      string            `json:"client_email"`
+	RemindersConsent bool              `json:"reminders_consent"`
+	StartAt          time.Time         `json:"start_at"`
+	EndAt            time.Time         `json:"end_at"`
+	TimeZone         string            `json:"time_zone"`
+	Status           AppointmentStatus `json:"status"`
+	Comment          string            `json:"comment"`
+	Price            int64             `json:"price"`
+	Currency         string            `json:"currency"`
+	SeriesID         *uint             `json:"series_id"`
+	OccurrenceAt     *time.Time        `json:"occurrence_at"`
+	SeriesException  bool              `json:"series_exception"`
+	SessionID        *uint             `json:"session_id"`
+	ResourceIDs      []uint            `json:"resource_ids"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// Имена сотрудника и секции заполняются, если связи загружены.
+// Время - в часовом поясе записи
+func (a *Appointment) ToResponse() AppointmentResponse {
+	loc := a.Location()
+
+	var occurrenceAt *time.Time
+	if a.OccurrenceAt != nil {
+		local := a.OccurrenceAt.In(loc)
+		occurrenceAt = &local
+	}
+
+	return AppointmentResponse{
+		ID:               a.ID,
+		UserID:           a.UserID,
+		EmployeeID:       a.EmployeeID,
+		EmployeeName:     a.Employee.Name,
+		SectionID:        a.SectionID,
+		SectionName:      a.Section.Name,
+		ClientID:         a.ClientID,
+		ClientName:       a.ClientName,
+		ClientPhone:      a.ClientPhone,
+		ClientEmail:      a.ClientEmail,
+		RemindersConsent: a.RemindersConsent,
+		StartAt:          a.StartAt.In(loc),
+		EndAt:            a.EndAt.In(loc),
+		TimeZone:         a.TimeZone,
+		Status:           a.Status,
+		Comment:          a.Comment,
+		Price:            a.Price,
+		Currency:         a.Currency,
+		SeriesID:         a.SeriesID,
+		OccurrenceAt:     occurrenceAt,
+		SeriesException:  a.SeriesException,
+		SessionID:        a.SessionID,
+		ResourceIDs:      a.ResourceIDs,
+		CreatedAt:        a.CreatedAt,
+		UpdatedAt:        a.UpdatedAt,
+	}
+}
+
+// Фильтр списка записей, нулевые значения не применяются.
+// From/To выбирают записи, пересекающиеся с интервалом
+type AppointmentFilter struct {
+	UserID     uint
+	EmployeeID uint
+	SectionID  uint
+	ClientID   uint
+	SeriesID   uint
+	SessionID  uint
+	ResourceID uint
+	Status     AppointmentStatus
+	From       *time.Time
+	To         *time.Time
+}
+
+type PaginatedAppointments struct {
+	Appointments []AppointmentResponse `json:"appointments"`
+	TotalCount   int64                 `json:"total_count"`
+	TotalPages   int                   `json:"total_pages"`
+	Page         int                   `json:"page"`
+	Limit        int                   `json:"limit"`
+	HasMore      bool                  `json:"has_more"`
+}
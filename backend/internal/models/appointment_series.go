@@ -0,0 +1,46 @@
+package models
+
+import (
+	"record-services/pkg/timezone"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Серия повторяющихся записей по правилу RRULE. Вхождения хранятся
+// обычными записями со ссылкой на серию: на каждое время правила
+// создается запись с OccurrenceAt, равным этому времени
+type AppointmentSeries struct {
+	gorm.Model
+	UserID uint `gorm:"not null;index" json:"user_id"`
+
+	EmployeeID uint `gorm:"not null;index" json:"employee_id"`
+	SectionID  uint `gorm:"not null;index" json:"section_id"`
+
+	ClientID    *uint  `gorm:"index" json:"client_id"`
+	ClientName  string `gorm:"not null;size:255" json:"client_name"`
+	ClientPhone string `gorm:"size:20" json:"client_phone"`
+	ClientEmail string `gorm:"size:255" json:"client_email"`
+
+	// Правило без префикса RRULE: и первое вхождение (DTSTART) в UTC.
+	// Правило раскрывается по часам пояса TimeZone (DTSTART;TZID),
+	// так что при переходе на летнее время вхождения не сдвигаются
+	RRule           string    `gorm:"not null;size:255" json:"rrule"`
+	StartAt         time.Time `gorm:"not null" json:"start_at"`
+	TimeZone        string    `gorm:"not null;size:64;default:UTC" json:"time_zone"`
+	DurationMinutes int       `gorm:"not null" json:"duration_minutes"`
+	Comment         string    `gorm:"type:text" json:"comment"`
+}
+
+func (s *AppointmentSeries) TableName() string {
+	return "appointment_series"
+}
+
+// Первое вхождение в часовом поясе серии, от него раскрывается правило
+func (s *AppointmentSeries) LocalStart() time.Time {
+	return s.StartAt.In(timezone.LoadOrUTC(s.TimeZone))
+}
+
+func (s *AppointmentSeries) Duration() time.Duration {
+	return time.Duration(s.DurationMinutes) * time.Minute
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+type AuditAction string
+
+const (
+	AuditActionUserApprove     AuditAction = "user.approve"
+	AuditActionUserReject      AuditAction = "user.reject"
+	AuditActionUserActivate    AuditAction = "user.activate"
+	AuditActionUserDeactivate  AuditAction = "user.deactivate"
+	AuditActionUserGrantAdmin  AuditAction = "user.grant_admin"
+	AuditActionUserRevokeAdmin AuditAction = "user.revoke_admin"
+	AuditActionUserSetRoles    AuditAction = "user.set_roles"
+	AuditActionUserSetOwner    AuditAction = "user.set_owner"
+	AuditActionUserDelete      AuditAction = "user.delete"
+	AuditActionUserRestore     AuditAction = "user.restore"
+)
+
+// Запись журнала действий администраторов. Записи не изменяются и не удаляются
+type AuditLog struct {
+	ID         uint        `gorm:"primarykey" json:"id"`
+	ActorID    uint        `gorm:"not null;index" json:"actor_id"`
+	Action     AuditAction `gorm:"not null;size:50;index" json:"action"`
+	TargetType string      `gorm:"not null;size:50" json:"target_type"`
+	TargetID   uint        `gorm:"not null;index" json:"target_id"`
+	Details    string      `gorm:"type:text" json:"details"`
+	IP         string      `gorm:"size:45" json:"ip"`
+	CreatedAt  time.Time   `gorm:"index" json:"created_at"`
+}
+
+func (a *AuditLog) TableName() string {
+	return "audit_logs"
+}
+
+// Фильтр журнала, нулевые значения не применяются
+type AuditFilter struct {
+	ActorID    uint
+	TargetType string
+	TargetID   uint
+	Action     AuditAction
+}
+
+type PaginatedAuditLogs struct {
+	Entries    []AuditLog `json:"entries"`
+	TotalCount int64      `json:"total_count"`
+	TotalPages int        `json:"total_pages"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+	HasMore    bool       `json:"has_more"`
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Страница онлайн-записи владельца, доступна без авторизации
+// по адресу /api/public/{slug}
+type BookingPage struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;uniqueIndex" json:"user_id"`
+	Slug        string `gorm:"not null;size:50;uniqueIndex" json:"slug"`
+	Title       string `gorm:"not null;size:255" json:"title"`
+	Description string `gorm:"type:text" json:"description"`
+	IsEnabled   bool   `gorm:"not null;default:false" json:"is_enabled"`
+}
+
+func (p *BookingPage) TableName() string {
+	return "booking_pages"
+}
+
+type BookingPageResponse struct {
+	ID          uint      `json:"id"`
+	UserID      uint      `json:"user_id"`
+	Slug        string    `json:"slug"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	IsEnabled   bool      `json:"is_enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (p *BookingPage) ToResponse() BookingPageResponse {
+	return BookingPageResponse{
+		ID:          p.ID,
+		UserID:      p.UserID,
+		Slug:        p.Slug,
+		Title:       p.Title,
+		Description: p.Description,
+		IsEnabled:   p.IsEnabled,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+	}
+}
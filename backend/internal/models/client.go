@@ -0,0 +1,112 @@
+package models
+
+import (
+	"record-services/pkg/types"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Уникальные индексы, по которым клиенты владельца не дублируются
+const (
+	ClientPhoneUniqueIndex = "idx_clients_user_phone"
+	ClientEmailUniqueIndex = "idx_clients_user_email"
+)
+
+// Клиент владельца. Не связан с пользователями системы.
+// Phone хранится в формате E.164, Email - в нижнем регистре
+type Client struct {
+	gorm.Model
+	UserID uint `gorm:"not null;index" json:"user_id"`
+
+	Name  string           `gorm:"not null;size:255;index" json:"name"`
+	Phone string           `gorm:"size:16" json:"phone"`
+	Email string           `gorm:"size:255" json:"email"`
+	Notes string           `gorm:"type:text" json:"notes"`
+	Tags  types.StringList `gorm:"not null;default:'[]'" json:"tags"`
+
+	// Согласия клиента на напоминания о записи и рекламные рассылки
+	RemindersConsent bool `gorm:"not null;default:false" json:"reminders_consent"`
+	MarketingConsent bool `gorm:"not null;default:false" json:"marketing_consent"`
+}
+
+func (c *Client) TableName() string {
+	return "clients"
+}
+
+// Добавляет теги, которых еще нет у клиента
+func (c *Client) AddTags(tags []string) {
+	existing := make(map[string]bool, len(c.Tags))
+	for _, tag := range c.Tags {
+		existing[tag] = true
+	}
+	for _, tag := range tags {
+		if !existing[tag] {
+			existing[tag] = true
+			c.Tags = append(c.Tags, tag)
+		}
+	}
+}
+
+// Статистика визитов клиента по записям
+type ClientVisitStats struct {
+	Total       int64      `json:"total"`
+	Completed   int64      `json:"completed"`
+	Cancelled   int64      `json:"cancelled"`
+	NoShow      int64      `json:"no_show"`
+	LastVisitAt *time.Time `json:"last_visit_at"`
+	NextVisitAt *time.Time `json:"next_visit_at"`
+}
+
+type ClientResponse struct {
+	ID               uint              `json:"id"`
+	UserID           uint              `json:"user_id"`
+	Name             string            `json:"name"`
+	Phone            string            `json:"phone"`
+	Email            string            `json:"email"`
+	Notes            string            `json:"notes"`
+	Tags             []string          `json:"tags"`
+	RemindersConsent bool              `json:"reminders_consent"`
+	MarketingConsent bool              `json:"marketing_consent"`
+	Stats            *ClientVisitStats `json:"stats,omitempty"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+func (c *Client) ToResponse() ClientResponse {
+	tags := c.Tags
+	if tags == nil {
+		tags = types.StringList{}
+	}
+
+	return ClientResponse{
+		ID:               c.ID,
+		UserID:           c.UserID,
+		Name:             c.Name,
+		Phone:            c.Phone,
+		Email:            c.Email,
+		Notes:            c.Notes,
+		Tags:             tags,
+		RemindersConsent: c.RemindersConsent,
+		MarketingConsent: c.MarketingConsent,
+		CreatedAt:        c.CreatedAt,
+		UpdatedAt:        c.UpdatedAt,
+	}
+}
+
+// Фильтр списка клиентов, пустые поля не применяются.
+// Search ищет по имени, телефону и email
+type ClientFilter struct {
+	UserID uint
+	Search string
+	Tag    string
+}
+
+type PaginatedClients struct {
+	Clients    []ClientResponse `json:"clients"`
+	TotalCount int64            `json:"total_count"`
+	TotalPages int              `json:"total_pages"`
+	Page       int              `json:"page"`
+	Limit      int              `json:"limit"`
+	HasMore    bool             `json:"has_more"`
+}
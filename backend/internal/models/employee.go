@@ -1,6 +1,11 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"record-services/pkg/types"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Employee struct {
 	gorm.Model
@@ -8,6 +13,8 @@ type Employee struct {
 	Email    string `gorm:"size:255;index" json:"email" validate:"omitempty,email"`
 	Phone    string `gorm:"size:20" json:"phone"`
 	IsActive bool   `gorm:"not null;default:false;index" json:"is_active"`
+	// Часовой пояс IANA графика сотрудника. Пустой - пояс владельца
+	TimeZone string `gorm:"not null;size:64;default:''" json:"time_zone"`
 
 	// Принадлежит пользователю (владелец)
 	UserID uint `gorm:"not null;index" json:"user_id"`
@@ -16,3 +23,68 @@ type Employee struct {
 	// Связь многие-ко-многим с секциями
 	Sections []Section `gorm:"many2many:employee_sections;" json:"sections,omitempty"`
 }
+
+func (e *Employee) TableName() string {
+	return "employees"
+}
+
+// Сотрудник назначен в секцию. Секции должны быть загружены
+func (e *Employee) HasSection(sectionID uint) bool {
+	for _, section := range e.Sections {
+		if section.ID == sectionID {
+			return true
+		}
+	}
+	return false
+}
+
+type EmployeeResponse struct {
+	ID        uint              `json:"id"`
+	Name      string            `json:"name"`
+	Email     string            `json:"email"`
+	Phone     string            `json:"phone"`
+	IsActive  bool              `json:"is_active"`
+	TimeZone  string            `json:"time_zone"`
+	UserID    uint              `json:"user_id"`
+	Sections  []SectionResponse `json:"sections"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+func (e *Employee) ToResponse() EmployeeResponse {
+	sections := make([]SectionResponse, len(e.Sections))
+	for i := range e.Sections {
+		sections[i] = e.Sections[i].ToResponse()
+	}
+
+	return EmployeeResponse{
+		ID:        e.ID,
+		Name:      e.Name,
+		Email:     e.Email,
+		Phone:     e.Phone,
+		IsActive:  e.IsActive,
+		TimeZone:  e.TimeZone,
+		UserID:    e.UserID,
+		Sections:  sections,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+// Фильтр списка сотрудников, пустые поля не применяются.
+// Search ищет по имени и телефону
+type EmployeeFilter struct {
+	UserID    uint
+	SectionID uint
+	IsActive  types.CheckValue
+	Search    string
+}
+
+type PaginatedEmployees struct {
+	Employees  []EmployeeResponse `json:"employees"`
+	TotalCount int64              `json:"total_count"`
+	TotalPages int                `json:"total_pages"`
+	Page       int                `json:"page"`
+	Limit      int                `json:"limit"`
+	HasMore    bool               `json:"has_more"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"record-services/pkg/timezone"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Уникальный индекс: у сотрудника одно занятие по услуге на одно время
+const GroupSessionSlotIndex = "idx_group_sessions_slot"
+
+// Занятие групповой услуги: время сотрудника, на которое записываются
+// несколько клиентов. Каждое место - отдельная запись с SessionID занятия.
+// Занятие создается при записи на первое место, вместимость берется
+// из секции на этот момент
+type GroupSession struct {
+	gorm.Model
+	// Владелец, к которому относятся сотрудник и секция
+	UserID uint `gorm:"not null;index" json:"user_id"`
+
+	EmployeeID uint     `gorm:"not null;index" json:"employee_id"`
+	Employee   Employee `gorm:"foreignKey:EmployeeID" json:"-"`
+	SectionID  uint     `gorm:"not null;index" json:"section_id"`
+	Section    Section  `gorm:"foreignKey:SectionID" json:"-"`
+
+	StartAt  time.Time `gorm:"not null;index" json:"start_at"`
+	EndAt    time.Time `gorm:"not null" json:"end_at"`
+	Capacity int       `gorm:"not null" json:"capacity"`
+	// Часовой пояс IANA сотрудника на момент создания занятия
+	TimeZone string `gorm:"not null;size:64;default:UTC" json:"time_zone"`
+
+	// Занятые места - активные записи. Заполняется при чтении
+	Booked int `gorm:"->;-:migration" json:"booked"`
+}
+
+func (s *GroupSession) TableName() string {
+	return "group_sessions"
+}
+
+func (s *GroupSession) Available() int {
+	return max(s.Capacity-s.Booked, 0)
+}
+
+type GroupSessionResponse struct {
+	ID           uint      `json:"id"`
+	UserID       uint      `json:"user_id"`
+	EmployeeID   uint      `json:"employee_id"`
+	EmployeeName string    `json:"employee_name"`
+	SectionID    uint      `json:"section_id"`
+	SectionName  string    `json:"section_name"`
+	StartAt      time.Time `json:"start_at"`
+	EndAt        time.Time `json:"end_at"`
+	TimeZone     string    `json:"time_zone"`
+	Capacity     int       `json:"capacity"`
+	Booked       int       `json:"booked"`
+	Available    int       `json:"available"`
+	// Только в карточке занятия: записи на места, включая отмененные
+	Attendees []AppointmentResponse `json:"attendees,omitempty"`
+}
+
+// Имена сотрудника и секции заполняются, если связи загружены.
+// Время - в часовом поясе занятия
+func (s *GroupSession) ToResponse() GroupSessionResponse {
+	loc := timezone.LoadOrUTC(s.TimeZone)
+
+	return GroupSessionResponse{
+		ID:           s.ID,
+		UserID:       s.UserID,
+		EmployeeID:   s.EmployeeID,
+		EmployeeName: s.Employee.Name,
+		SectionID:    s.SectionID,
+		SectionName:  s.Section.Name,
+		StartAt:      s.StartAt.In(loc),
+		EndAt:        s.EndAt.In(loc),
+		TimeZone:     s.TimeZone,
+		Capacity:     s.Capacity,
+		Booked:       s.Booked,
+		Available:    s.Available(),
+	}
+}
+
+// Фильтр списка занятий, нулевые значения не применяются.
+// From/To выбирают занятия, начинающиеся в интервале [From, To)
+type GroupSessionFilter struct {
+	UserID      uint
+	SectionID   uint
+	EmployeeIDs []uint
+	From        *time.Time
+	To          *time.Time
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending JobStatus = "pending"
+	// Задача взята обработчиком до LockedUntil. Если процесс остановился,
+	// после LockedUntil задача берется снова
+	JobStatusRunning   JobStatus = "running"
+	JobStatusDone      JobStatus = "done"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// Фоновая задача, выполняемая не раньше RunAt. Хранится в БД, поэтому
+// переживает перезапуск сервера. Key объединяет задачи одного объекта,
+// например напоминания одной записи, чтобы заменять и отменять их вместе
+type Job struct {
+	gorm.Model
+	Kind    string    `gorm:"not null;size:50" json:"kind"`
+	Key     string    `gorm:"not null;size:100;index" json:"key"`
+	Payload string    `gorm:"type:text;not null" json:"payload"`
+	RunAt   time.Time `gorm:"not null;index:idx_jobs_due,priority:2" json:"run_at"`
+	Status  JobStatus `gorm:"not null;size:20;default:pending;index:idx_jobs_due,priority:1" json:"status"`
+
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	LastError   string     `gorm:"type:text" json:"last_error"`
+	LockedUntil *time.Time `json:"locked_until"`
+}
+
+func (j *Job) TableName() string {
+	return "jobs"
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Одноразовый код восстановления для входа без приложения-аутентификатора
+type RecoveryCode struct {
+	gorm.Model
+	UserID   uint       `gorm:"not null;index" json:"user_id"`
+	User     User       `gorm:"foreignKey:UserID" json:"-"`
+	CodeHash string     `gorm:"not null;size:64;index" json:"-"`
+	UsedAt   *time.Time `json:"used_at"`
+}
+
+func (c *RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
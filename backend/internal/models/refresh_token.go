@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Refresh токен. Все токены, полученные ротацией от одного логина,
+// образуют семейство (FamilyID) - это и есть сессия пользователя
+type RefreshToken struct {
+	gorm.Model
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	User      User       `gorm:"foreignKey:UserID" json:"-"`
+	TokenHash string     `gorm:"not null;uniqueIndex;size:64" json:"-"`
+	FamilyID  string     `gorm:"not null;index;size:64" json:"family_id"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	UserAgent string     `gorm:"size:255" json:"user_agent"`
+	IP        string     `gorm:"size:45" json:"ip"`
+}
+
+func (t *RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// Токен можно обменять на новую пару токенов
+func (t *RefreshToken) IsActive(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Кому отправляется напоминание о записи
+type ReminderRecipient string
+
+const (
+	ReminderRecipientClient   ReminderRecipient = "client"
+	ReminderRecipientEmployee ReminderRecipient = "employee"
+)
+
+// Правило напоминаний владельца: письмо получателю за MinutesBefore
+// минут до начала каждой активной записи. Клиенту напоминание
+// отправляется только с его согласия
+type ReminderRule struct {
+	gorm.Model
+	UserID        uint              `gorm:"not null;index" json:"user_id"`
+	Recipient     ReminderRecipient `gorm:"not null;size:20" json:"recipient"`
+	MinutesBefore int               `gorm:"not null" json:"minutes_before"`
+}
+
+func (r *ReminderRule) TableName() string {
+	return "reminder_rules"
+}
+
+func (r *ReminderRule) Before() time.Duration {
+	return time.Duration(r.MinutesBefore) * time.Minute
+}
+
+type ReminderRuleResponse struct {
+	ID            uint              `json:"id"`
+	Recipient     ReminderRecipient `json:"recipient"`
+	MinutesBefore int               `json:"minutes_before"`
+}
+
+func (r *ReminderRule) ToResponse() ReminderRuleResponse {
+	return ReminderRuleResponse{
+		ID:            r.ID,
+		Recipient:     r.Recipient,
+		MinutesBefore: r.MinutesBefore,
+	}
+}
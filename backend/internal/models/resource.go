@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Ресурс владельца: помещение или оборудование, без которого не оказать
+// услугу. Секция задает нужные ей ресурсы, и запись занимает их на свое
+// время так же, как время сотрудника
+type Resource struct {
+	gorm.Model
+	Name    string `gorm:"not null;size:255;index" json:"name"`
+	Comment string `gorm:"type:text" json:"comment"`
+
+	UserID uint `gorm:"not null;index" json:"user_id"`
+	User   User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+func (r *Resource) TableName() string {
+	return "resources"
+}
+
+type ResourceResponse struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Comment   string    `json:"comment"`
+	UserID    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r *Resource) ToResponse() ResourceResponse {
+	return ResourceResponse{
+		ID:        r.ID,
+		Name:      r.Name,
+		Comment:   r.Comment,
+		UserID:    r.UserID,
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+// Фильтр списка ресурсов. UserID = 0 - ресурсы всех пользователей
+type ResourceFilter struct {
+	UserID uint
+	Name   string
+}
+
+type PaginatedResources struct {
+	Resources  []ResourceResponse `json:"resources"`
+	TotalCount int64              `json:"total_count"`
+	TotalPages int                `json:"total_pages"`
+	Page       int                `json:"page"`
+	Limit      int                `json:"limit"`
+	HasMore    bool               `json:"has_more"`
+}
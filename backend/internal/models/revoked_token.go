@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type RevocationKind string
+
+const (
+	// Отозван один access токен (jti)
+	RevocationKindToken RevocationKind = "token"
+	// Отозваны все access токены сессии (sid)
+	RevocationKindSession RevocationKind = "session"
+	// Отозваны все access токены пользователя, выпущенные до RevokedAt
+	RevocationKindUser RevocationKind = "user"
+	// Использован промежуточный MFA токен (jti): повторный вход по нему невозможен
+	RevocationKindMFAChallenge RevocationKind = "mfa_challenge"
+)
+
+// Запись об отзыве. Хранится, пока не истекут все access токены,
+// которые она может заблокировать
+type RevokedToken struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	Kind      RevocationKind `gorm:"not null;size:20;uniqueIndex:idx_revoked_tokens_kind_value" json:"kind"`
+	Value     string         `gorm:"not null;size:64;uniqueIndex:idx_revoked_tokens_kind_value" json:"value"`
+	RevokedAt time.Time      `gorm:"not null" json:"revoked_at"`
+	ExpiresAt time.Time      `gorm:"not null;index" json:"expires_at"`
+}
+
+func (t *RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
@@ -0,0 +1,22 @@
+package models
+
+type Permission struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Code        string `gorm:"not null;uniqueIndex;size:100" json:"code"`
+	Description string `gorm:"size:255" json:"description"`
+}
+
+func (p *Permission) TableName() string {
+	return "permissions"
+}
+
+type Role struct {
+	ID          uint         `gorm:"primarykey" json:"id"`
+	Name        string       `gorm:"not null;uniqueIndex;size:50" json:"name"`
+	Description string       `gorm:"size:255" json:"description"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
+}
+
+func (r *Role) TableName() string {
+	return "roles"
+}
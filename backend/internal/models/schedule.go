@@ -0,0 +1,134 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Время суток в минутах от полуночи. В JSON - строка "HH:MM",
+// "24:00" допустимо как конец рабочего дня
+type ClockTime int
+
+const MinutesPerDay ClockTime = 24 * 60
+
+func (c ClockTime) String() string {
+	return fmt.Sprintf("%02d:%02d", int(c)/60, int(c)%60)
+}
+
+func (c ClockTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c *ClockTime) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	if len(value) != 5 || value[2] != ':' {
+		return fmt.Errorf("некорректное время %q, ожидается HH:MM", value)
+	}
+	hours, errHours := strconv.Atoi(value[:2])
+	minutes, errMinutes := strconv.Atoi(value[3:])
+	if errHours != nil || errMinutes != nil || hours < 0 || minutes < 0 || minutes > 59 || hours*60+minutes > int(MinutesPerDay) {
+		return fmt.Errorf("некорректное время %q", value)
+	}
+
+	*c = ClockTime(hours*60 + minutes)
+	return nil
+}
+
+// Момент времени t на дату date в часовом поясе даты
+func (c ClockTime) On(date time.Time) time.Time {
+	year, month, day := date.Date()
+	return time.Date(year, month, day, 0, int(c), 0, 0, date.Location())
+}
+
+// Интервал недельного графика сотрудника. Перерывы вычитаются
+// из рабочих интервалов того же дня
+type WeeklySchedule struct {
+	gorm.Model
+	EmployeeID uint         `gorm:"not null;index" json:"employee_id"`
+	Weekday    time.Weekday `gorm:"not null" json:"weekday"`
+	Start      ClockTime    `gorm:"column:start_minute;not null" json:"start"`
+	End        ClockTime    `gorm:"column:end_minute;not null" json:"end"`
+	IsBreak    bool         `gorm:"not null;default:false" json:"is_break"`
+}
+
+func (s *WeeklySchedule) TableName() string {
+	return "weekly_schedules"
+}
+
+type WeeklyScheduleResponse struct {
+	ID         uint         `json:"id"`
+	EmployeeID uint         `json:"employee_id"`
+	Weekday    time.Weekday `json:"weekday"`
+	Start      ClockTime    `json:"start"`
+	End        ClockTime    `json:"end"`
+	IsBreak    bool         `json:"is_break"`
+}
+
+func (s *WeeklySchedule) ToResponse() WeeklyScheduleResponse {
+	return WeeklyScheduleResponse{
+		ID:         s.ID,
+		EmployeeID: s.EmployeeID,
+		Weekday:    s.Weekday,
+		Start:      s.Start,
+		End:        s.End,
+		IsBreak:    s.IsBreak,
+	}
+}
+
+// График на конкретную дату, заменяет недельный. Выходной день задается
+// одной строкой с IsDayOff, интервалы при этом не учитываются.
+// Date хранится как полночь UTC календарной даты
+type ScheduleOverride struct {
+	gorm.Model
+	EmployeeID uint      `gorm:"not null;index:idx_schedule_overrides_employee_date" json:"employee_id"`
+	Date       time.Time `gorm:"not null;type:date;index:idx_schedule_overrides_employee_date" json:"date"`
+	IsDayOff   bool      `gorm:"not null;default:false" json:"is_day_off"`
+	Start      ClockTime `gorm:"column:start_minute;not null;default:0" json:"start"`
+	End        ClockTime `gorm:"column:end_minute;not null;default:0" json:"end"`
+	IsBreak    bool      `gorm:"not null;default:false" json:"is_break"`
+}
+
+func (o *ScheduleOverride) TableName() string {
+	return "schedule_overrides"
+}
+
+type ScheduleOverrideResponse struct {
+	ID         uint      `json:"id"`
+	EmployeeID uint      `json:"employee_id"`
+	Date       time.Time `json:"date"`
+	IsDayOff   bool      `json:"is_day_off"`
+	Start      ClockTime `json:"start"`
+	End        ClockTime `json:"end"`
+	IsBreak    bool      `json:"is_break"`
+}
+
+func (o *ScheduleOverride) ToResponse() ScheduleOverrideResponse {
+	return ScheduleOverrideResponse{
+		ID:         o.ID,
+		EmployeeID: o.EmployeeID,
+		Date:       o.Date,
+		IsDayOff:   o.IsDayOff,
+		Start:      o.Start,
+		End:        o.End,
+		IsBreak:    o.IsBreak,
+	}
+}
+
+// Свободное время сотрудника под запись. Для групповой услуги слот -
+// занятие: существующее с SessionID или новое, и свободные в нем места
+type Slot struct {
+	EmployeeID uint      `json:"employee_id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	SessionID  *uint     `json:"session_id,omitempty"`
+	Capacity   int       `json:"capacity"`
+	Available  int       `json:"available"`
+}
@@ -1,19 +1,191 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// Значения параметров услуги для новых секций
+const (
+	DefaultSectionDuration = 60
+	DefaultCurrency        = "RUB"
+	DefaultSectionCapacity = 1
+)
+
+// Секция - услуга владельца. Длительность, буферы и минимальное время
+// до записи хранятся в минутах, цена - в минимальных единицах валюты (копейках)
 type Section struct {
 	gorm.Model
 	Name    string `gorm:"not null;size:255;index" json:"name"`
 	Comment string `gorm:"type:text" json:"comment"`
 
+	DurationMinutes     int    `gorm:"not null;default:60" json:"duration_minutes"`
+	Price               int64  `gorm:"not null;default:0" json:"price"`
+	Currency            string `gorm:"not null;size:3;default:'RUB'" json:"currency"`
+	BufferBeforeMinutes int    `gorm:"not null;default:0" json:"buffer_before_minutes"`
+	BufferAfterMinutes  int    `gorm:"not null;default:0" json:"buffer_after_minutes"`
+	// На сколько дней вперед можно записаться, 0 - без ограничения
+	MaxAdvanceDays   int `gorm:"not null;default:0" json:"max_advance_days"`
+	MinNoticeMinutes int `gorm:"not null;default:0" json:"min_notice_minutes"`
+	// Мест в одном занятии. Больше одного - групповая услуга: клиенты
+	// записываются на места в занятии сотрудника
+	Capacity int `gorm:"not null;default:1" json:"capacity"`
+
 	UserID uint `gorm:"not null;index" json:"user_id"`
 	User   User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 
 	// Связь многие-ко-многим с сотрудниками
 	Employees []Employee `gorm:"many2many:employee_sections;" json:"employees,omitempty"`
+	// Ресурсы, которые занимает каждая запись на услугу
+	Resources []Resource `gorm:"many2many:section_resources;" json:"resources,omitempty"`
 }
 
 func (s *Section) TableName() string {
 	return "sections"
 }
+
+func (s *Section) IsGroup() bool {
+	return s.Capacity > 1
+}
+
+// Ресурсы должны быть загружены
+func (s *Section) ResourceIDs() []uint {
+	ids := make([]uint, len(s.Resources))
+	for i := range s.Resources {
+		ids[i] = s.Resources[i].ID
+	}
+	return ids
+}
+
+// Условия услуги для сотрудника: параметры секции с учетом
+// переопределений из employee_sections. override может быть nil
+func (s *Section) Terms(override *EmployeeSection) ServiceTerms {
+	terms := ServiceTerms{
+		Duration:     time.Duration(s.DurationMinutes) * time.Minute,
+		BufferBefore: time.Duration(s.BufferBeforeMinutes) * time.Minute,
+		BufferAfter:  time.Duration(s.BufferAfterMinutes) * time.Minute,
+		Price:        s.Price,
+		Currency:     s.Currency,
+		MaxAdvance:   time.Duration(s.MaxAdvanceDays) * 24 * time.Hour,
+		MinNotice:    time.Duration(s.MinNoticeMinutes) * time.Minute,
+		Capacity:     max(s.Capacity, DefaultSectionCapacity),
+		Resources:    s.ResourceIDs(),
+	}
+
+	if override == nil {
+		return terms
+	}
+	if override.DurationMinutes != nil {
+		terms.Duration = time.Duration(*override.DurationMinutes) * time.Minute
+	}
+	if override.Price != nil {
+		terms.Price = *override.Price
+	}
+	if override.BufferBeforeMinutes != nil {
+		terms.BufferBefore = time.Duration(*override.BufferBeforeMinutes) * time.Minute
+	}
+	if override.BufferAfterMinutes != nil {
+		terms.BufferAfter = time.Duration(*override.BufferAfterMinutes) * time.Minute
+	}
+	return terms
+}
+
+type SectionResponse struct {
+	ID                  uint               `json:"id"`
+	Name                string             `json:"name"`
+	Comment             string             `json:"comment"`
+	DurationMinutes     int                `json:"duration_minutes"`
+	Price               int64              `json:"price"`
+	Currency            string             `json:"currency"`
+	BufferBeforeMinutes int                `json:"buffer_before_minutes"`
+	BufferAfterMinutes  int                `json:"buffer_after_minutes"`
+	MaxAdvanceDays      int                `json:"max_advance_days"`
+	MinNoticeMinutes    int                `json:"min_notice_minutes"`
+	Capacity            int                `json:"capacity"`
+	Resources           []ResourceResponse `json:"resources"`
+	UserID              uint               `json:"user_id"`
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
+}
+
+func (s *Section) ToResponse() SectionResponse {
+	resources := make([]ResourceResponse, len(s.Resources))
+	for i := range s.Resources {
+		resources[i] = s.Resources[i].ToResponse()
+	}
+
+	return SectionResponse{
+		ID:                  s.ID,
+		Name:                s.Name,
+		Comment:             s.Comment,
+		DurationMinutes:     s.DurationMinutes,
+		Price:               s.Price,
+		Currency:            s.Currency,
+		BufferBeforeMinutes: s.BufferBeforeMinutes,
+		BufferAfterMinutes:  s.BufferAfterMinutes,
+		MaxAdvanceDays:      s.MaxAdvanceDays,
+		MinNoticeMinutes:    s.MinNoticeMinutes,
+		Capacity:            s.Capacity,
+		Resources:           resources,
+		UserID:              s.UserID,
+		CreatedAt:           s.CreatedAt,
+		UpdatedAt:           s.UpdatedAt,
+	}
+}
+
+// Назначение сотрудника в секцию. Заданные поля переопределяют
+// параметры секции для этого сотрудника, nil - как у секции
+type EmployeeSection struct {
+	EmployeeID          uint   `gorm:"primaryKey" json:"employee_id"`
+	SectionID           uint   `gorm:"primaryKey" json:"section_id"`
+	DurationMinutes     *int   `json:"duration_minutes"`
+	Price               *int64 `json:"price"`
+	BufferBeforeMinutes *int   `json:"buffer_before_minutes"`
+	BufferAfterMinutes  *int   `json:"buffer_after_minutes"`
+}
+
+func (es *EmployeeSection) TableName() string {
+	return "employee_sections"
+}
+
+// Итоговые условия оказания услуги сотрудником
+type ServiceTerms struct {
+	Duration     time.Duration
+	BufferBefore time.Duration
+	BufferAfter  time.Duration
+	Price        int64
+	Currency     string
+	// 0 - без ограничения
+	MaxAdvance time.Duration
+	MinNotice  time.Duration
+	// Мест в занятии, 1 - индивидуальная запись
+	Capacity int
+	// Ресурсы, занимаемые записью на время [StartAt, EndAt) без буферов
+	Resources []uint
+}
+
+// Начало записи допустимо в интервале [notBefore, notAfter].
+// Нулевой notAfter - без ограничения
+func (t ServiceTerms) BookingWindow(now time.Time) (notBefore, notAfter time.Time) {
+	notBefore = now.Add(t.MinNotice)
+	if t.MaxAdvance > 0 {
+		notAfter = now.Add(t.MaxAdvance)
+	}
+	return notBefore, notAfter
+}
+
+// Фильтр списка секций. UserID = 0 - секции всех пользователей
+type SectionFilter struct {
+	UserID uint
+	Name   string
+}
+
+type PaginatedSections struct {
+	Sections   []SectionResponse `json:"sections"`
+	TotalCount int64             `json:"total_count"`
+	TotalPages int               `json:"total_pages"`
+	Page       int               `json:"page"`
+	Limit      int               `json:"limit"`
+	HasMore    bool              `json:"has_more"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestSectionCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		group    bool
+		// Мест в занятии по условиям услуги
+		want int
+	}{
+		{"не задано", 0, false, 1},
+		{"индивидуальная", 1, false, 1},
+		{"группа", 2, true, 2},
+		{"большая группа", 30, true, 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			section := &Section{Capacity: tt.capacity}
+			if got := section.IsGroup(); got != tt.group {
+				t.Fatalf("IsGroup = %v, ожидалось %v", got, tt.group)
+			}
+			if got := section.Terms(nil).Capacity; got != tt.want {
+				t.Fatalf("Terms.Capacity = %d, ожидалось %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectionTerms(t *testing.T) {
+	duration, bufferBefore, bufferAfter := 90, 5, 20
+	price := int64(250000)
+
+	section := &Section{
+		DurationMinutes:     60,
+		Price:               150000,
+		Currency:            "RUB",
+		BufferBeforeMinutes: 10,
+		BufferAfterMinutes:  15,
+		MaxAdvanceDays:      30,
+		MinNoticeMinutes:    120,
+		Capacity:            8,
+		Resources:           []Resource{{Model: gorm.Model{ID: 3}}, {Model: gorm.Model{ID: 5}}},
+	}
+
+	tests := []struct {
+		name     string
+		override *EmployeeSection
+		want     ServiceTerms
+	}{
+		{"параметры секции", nil, ServiceTerms{
+			Duration: time.Hour, BufferBefore: 10 * time.Minute, BufferAfter: 15 * time.Minute, Price: 150000,
+		}},
+		{"без переопределений", &EmployeeSection{}, ServiceTerms{
+			Duration: time.Hour, BufferBefore: 10 * time.Minute, BufferAfter: 15 * time.Minute, Price: 150000,
+		}},
+		{"переопределения сотрудника", &EmployeeSection{
+			DurationMinutes: &duration, Price: &price, BufferBeforeMinutes: &bufferBefore, BufferAfterMinutes: &bufferAfter,
+		}, ServiceTerms{
+			Duration: 90 * time.Minute, BufferBefore: 5 * time.Minute, BufferAfter: 20 * time.Minute, Price: 250000,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := section.Terms(tt.override)
+			if got.Duration != tt.want.Duration || got.BufferBefore != tt.want.BufferBefore ||
+				got.BufferAfter != tt.want.BufferAfter || got.Price != tt.want.Price {
+				t.Fatalf("получено %+v, ожидалось %+v", got, tt.want)
+			}
+
+			// Вместимость, окно записи и ресурсы сотрудник не переопределяет
+			if got.Capacity != 8 || got.Currency != "RUB" || got.MaxAdvance != 30*24*time.Hour || got.MinNotice != 2*time.Hour {
+				t.Fatalf("параметры секции изменены: %+v", got)
+			}
+			if len(got.Resources) != 2 || got.Resources[0] != 3 || got.Resources[1] != 5 {
+				t.Fatalf("ресурсы %v, ожидались [3 5]", got.Resources)
+			}
+		})
+	}
+}
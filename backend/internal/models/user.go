@@ -1,11 +1,21 @@
 package models
 
 import (
+	"record-services/pkg/types"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Статус заявки на регистрацию
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending  ApprovalStatus = "pending"
+	ApprovalStatusApproved ApprovalStatus = "approved"
+	ApprovalStatusRejected ApprovalStatus = "rejected"
+)
+
 type User struct {
 	gorm.Model
 	Name         string `gorm:"not null;size:100" json:"name"`
@@ -14,6 +24,30 @@ type User struct {
 	IsActive     bool   `gorm:"not null;default:false;index" json:"is_active"`
 	IsAdmin      bool   `gorm:"not null;default:false" json:"is_admin"`
 
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// Новый email до подтверждения по ссылке из письма
+	PendingEmail string `gorm:"size:255" json:"pending_email,omitempty"`
+	// Пользователи, созданные до появления одобрения, считаются одобренными
+	ApprovalStatus ApprovalStatus `gorm:"not null;size:20;default:approved;index" json:"approval_status"`
+	// Часовой пояс IANA, в котором владелец ведет расписание. Сотрудники
+	// без своего пояса работают в нем
+	TimeZone string `gorm:"not null;size:64;default:UTC" json:"time_zone"`
+	// Владелец, в организации которого работает пользователь (менеджер,
+	// администратор зала, сотрудник). Пусто - пользователь сам владелец
+	OwnerID *uint `gorm:"index" json:"owner_id"`
+
+	// Второй фактор (TOTP). Секрет сохраняется при настройке,
+	// TOTPEnabled включается после подтверждения первым кодом
+	TOTPSecret   string `gorm:"size:64" json:"-"`
+	TOTPEnabled  bool   `gorm:"not null;default:false" json:"totp_enabled"`
+	TOTPLastStep int64  `gorm:"not null;default:0" json:"-"`
+
+	// Блокировка входа после серии неверных паролей
+	FailedLoginAttempts int        `gorm:"not null;default:0" json:"failed_login_attempts"`
+	LockedUntil         *time.Time `json:"locked_until"`
+
+	Roles []Role `gorm:"many2many:user_roles;" json:"roles,omitempty"`
+
 	Sections  []Section  `gorm:"foreignKey:UserID" json:"sections,omitempty"`
 	Employees []Employee `gorm:"foreignKey:UserID" json:"employees,omitempty"`
 }
@@ -28,7 +62,84 @@ type UserResponse struct {
 	Email    string `json:"email" validate:"required,email"`
 	IsActive bool   `json:"is_active"`
 	IsAdmin  bool   `json:"is_admin"`
+	EmailVerified  bool           `json:"email_verified"`
+	PendingEmail   string         `json:"pending_email,omitempty"`
+	ApprovalStatus ApprovalStatus `json:"approval_status"`
+	TimeZone       string         `json:"time_zone"`
+	OwnerID        *uint          `json:"owner_id,omitempty"`
+	TOTPEnabled    bool           `json:"totp_enabled"`
+	Roles          []string       `json:"roles"`
 	CreatedAt time.Time `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (u *User) RoleNames() []string {
+	names := make([]string, len(u.Roles))
+	for i, role := range u.Roles {
+		names[i] = role.Name
+	}
+	return names
+}
+
+// Объединение прав всех ролей пользователя
+func (u *User) PermissionCodes() []string {
+	seen := make(map[string]bool)
+	codes := make([]string, 0)
+	for _, role := range u.Roles {
+		for _, permission := range role.Permissions {
+			if !seen[permission.Code] {
+				seen[permission.Code] = true
+				codes = append(codes, permission.Code)
+			}
+		}
+	}
+	return codes
+}
+
+// Владелец, с данными которого работает пользователь
+func (u *User) Owner() uint {
+	if u.OwnerID != nil {
+		return *u.OwnerID
+	}
+	return u.ID
+}
+
+func (u *User) IsLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+func (u *User) ToResponse() UserResponse {
+	response := UserResponse{
+		ID:             u.ID,
+		Name:           u.Name,
+		Email:          u.Email,
+		IsActive:       u.IsActive,
+		IsAdmin:        u.IsAdmin,
+		EmailVerified:  u.EmailVerifiedAt != nil,
+		PendingEmail:   u.PendingEmail,
+		ApprovalStatus: u.ApprovalStatus,
+		TimeZone:       u.TimeZone,
+		OwnerID:        u.OwnerID,
+		TOTPEnabled:    u.TOTPEnabled,
+		Roles:          u.RoleNames(),
+		CreatedAt:      u.CreatedAt,
+	}
+	if u.DeletedAt.Valid {
+		response.DeletedAt = &u.DeletedAt.Time
+	}
+	return response
+}
+
+// Фильтр списка пользователей, пустые поля не применяются.
+// Удаленные пользователи по умолчанию не выбираются
+type UserFilter struct {
+	Name           string
+	Email          string
+	ApprovalStatus ApprovalStatus
+	EmailVerified  types.CheckValue
+	IsActive       types.CheckValue
+	IsAdmin        types.CheckValue
+	Deleted        types.CheckValue
 }
 
 type PaginatedUsers struct {
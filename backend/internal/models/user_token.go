@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserTokenPurpose string
+
+const (
+	UserTokenEmailVerification UserTokenPurpose = "email_verification"
+	UserTokenPasswordReset     UserTokenPurpose = "password_reset"
+	UserTokenEmailChange       UserTokenPurpose = "email_change"
+)
+
+// Одноразовый токен из письма. В БД хранится только хеш
+type UserToken struct {
+	gorm.Model
+	UserID    uint             `gorm:"not null;index" json:"user_id"`
+	User      User             `gorm:"foreignKey:UserID" json:"-"`
+	Purpose   UserTokenPurpose `gorm:"not null;size:50;index" json:"purpose"`
+	TokenHash string           `gorm:"not null;uniqueIndex;size:64" json:"-"`
+	ExpiresAt time.Time        `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time       `json:"used_at"`
+}
+
+func (t *UserToken) TableName() string {
+	return "user_tokens"
+}
+
+func (t *UserToken) IsValid(purpose UserTokenPurpose, now time.Time) bool {
+	return t.Purpose == purpose && t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
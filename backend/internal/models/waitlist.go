@@ -0,0 +1,166 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting   WaitlistStatus = "waiting"
+	WaitlistStatusBooked    WaitlistStatus = "booked"
+	WaitlistStatusCancelled WaitlistStatus = "cancelled"
+	// Интервал ожидания прошел, подходящее время не освободилось
+	WaitlistStatusExpired WaitlistStatus = "expired"
+)
+
+type WaitlistOfferStatus string
+
+const (
+	WaitlistOfferPending  WaitlistOfferStatus = "pending"
+	WaitlistOfferClaimed  WaitlistOfferStatus = "claimed"
+	WaitlistOfferDeclined WaitlistOfferStatus = "declined"
+	// Клиент не ответил вовремя или время заняли раньше
+	WaitlistOfferExpired WaitlistOfferStatus = "expired"
+)
+
+// Заявка клиента в листе ожидания: запись на услугу с началом
+// в интервале [WindowStart, WindowEnd). Освободившееся время
+// предлагается заявкам в порядке их создания
+type WaitlistEntry struct {
+	gorm.Model
+	UserID uint `gorm:"not null;index" json:"user_id"`
+
+	SectionID uint    `gorm:"not null;index" json:"section_id"`
+	Section   Section `gorm:"foreignKey:SectionID" json:"-"`
+	// nil - подходит любой сотрудник услуги
+	EmployeeID *uint `gorm:"index" json:"employee_id"`
+
+	ClientID    *uint  `gorm:"index" json:"client_id"`
+	ClientName  string `gorm:"not null;size:255" json:"client_name"`
+	ClientPhone string `gorm:"size:20" json:"client_phone"`
+	// Предложения отправляются на email
+	ClientEmail string `gorm:"not null;size:255" json:"client_email"`
+	// Согласие на напоминания, переходит в запись по предложению
+	RemindersConsent bool `gorm:"not null;default:false" json:"reminders_consent"`
+
+	WindowStart time.Time      `gorm:"not null" json:"window_start"`
+	WindowEnd   time.Time      `gorm:"not null;index" json:"window_end"`
+	Status      WaitlistStatus `gorm:"not null;size:20;default:waiting;index" json:"status"`
+	Comment     string         `gorm:"type:text" json:"comment"`
+
+	// Запись, созданная по принятому предложению
+	AppointmentID *uint `json:"appointment_id"`
+}
+
+func (e *WaitlistEntry) TableName() string {
+	return "waitlist_entries"
+}
+
+// Предложение освободившегося времени заявке. Принимается по ссылке
+// с токеном до ExpiresAt, после чего время предлагается следующей заявке
+type WaitlistOffer struct {
+	gorm.Model
+	EntryID    uint          `gorm:"not null;index" json:"entry_id"`
+	Entry      WaitlistEntry `gorm:"foreignKey:EntryID" json:"-"`
+	EmployeeID uint          `gorm:"not null" json:"employee_id"`
+	Employee   Employee      `gorm:"foreignKey:EmployeeID" json:"-"`
+
+	StartAt   time.Time           `gorm:"not null" json:"start_at"`
+	EndAt     time.Time           `gorm:"not null" json:"end_at"`
+	ExpiresAt time.Time           `gorm:"not null;index" json:"expires_at"`
+	Status    WaitlistOfferStatus `gorm:"not null;size:20;default:pending;index" json:"status"`
+
+	// SHA-256 токена ссылки
+	TokenHash string `gorm:"not null;size:64;uniqueIndex" json:"-"`
+}
+
+func (o *WaitlistOffer) TableName() string {
+	return "waitlist_offers"
+}
+
+type WaitlistEntryResponse struct {
+	ID               uint                    `json:"id"`
+	UserID           uint                    `json:"user_id"`
+	SectionID        uint                    `json:"section_id"`
+	EmployeeID       *uint                   `json:"employee_id"`
+	ClientID         *uint                   `json:"client_id"`
+	ClientName       string                  `json:"client_name"`
+	ClientPhone      string                  `json:"client_phone"`
+	ClientEmail      string                  `json:"client_email"`
+	RemindersConsent bool                    `json:"reminders_consent"`
+	WindowStart      time.Time               `json:"window_start"`
+	WindowEnd        time.Time               `json:"window_end"`
+	Status           WaitlistStatus          `json:"status"`
+	Comment          string                  `json:"comment"`
+	AppointmentID    *uint                   `json:"appointment_id"`
+	Offers           []WaitlistOfferResponse `json:"offers,omitempty"`
+	CreatedAt        time.Time               `json:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at"`
+}
+
+func (e *WaitlistEntry) ToResponse() WaitlistEntryResponse {
+	return WaitlistEntryResponse{
+		ID:               e.ID,
+		UserID:           e.UserID,
+		SectionID:        e.SectionID,
+		EmployeeID:       e.EmployeeID,
+		ClientID:         e.ClientID,
+		ClientName:       e.ClientName,
+		ClientPhone:      e.ClientPhone,
+		ClientEmail:      e.ClientEmail,
+		RemindersConsent: e.RemindersConsent,
+		WindowStart:      e.WindowStart,
+		WindowEnd:        e.WindowEnd,
+		Status:           e.Status,
+		Comment:          e.Comment,
+		AppointmentID:    e.AppointmentID,
+		CreatedAt:        e.CreatedAt,
+		UpdatedAt:        e.UpdatedAt,
+	}
+}
+
+type WaitlistOfferResponse struct {
+	ID         uint                `json:"id"`
+	EntryID    uint                `json:"entry_id"`
+	EmployeeID uint                `json:"employee_id"`
+	StartAt    time.Time           `json:"start_at"`
+	EndAt      time.Time           `json:"end_at"`
+	ExpiresAt  time.Time           `json:"expires_at"`
+	Status     WaitlistOfferStatus `json:"status"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+func (o *WaitlistOffer) ToResponse() WaitlistOfferResponse {
+	return WaitlistOfferResponse{
+		ID:         o.ID,
+		EntryID:    o.EntryID,
+		EmployeeID: o.EmployeeID,
+		StartAt:    o.StartAt,
+		EndAt:      o.EndAt,
+		ExpiresAt:  o.ExpiresAt,
+		Status:     o.Status,
+		CreatedAt:  o.CreatedAt,
+		UpdatedAt:  o.UpdatedAt,
+	}
+}
+
+// Фильтр листа ожидания, нулевые значения не применяются
+type WaitlistFilter struct {
+	UserID     uint
+	SectionID  uint
+	EmployeeID uint
+	Status     WaitlistStatus
+}
+
+type PaginatedWaitlist struct {
+	Entries    []WaitlistEntryResponse `json:"entries"`
+	TotalCount int64                   `json:"total_count"`
+	TotalPages int                     `json:"total_pages"`
+	Page       int                     `json:"page"`
+	Limit      int                     `json:"limit"`
+	HasMore    bool                    `json:"has_more"`
+}
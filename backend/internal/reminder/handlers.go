@@ -0,0 +1,108 @@
+package reminder
+
+import (
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/repositories/reminder_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+// Правила напоминаний настраиваются владельцем вместе со страницей
+// онлайн-записи, поэтому доступ к ним определяют права на секции
+type ReminderHandlers struct {
+	mux        *http.ServeMux
+	logger     *zerolog.Logger
+	repository reminder_repository.ReminderRepository
+	validator  *validator.Validate
+}
+
+func NewReminderHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository reminder_repository.ReminderRepository, validator *validator.Validate) *ReminderHandlers {
+	reminderHandlers := &ReminderHandlers{
+		mux:        mux,
+		logger:     logger,
+		repository: repository,
+		validator:  validator,
+	}
+
+	read := middleware.RequirePermission(consts.PermSectionsRead)
+	write := middleware.RequirePermission(consts.PermSectionsWrite)
+
+	reminderHandlers.mux.Handle("GET /api/reminders", read(http.HandlerFunc(reminderHandlers.getRules)))
+	reminderHandlers.mux.Handle("PUT /api/reminders", write(http.HandlerFunc(reminderHandlers.saveRules)))
+
+	return reminderHandlers
+}
+
+// Правила напоминаний организации текущего пользователя
+func (h *ReminderHandlers) getRules(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	rules, err := h.repository.GetRules(r.Context(), claims.Owner())
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении напоминаний", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, toResponses(rules))
+}
+
+// Заменяет все правила, например: клиенту за 1440 и 120 минут,
+// сотруднику за 60. Новые правила применяются к записям, которые
+// создаются или переносятся после их сохранения
+func (h *ReminderHandlers) saveRules(w http.ResponseWriter, r *http.Request) {
+	var rulesData struct {
+		Reminders []struct {
+			Recipient models.ReminderRecipient `json:"recipient" validate:"required,oneof=client employee"`
+			// От 5 минут до 7 дней
+			MinutesBefore int `json:"minutesBefore" validate:"required,min=5,max=10080"`
+		} `json:"reminders" validate:"max=10,dive"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &rulesData) {
+		return
+	}
+
+	type ruleKey struct {
+		recipient     models.ReminderRecipient
+		minutesBefore int
+	}
+	seen := make(map[ruleKey]bool)
+	rules := make([]models.ReminderRule, 0, len(rulesData.Reminders))
+	for _, reminder := range rulesData.Reminders {
+		key := ruleKey{reminder.Recipient, reminder.MinutesBefore}
+		if seen[key] {
+			httputils.SendError(w, "Напоминания повторяются", http.StatusBadRequest)
+			return
+		}
+		seen[key] = true
+
+		rules = append(rules, models.ReminderRule{
+			Recipient:     reminder.Recipient,
+			MinutesBefore: reminder.MinutesBefore,
+		})
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	if err := h.repository.ReplaceRules(r.Context(), claims.Owner(), rules); err != nil {
+		httputils.SendError(w, "Ошибка при сохранении напоминаний", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Пользователь %d изменил напоминания: %d правил", claims.ID, len(rules))
+
+	httputils.SendJSONResponse(w, toResponses(rules))
+}
+
+func toResponses(rules []models.ReminderRule) []models.ReminderRuleResponse {
+	responses := make([]models.ReminderRuleResponse, len(rules))
+	for i := range rules {
+		responses[i] = rules[i].ToResponse()
+	}
+	return responses
+}
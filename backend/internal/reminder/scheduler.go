@@ -0,0 +1,221 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"record-services/internal/models"
+	"record-services/internal/repositories/appointment_repository"
+	"record-services/internal/repositories/client_repository"
+	"record-services/internal/repositories/job_repository"
+	"record-services/internal/repositories/reminder_repository"
+	"record-services/pkg/mailer"
+	"record-services/pkg/timezone"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Вид фоновой задачи напоминания
+const JobKind = "appointment_reminder"
+
+const (
+	// Проверка записей, напоминания о которых не удалось запланировать
+	resyncInterval = time.Minute
+	// Записей за одну проверку
+	resyncBatchSize = 100
+)
+
+// Данные задачи. Время начала сверяется при отправке: напоминание
+// о перенесенной записи не отправляется, даже если не было отменено
+type reminderPayload struct {
+	AppointmentID uint                     `json:"appointment_id"`
+	Recipient     models.ReminderRecipient `json:"recipient"`
+	StartAt       time.Time                `json:"start_at"`
+}
+
+// Напоминания о записях по правилам владельца. Напоминания - фоновые
+// задачи в БД: планируются при создании и переносе записи, отменяются
+// при ее отмене и отправляются Runner из пакета jobs. Запись сохраняется
+// раньше напоминаний, поэтому записи, напоминания о которых не удалось
+// запланировать, периодически планируются заново
+type Scheduler struct {
+	logger       *zerolog.Logger
+	rules        reminder_repository.ReminderRepository
+	jobs         job_repository.JobRepository
+	appointments appointment_repository.AppointmentRepository
+	clients      client_repository.ClientRepository
+	mailer       mailer.Mailer
+}
+
+func NewScheduler(logger *zerolog.Logger, rules reminder_repository.ReminderRepository, jobs job_repository.JobRepository, appointments appointment_repository.AppointmentRepository, clients client_repository.ClientRepository, mailer mailer.Mailer) *Scheduler {
+	return &Scheduler{
+		logger:       logger,
+		rules:        rules,
+		jobs:         jobs,
+		appointments: appointments,
+		clients:      clients,
+		mailer:       mailer,
+	}
+}
+
+// Запускает периодическое планирование пропущенных напоминаний
+func (s *Scheduler) Start() {
+	go s.run()
+}
+
+func (s *Scheduler) run() {
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.resync()
+	}
+}
+
+// Планирует напоминания о будущих записях, для которых это не удалось
+// при сохранении: ошибка БД или остановка сервера
+func (s *Scheduler) resync() {
+	ctx := context.Background()
+
+	appointments, err := s.appointments.GetUnscheduledReminders(ctx, time.Now(), resyncBatchSize)
+	if err != nil {
+		return
+	}
+
+	for i := range appointments {
+		s.Schedule(ctx, &appointments[i])
+	}
+}
+
+// Планирует напоминания о сохраненной записи заново: прежние отменяются,
+// для активной записи создаются по правилам владельца. Напоминания,
+// время которых уже прошло, не создаются. Ошибки только логируются:
+// запись уже сохранена, а напоминания запланирует периодическая проверка
+func (s *Scheduler) Schedule(ctx context.Context, appointment *models.Appointment) {
+	if !appointment.Status.IsActive() {
+		s.Cancel(ctx, appointment.ID)
+		return
+	}
+
+	rules, err := s.rules.GetRules(ctx, appointment.UserID)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	jobs := make([]models.Job, 0, len(rules))
+	for i := range rules {
+		runAt := appointment.StartAt.Add(-rules[i].Before())
+		if !runAt.After(now) {
+			continue
+		}
+
+		payload, err := json.Marshal(reminderPayload{
+			AppointmentID: appointment.ID,
+			Recipient:     rules[i].Recipient,
+			StartAt:       appointment.StartAt,
+		})
+		if err != nil {
+			s.logger.Error().Err(err).Msgf("Ошибка при подготовке напоминания о записи: %d", appointment.ID)
+			return
+		}
+
+		jobs = append(jobs, models.Job{Kind: JobKind, Payload: string(payload), RunAt: runAt})
+	}
+
+	if err := s.jobs.Replace(ctx, jobKey(appointment.ID), jobs); err != nil {
+		return
+	}
+	_ = s.appointments.SetRemindersScheduled(ctx, appointment.ID, time.Now())
+}
+
+// Планирует напоминания о нескольких записях, например вхождениях серии
+func (s *Scheduler) ScheduleAll(ctx context.Context, appointments []models.Appointment) {
+	for i := range appointments {
+		s.Schedule(ctx, &appointments[i])
+	}
+}
+
+// Отменяет еще не отправленные напоминания о записи
+func (s *Scheduler) Cancel(ctx context.Context, appointmentID uint) {
+	_ = s.jobs.Cancel(ctx, jobKey(appointmentID))
+}
+
+// Обработчик задачи напоминания. Отмененная, перенесенная, удаленная
+// или уже начавшаяся запись, а также получатель без email или клиент,
+// не давший согласия ни при записи, ни в карточке, - задача завершается
+// без отправки
+func (s *Scheduler) Send(ctx context.Context, job *models.Job) error {
+	var payload reminderPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		s.logger.Error().Err(err).Msgf("Некорректные данные напоминания: %d", job.ID)
+		return nil
+	}
+
+	appointment, err := s.appointments.GetById(ctx, payload.AppointmentID)
+	if err != nil {
+		return err
+	}
+
+	if appointment == nil || !appointment.Status.IsActive() ||
+		!appointment.StartAt.Equal(payload.StartAt) || !appointment.StartAt.After(time.Now()) {
+		return nil
+	}
+
+	var to, name string
+	switch payload.Recipient {
+	case models.ReminderRecipientClient:
+		// Согласие дается при онлайн-записи или хранится в карточке клиента
+		to, name = appointment.ClientEmail, appointment.ClientName
+		consent := appointment.RemindersConsent
+		if appointment.ClientID != nil {
+			client, err := s.clients.GetById(ctx, *appointment.ClientID)
+			if err != nil {
+				return err
+			}
+			if client != nil {
+				consent = consent || client.RemindersConsent
+				if to == "" {
+					to = client.Email
+				}
+			}
+		}
+		if !consent {
+			return nil
+		}
+	case models.ReminderRecipientEmployee:
+		to, name = appointment.Employee.Email, appointment.Employee.Name
+	}
+
+	if to == "" {
+		return nil
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      to,
+		Subject: "Напоминание о записи: " + appointment.Section.Name,
+		Body:    reminderBody(appointment, payload.Recipient, name),
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info().Msgf("Отправлено напоминание о записи %d (%s)", appointment.ID, payload.Recipient)
+	return nil
+}
+
+func reminderBody(appointment *models.Appointment, recipient models.ReminderRecipient, name string) string {
+	start := timezone.Format(appointment.StartAt, appointment.Location())
+
+	if recipient == models.ReminderRecipientEmployee {
+		return fmt.Sprintf("Здравствуйте, %s!\n\nНапоминаем о записи клиента %s на «%s» %s.",
+			name, appointment.ClientName, appointment.Section.Name, start)
+	}
+	return fmt.Sprintf("Здравствуйте, %s!\n\nНапоминаем, что вы записаны на «%s» %s, сотрудник %s.",
+		name, appointment.Section.Name, start, appointment.Employee.Name)
+}
+
+func jobKey(appointmentID uint) string {
+	return fmt.Sprintf("appointment:%d", appointmentID)
+}
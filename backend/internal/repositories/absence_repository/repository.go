@@ -0,0 +1,83 @@
+package absence_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type AbsenceRepository interface {
+	GetById(ctx context.Context, id uint) (*models.Absence, error)
+	// Отсутствия сотрудника, пересекающиеся с интервалом [from, to)
+	GetByEmployee(ctx context.Context, employeeID uint, from, to time.Time) ([]models.Absence, error)
+	Create(ctx context.Context, absence *models.Absence) (*models.Absence, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type absenceRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewAbsenceRepository(db *gorm.DB, logger *zerolog.Logger) AbsenceRepository {
+	return &absenceRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *absenceRepository) GetById(ctx context.Context, id uint) (*models.Absence, error) {
+	absence := &models.Absence{}
+	result := r.db.WithContext(ctx).First(absence, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении отсутствия по id: %d", id)
+		return nil, result.Error
+	}
+	return absence, nil
+}
+
+func (r *absenceRepository) GetByEmployee(ctx context.Context, employeeID uint, from, to time.Time) ([]models.Absence, error) {
+	var absences []models.Absence
+	result := r.db.WithContext(ctx).
+		Where("employee_id = ? AND start_at < ? AND end_at > ?", employeeID, to, from).
+		Order("start_at").
+		Find(&absences)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении отсутствий сотрудника: %d", employeeID)
+		return nil, result.Error
+	}
+	return absences, nil
+}
+
+func (r *absenceRepository) Create(ctx context.Context, absence *models.Absence) (*models.Absence, error) {
+	result := r.db.WithContext(ctx).Create(absence)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании отсутствия сотрудника: %d", absence.EmployeeID)
+		return nil, result.Error
+	}
+	return absence, nil
+}
+
+func (r *absenceRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Absence{}, id)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при удалении отсутствия по id: %d", id)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+
+	return nil
+}
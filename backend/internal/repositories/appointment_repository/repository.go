@@ -0,0 +1,274 @@
+package appointment_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/database"
+	"record-services/pkg/types"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Create и Update возвращают consts.ErrConflict, если активная запись
+// пересекается по времени с другой активной записью того же сотрудника
+// или занимающей тот же ресурс
+type AppointmentRepository interface {
+	GetById(ctx context.Context, id uint) (*models.Appointment, error)
+	Create(ctx context.Context, appointment *models.Appointment) (*models.Appointment, error)
+	Update(ctx context.Context, appointment *models.Appointment) (*models.Appointment, error)
+	GetAllWithPagination(ctx context.Context, limit, page int, filter models.AppointmentFilter) (*models.PaginatedAppointments, error)
+	// Активные записи сотрудника, пересекающиеся с интервалом [from, to)
+	GetActiveByEmployee(ctx context.Context, employeeID uint, from, to time.Time) ([]models.Appointment, error)
+	// Активные записи, занимающие хотя бы один из ресурсов, пересекающиеся с интервалом [from, to)
+	GetActiveByResources(ctx context.Context, resourceIDs []uint, from, to time.Time) ([]models.Appointment, error)
+	GetClientStats(ctx context.Context, clientID uint) (*models.ClientVisitStats, error)
+	GetByManageTokenHash(ctx context.Context, tokenHash string) (*models.Appointment, error)
+	// До limit активных записей, начинающихся после now, напоминания
+	// о которых не планировались после их последнего изменения
+	GetUnscheduledReminders(ctx context.Context, now time.Time, limit int) ([]models.Appointment, error)
+	// Отмечает, что напоминания о записи запланированы в момент at.
+	// Время изменения записи не меняется
+	SetRemindersScheduled(ctx context.Context, id uint, at time.Time) error
+}
+
+type appointmentRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewAppointmentRepository(db *gorm.DB, logger *zerolog.Logger) AppointmentRepository {
+	return &appointmentRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *appointmentRepository) GetById(ctx context.Context, id uint) (*models.Appointment, error) {
+	appointment := &models.Appointment{}
+	result := r.db.WithContext(ctx).
+		Preload("Employee").
+		Preload("Section").
+		First(appointment, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении записи по id: %d", id)
+		return nil, result.Error
+	}
+	return appointment, nil
+}
+
+func (r *appointmentRepository) Create(ctx context.Context, appointment *models.Appointment) (*models.Appointment, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Create(appointment)
+	if result.Error != nil {
+		if database.IsExclusionViolation(result.Error, models.AppointmentExclusionConstraints...) {
+			return nil, consts.ErrConflict
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании записи к сотруднику: %d", appointment.EmployeeID)
+		return nil, result.Error
+	}
+	return appointment, nil
+}
+
+func (r *appointmentRepository) Update(ctx context.Context, appointment *models.Appointment) (*models.Appointment, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Save(appointment)
+	if result.Error != nil {
+		if database.IsExclusionViolation(result.Error, models.AppointmentExclusionConstraints...) {
+			return nil, consts.ErrConflict
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при обновлении записи: %d", appointment.ID)
+		return nil, result.Error
+	}
+	return appointment, nil
+}
+
+func (r *appointmentRepository) GetAllWithPagination(ctx context.Context, limit, page int, filter models.AppointmentFilter) (*models.PaginatedAppointments, error) {
+	var appointments []models.Appointment
+	var totalCount int64
+
+	db := r.db.WithContext(ctx)
+
+	if err := applyAppointmentFilter(db.Model(&models.Appointment{}), filter).Count(&totalCount).Error; err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при подсчете записей")
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 30
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	result := applyAppointmentFilter(db.Model(&models.Appointment{}), filter).
+		Preload("Employee").
+		Preload("Section").
+		Order("start_at").
+		Limit(limit).
+		Offset(offset).
+		Find(&appointments)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении записей")
+		return nil, result.Error
+	}
+
+	totalPages := int(totalCount) / limit
+	if int(totalCount)%limit > 0 {
+		totalPages++
+	}
+
+	appointmentsResponse := make([]models.AppointmentResponse, len(appointments))
+	for i := range appointments {
+		appointmentsResponse[i] = appointments[i].ToResponse()
+	}
+
+	return &models.PaginatedAppointments{
+		Appointments: appointmentsResponse,
+		TotalCount:   totalCount,
+		TotalPages:   totalPages,
+		Page:         page,
+		Limit:        limit,
+		HasMore:      page < totalPages,
+	}, nil
+}
+
+func (r *appointmentRepository) GetActiveByEmployee(ctx context.Context, employeeID uint, from, to time.Time) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+	result := r.db.WithContext(ctx).
+		Where("employee_id = ? AND status IN ? AND start_at < ? AND end_at > ?",
+			employeeID, []models.AppointmentStatus{models.AppointmentStatusPending, models.AppointmentStatusConfirmed}, to, from).
+		Order("start_at").
+		Find(&appointments)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении записей сотрудника: %d", employeeID)
+		return nil, result.Error
+	}
+	return appointments, nil
+}
+
+func (r *appointmentRepository) GetUnscheduledReminders(ctx context.Context, now time.Time, limit int) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+	result := r.db.WithContext(ctx).
+		Where("status IN ? AND start_at > ? AND (reminders_scheduled_at IS NULL OR reminders_scheduled_at < updated_at)",
+			[]models.AppointmentStatus{models.AppointmentStatusPending, models.AppointmentStatusConfirmed}, now).
+		Order("start_at").
+		Limit(limit).
+		Find(&appointments)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении записей без напоминаний")
+		return nil, result.Error
+	}
+	return appointments, nil
+}
+
+func (r *appointmentRepository) SetRemindersScheduled(ctx context.Context, id uint, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(&models.Appointment{}).
+		Where("id = ?", id).
+		UpdateColumn("reminders_scheduled_at", at)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при отметке напоминаний записи: %d", id)
+		return result.Error
+	}
+	return nil
+}
+
+func (r *appointmentRepository) GetActiveByResources(ctx context.Context, resourceIDs []uint, from, to time.Time) ([]models.Appointment, error) {
+	var appointments []models.Appointment
+	result := r.db.WithContext(ctx).
+		Where("resource_ids && ?::integer[] AND status IN ? AND start_at < ? AND end_at > ?",
+			types.IDList(resourceIDs), []models.AppointmentStatus{models.AppointmentStatusPending, models.AppointmentStatusConfirmed}, to, from).
+		Order("start_at").
+		Find(&appointments)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении записей ресурсов")
+		return nil, result.Error
+	}
+	return appointments, nil
+}
+
+func (r *appointmentRepository) GetByManageTokenHash(ctx context.Context, tokenHash string) (*models.Appointment, error) {
+	appointment := &models.Appointment{}
+	result := r.db.WithContext(ctx).
+		Preload("Employee").
+		Preload("Section.Resources").
+		Where("manage_token_hash = ?", tokenHash).
+		First(appointment)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении записи по токену управления")
+		return nil, result.Error
+	}
+	return appointment, nil
+}
+
+func (r *appointmentRepository) GetClientStats(ctx context.Context, clientID uint) (*models.ClientVisitStats, error) {
+	stats := &models.ClientVisitStats{}
+	result := r.db.WithContext(ctx).Model(&models.Appointment{}).
+		Select(`COUNT(*) AS total,
+			COUNT(*) FILTER (WHERE status = ?) AS completed,
+			COUNT(*) FILTER (WHERE status = ?) AS cancelled,
+			COUNT(*) FILTER (WHERE status = ?) AS no_show,
+			MAX(start_at) FILTER (WHERE status = ?) AS last_visit_at,
+			MIN(start_at) FILTER (WHERE status IN ? AND start_at > NOW()) AS next_visit_at`,
+			models.AppointmentStatusCompleted, models.AppointmentStatusCancelled, models.AppointmentStatusNoShow,
+			models.AppointmentStatusCompleted,
+			[]models.AppointmentStatus{models.AppointmentStatusPending, models.AppointmentStatusConfirmed}).
+		Where("client_id = ?", clientID).
+		Scan(stats)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении статистики визитов клиента: %d", clientID)
+		return nil, result.Error
+	}
+	return stats, nil
+}
+
+func applyAppointmentFilter(query *gorm.DB, filter models.AppointmentFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.EmployeeID != 0 {
+		query = query.Where("employee_id = ?", filter.EmployeeID)
+	}
+	if filter.SectionID != 0 {
+		query = query.Where("section_id = ?", filter.SectionID)
+	}
+	if filter.ClientID != 0 {
+		query = query.Where("client_id = ?", filter.ClientID)
+	}
+	if filter.SeriesID != 0 {
+		query = query.Where("series_id = ?", filter.SeriesID)
+	}
+	if filter.SessionID != 0 {
+		query = query.Where("session_id = ?", filter.SessionID)
+	}
+	if filter.ResourceID != 0 {
+		query = query.Where("? = ANY(resource_ids)", filter.ResourceID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		query = query.Where("end_at > ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("start_at < ?", *filter.To)
+	}
+	return query
+}
@@ -0,0 +1,93 @@
+package audit_repository
+
+import (
+	"record-services/internal/models"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	Create(entry *models.AuditLog) error
+	GetAllWithPagination(limit, page int, filter models.AuditFilter) (*models.PaginatedAuditLogs, error)
+}
+
+type auditRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewAuditRepository(db *gorm.DB, logger *zerolog.Logger) AuditRepository {
+	return &auditRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *auditRepository) Create(entry *models.AuditLog) error {
+	result := r.db.Create(entry)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при записи в журнал действия %s над %s %d", entry.Action, entry.TargetType, entry.TargetID)
+		return result.Error
+	}
+	return nil
+}
+
+func (r *auditRepository) GetAllWithPagination(limit, page int, filter models.AuditFilter) (*models.PaginatedAuditLogs, error) {
+	var entries []models.AuditLog
+	var totalCount int64
+
+	if err := applyAuditFilter(r.db.Model(&models.AuditLog{}), filter).Count(&totalCount).Error; err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при подсчете записей журнала")
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 30
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	result := applyAuditFilter(r.db.Model(&models.AuditLog{}), filter).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении записей журнала")
+		return nil, result.Error
+	}
+
+	totalPages := int(totalCount) / limit
+	if int(totalCount)%limit > 0 {
+		totalPages++
+	}
+
+	return &models.PaginatedAuditLogs{
+		Entries:    entries,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+		Page:       page,
+		Limit:      limit,
+		HasMore:    page < totalPages,
+	}, nil
+}
+
+func applyAuditFilter(query *gorm.DB, filter models.AuditFilter) *gorm.DB {
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	return query
+}
@@ -0,0 +1,70 @@
+package booking_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type BookingRepository interface {
+	GetByUser(ctx context.Context, userID uint) (*models.BookingPage, error)
+	GetBySlug(ctx context.Context, slug string) (*models.BookingPage, error)
+	// Создает или обновляет страницу. Занятый адрес - consts.ErrAlreadyExists
+	Save(ctx context.Context, page *models.BookingPage) (*models.BookingPage, error)
+}
+
+type bookingRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewBookingRepository(db *gorm.DB, logger *zerolog.Logger) BookingRepository {
+	return &bookingRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *bookingRepository) GetByUser(ctx context.Context, userID uint) (*models.BookingPage, error) {
+	page := &models.BookingPage{}
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).First(page)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении страницы записи пользователя: %d", userID)
+		return nil, result.Error
+	}
+	return page, nil
+}
+
+func (r *bookingRepository) GetBySlug(ctx context.Context, slug string) (*models.BookingPage, error) {
+	page := &models.BookingPage{}
+	result := r.db.WithContext(ctx).Where("slug = ?", slug).First(page)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении страницы записи: %s", slug)
+		return nil, result.Error
+	}
+	return page, nil
+}
+
+func (r *bookingRepository) Save(ctx context.Context, page *models.BookingPage) (*models.BookingPage, error) {
+	result := r.db.WithContext(ctx).Save(page)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return nil, consts.ErrAlreadyExists
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при сохранении страницы записи пользователя: %d", page.UserID)
+		return nil, result.Error
+	}
+	return page, nil
+}
@@ -0,0 +1,326 @@
+package client_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/types"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ClientRepository interface {
+	GetById(ctx context.Context, id uint) (*models.Client, error)
+	// Клиент владельца с таким телефоном, а если его нет - с таким email.
+	// Контакты должны быть нормализованы
+	FindByContacts(ctx context.Context, userID uint, phone, email string) (*models.Client, error)
+	Create(ctx context.Context, client *models.Client) (*models.Client, error)
+	// Существующий клиент владельца с контактами client или новый клиент
+	FindOrCreate(ctx context.Context, client *models.Client) (*models.Client, error)
+	// Создает карточку, только если ее контакты не совпадают ни с одной
+	// карточкой владельца. При совпадении возвращает nil
+	CreateIfUnknown(ctx context.Context, client *models.Client) (*models.Client, error)
+	// Отмечает согласие клиента на напоминания
+	GrantRemindersConsent(ctx context.Context, id uint) error
+	Update(ctx context.Context, client *models.Client) (*models.Client, error)
+	Delete(ctx context.Context, id uint) error
+	GetAllWithPagination(ctx context.Context, limit, page int, filter models.ClientFilter) (*models.PaginatedClients, error)
+	Merge(ctx context.Context, targetID uint, sourceIDs []uint) (*models.Client, error)
+}
+
+type clientRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewClientRepository(db *gorm.DB, logger *zerolog.Logger) ClientRepository {
+	return &clientRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *clientRepository) GetById(ctx context.Context, id uint) (*models.Client, error) {
+	client := &models.Client{}
+	result := r.db.WithContext(ctx).First(client, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении клиента по id: %d", id)
+		return nil, result.Error
+	}
+	return client, nil
+}
+
+func (r *clientRepository) FindByContacts(ctx context.Context, userID uint, phone, email string) (*models.Client, error) {
+	if phone == "" && email == "" {
+		return nil, nil
+	}
+
+	client := &models.Client{}
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	switch {
+	case phone != "" && email != "":
+		query = query.Where("(phone = ? OR email = ?)", phone, email).
+			Order(clause.Expr{SQL: "phone = ? DESC", Vars: []any{phone}})
+	case phone != "":
+		query = query.Where("phone = ?", phone)
+	default:
+		query = query.Where("email = ?", email)
+	}
+
+	result := query.First(client)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при поиске клиента пользователя: %d", userID)
+		return nil, result.Error
+	}
+	return client, nil
+}
+
+// Клиент с уже занятым телефоном или email - consts.ErrAlreadyExists
+func (r *clientRepository) Create(ctx context.Context, client *models.Client) (*models.Client, error) {
+	result := r.db.WithContext(ctx).Create(client)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return nil, consts.ErrAlreadyExists
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании клиента: %s", client.Name)
+		return nil, result.Error
+	}
+	return client, nil
+}
+
+func (r *clientRepository) FindOrCreate(ctx context.Context, client *models.Client) (*models.Client, error) {
+	existing, err := r.FindByContacts(ctx, client.UserID, client.Phone, client.Email)
+	if err != nil || existing != nil {
+		return existing, err
+	}
+
+	created, err := r.Create(ctx, client)
+	// Карточку с этими контактами успел создать параллельный запрос
+	if errors.Is(err, consts.ErrAlreadyExists) {
+		return r.FindByContacts(ctx, client.UserID, client.Phone, client.Email)
+	}
+	return created, err
+}
+
+// Для контактов из публичных форм: они не подтверждены, поэтому запись
+// по чужому телефону или email не должна попасть в историю чужой карточки
+func (r *clientRepository) CreateIfUnknown(ctx context.Context, client *models.Client) (*models.Client, error) {
+	existing, err := r.FindByContacts(ctx, client.UserID, client.Phone, client.Email)
+	if err != nil || existing != nil {
+		return nil, err
+	}
+
+	created, err := r.Create(ctx, client)
+	// Карточку с этими контактами успел создать параллельный запрос
+	if errors.Is(err, consts.ErrAlreadyExists) {
+		return nil, nil
+	}
+	return created, err
+}
+
+func (r *clientRepository) GrantRemindersConsent(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Model(&models.Client{}).Where("id = ?", id).Update("reminders_consent", true)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при сохранении согласия клиента на напоминания: %d", id)
+		return result.Error
+	}
+	return nil
+}
+
+func (r *clientRepository) Update(ctx context.Context, client *models.Client) (*models.Client, error) {
+	result := r.db.WithContext(ctx).Save(client)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return nil, consts.ErrAlreadyExists
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при обновлении клиента: %d", client.ID)
+		return nil, result.Error
+	}
+	return client, nil
+}
+
+func (r *clientRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Client{}, id)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при удалении клиента по id: %d", id)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *clientRepository) GetAllWithPagination(ctx context.Context, limit, page int, filter models.ClientFilter) (*models.PaginatedClients, error) {
+	var clients []models.Client
+	var totalCount int64
+
+	db := r.db.WithContext(ctx)
+
+	if err := applyClientFilter(db.Model(&models.Client{}), filter).Count(&totalCount).Error; err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при подсчете клиентов")
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 30
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	result := applyClientFilter(db.Model(&models.Client{}), filter).
+		Order("name").
+		Limit(limit).
+		Offset(offset).
+		Find(&clients)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении клиентов")
+		return nil, result.Error
+	}
+
+	totalPages := int(totalCount) / limit
+	if int(totalCount)%limit > 0 {
+		totalPages++
+	}
+
+	clientsResponse := make([]models.ClientResponse, len(clients))
+	for i := range clients {
+		clientsResponse[i] = clients[i].ToResponse()
+	}
+
+	return &models.PaginatedClients{
+		Clients:    clientsResponse,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+		Page:       page,
+		Limit:      limit,
+		HasMore:    page < totalPages,
+	}, nil
+}
+
+// Объединяет дубликаты в клиента targetID: записи дубликатов переходят к нему,
+// теги объединяются, пустые контакты и заметки заполняются из дубликатов,
+// согласия сохраняются, если были даны хотя бы в одной карточке.
+// Дубликаты удаляются. Все клиенты должны принадлежать одному владельцу,
+// иначе возвращается consts.ErrBadData
+func (r *clientRepository) Merge(ctx context.Context, targetID uint, sourceIDs []uint) (*models.Client, error) {
+	target := &models.Client{}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(target, targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return consts.ErrNotFound
+			}
+			return err
+		}
+
+		var sources []models.Client
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND id <> ? AND user_id = ?", sourceIDs, target.ID, target.UserID).
+			Order("id").
+			Find(&sources).Error; err != nil {
+			return err
+		}
+		if len(sources) == 0 || len(sources) != countUnique(sourceIDs, target.ID) {
+			return consts.ErrBadData
+		}
+
+		ids := make([]uint, len(sources))
+		for i, source := range sources {
+			ids[i] = source.ID
+			mergeInto(target, &source)
+		}
+
+		if err := tx.Model(&models.Appointment{}).Where("client_id IN ?", ids).Update("client_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		// Сначала удаляем дубликаты, чтобы их телефон и email освободились
+		if err := tx.Delete(&models.Client{}, ids).Error; err != nil {
+			return err
+		}
+
+		return tx.Save(target).Error
+	})
+	if err != nil {
+		if !errors.Is(err, consts.ErrNotFound) && !errors.Is(err, consts.ErrBadData) {
+			r.logger.Error().Err(err).Msgf("ошибка при объединении клиентов в клиента: %d", targetID)
+		}
+		return nil, err
+	}
+	return target, nil
+}
+
+func mergeInto(target, source *models.Client) {
+	if target.Phone == "" {
+		target.Phone = source.Phone
+	}
+	if target.Email == "" {
+		target.Email = source.Email
+	}
+	if source.Notes != "" {
+		if target.Notes != "" {
+			target.Notes += "\n\n"
+		}
+		target.Notes += source.Notes
+	}
+	target.AddTags(source.Tags)
+	target.RemindersConsent = target.RemindersConsent || source.RemindersConsent
+	target.MarketingConsent = target.MarketingConsent || source.MarketingConsent
+}
+
+func applyClientFilter(query *gorm.DB, filter models.ClientFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Search != "" {
+		pattern := "%" + filter.Search + "%"
+		// Телефон хранится без разделителей, поэтому ищем по цифрам запроса
+		if digits := onlyDigits(filter.Search); len(digits) >= 3 {
+			query = query.Where("(name ILIKE ? OR email ILIKE ? OR phone LIKE ?)", pattern, pattern, "%"+digits+"%")
+		} else {
+			query = query.Where("(name ILIKE ? OR email ILIKE ?)", pattern, pattern)
+		}
+	}
+	if filter.Tag != "" {
+		query = query.Where("tags @> CAST(? AS jsonb)", types.StringList{filter.Tag})
+	}
+	return query
+}
+
+func onlyDigits(value string) string {
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
+}
+
+// Количество различных идентификаторов, не считая exclude
+func countUnique(ids []uint, exclude uint) int {
+	unique := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if id != exclude {
+			unique[id] = true
+		}
+	}
+	return len(unique)
+}
@@ -0,0 +1,263 @@
+package employee_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/types"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmployeeRepository interface {
+	GetById(ctx context.Context, id uint) (*models.Employee, error)
+	Create(ctx context.Context, employee *models.Employee) (*models.Employee, error)
+	Update(ctx context.Context, employee *models.Employee) (*models.Employee, error)
+	Delete(ctx context.Context, id uint) error
+	GetAllWithPagination(ctx context.Context, limit, page int, filter models.EmployeeFilter) (*models.PaginatedEmployees, error)
+	ReplaceSections(ctx context.Context, employeeID uint, sectionIDs []uint) error
+	// Переопределения параметров секции для сотрудника. nil, если сотрудник не назначен в секцию
+	GetSectionSettings(ctx context.Context, employeeID, sectionID uint) (*models.EmployeeSection, error)
+	UpdateSectionSettings(ctx context.Context, settings *models.EmployeeSection) error
+	// Назначения всех сотрудников секции с их переопределениями
+	GetSectionAssignments(ctx context.Context, sectionID uint) ([]models.EmployeeSection, error)
+	// Часовой пояс графика сотрудника: свой или владельца. "" - сотрудник не найден
+	GetTimeZone(ctx context.Context, employeeID uint) (string, error)
+}
+
+type employeeRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewEmployeeRepository(db *gorm.DB, logger *zerolog.Logger) EmployeeRepository {
+	return &employeeRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *employeeRepository) GetById(ctx context.Context, id uint) (*models.Employee, error) {
+	employee := &models.Employee{}
+	result := r.db.WithContext(ctx).Preload("Sections").First(employee, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении сотрудника по id: %d", id)
+		return nil, result.Error
+	}
+	return employee, nil
+}
+
+func (r *employeeRepository) Create(ctx context.Context, employee *models.Employee) (*models.Employee, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Create(employee)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании сотрудника: %s", employee.Name)
+		return nil, result.Error
+	}
+	return employee, nil
+}
+
+// Секции сотрудника меняются только через ReplaceSections
+func (r *employeeRepository) Update(ctx context.Context, employee *models.Employee) (*models.Employee, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Save(employee)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при обновлении сотрудника: %d", employee.ID)
+		return nil, result.Error
+	}
+	return employee, nil
+}
+
+func (r *employeeRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Employee{}, id)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при удалении сотрудника по id: %d", id)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *employeeRepository) GetAllWithPagination(ctx context.Context, limit, page int, filter models.EmployeeFilter) (*models.PaginatedEmployees, error) {
+	var employees []models.Employee
+	var totalCount int64
+
+	db := r.db.WithContext(ctx)
+
+	if err := applyEmployeeFilter(db.Model(&models.Employee{}), filter).Count(&totalCount).Error; err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при подсчете сотрудников")
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 30
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	result := applyEmployeeFilter(db.Model(&models.Employee{}), filter).
+		Preload("Sections").
+		Order("name").
+		Limit(limit).
+		Offset(offset).
+		Find(&employees)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении сотрудников")
+		return nil, result.Error
+	}
+
+	totalPages := int(totalCount) / limit
+	if int(totalCount)%limit > 0 {
+		totalPages++
+	}
+
+	employeesResponse := make([]models.EmployeeResponse, len(employees))
+	for i := range employees {
+		employeesResponse[i] = employees[i].ToResponse()
+	}
+
+	return &models.PaginatedEmployees{
+		Employees:  employeesResponse,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+		Page:       page,
+		Limit:      limit,
+		HasMore:    page < totalPages,
+	}, nil
+}
+
+// Заменяет секции сотрудника целиком. Все секции должны принадлежать
+// владельцу сотрудника, иначе возвращается consts.ErrBadData
+func (r *employeeRepository) ReplaceSections(ctx context.Context, employeeID uint, sectionIDs []uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Блокировка строки сотрудника упорядочивает параллельные замены
+		employee := &models.Employee{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(employee, employeeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return consts.ErrNotFound
+			}
+			return err
+		}
+
+		sections := make([]models.Section, 0, len(sectionIDs))
+		if len(sectionIDs) > 0 {
+			if err := tx.Where("id IN ? AND user_id = ?", sectionIDs, employee.UserID).Find(&sections).Error; err != nil {
+				return err
+			}
+			if len(sections) != countUnique(sectionIDs) {
+				return consts.ErrBadData
+			}
+		}
+
+		return tx.Model(employee).Omit("Sections.*").Association("Sections").Replace(sections)
+	})
+	if err != nil {
+		if !errors.Is(err, consts.ErrNotFound) && !errors.Is(err, consts.ErrBadData) {
+			r.logger.Error().Err(err).Msgf("ошибка при замене секций сотрудника: %d", employeeID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *employeeRepository) GetSectionSettings(ctx context.Context, employeeID, sectionID uint) (*models.EmployeeSection, error) {
+	settings := &models.EmployeeSection{}
+	result := r.db.WithContext(ctx).
+		Where("employee_id = ? AND section_id = ?", employeeID, sectionID).
+		First(settings)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении параметров секции %d сотрудника: %d", sectionID, employeeID)
+		return nil, result.Error
+	}
+	return settings, nil
+}
+
+func (r *employeeRepository) GetSectionAssignments(ctx context.Context, sectionID uint) ([]models.EmployeeSection, error) {
+	var assignments []models.EmployeeSection
+	result := r.db.WithContext(ctx).Where("section_id = ?", sectionID).Find(&assignments)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении сотрудников секции: %d", sectionID)
+		return nil, result.Error
+	}
+	return assignments, nil
+}
+
+// Обновляет только существующее назначение, иначе consts.ErrNotFound
+func (r *employeeRepository) UpdateSectionSettings(ctx context.Context, settings *models.EmployeeSection) error {
+	result := r.db.WithContext(ctx).Model(&models.EmployeeSection{}).
+		Where("employee_id = ? AND section_id = ?", settings.EmployeeID, settings.SectionID).
+		Select("duration_minutes", "price", "buffer_before_minutes", "buffer_after_minutes").
+		Updates(settings)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при обновлении параметров секции %d сотрудника: %d", settings.SectionID, settings.EmployeeID)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+
+	return nil
+}
+
+func applyEmployeeFilter(query *gorm.DB, filter models.EmployeeFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.SectionID != 0 {
+		query = query.Where("id IN (SELECT employee_id FROM employee_sections WHERE section_id = ?)", filter.SectionID)
+	}
+	switch filter.IsActive {
+	case types.CheckValueTrue:
+		query = query.Where("is_active = ?", true)
+	case types.CheckValueFalse:
+		query = query.Where("is_active = ?", false)
+	}
+	if filter.Search != "" {
+		pattern := "%" + filter.Search + "%"
+		query = query.Where("(name ILIKE ? OR phone LIKE ?)", pattern, pattern)
+	}
+	return query
+}
+
+func countUnique(ids []uint) int {
+	unique := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	return len(unique)
+}
+
+func (r *employeeRepository) GetTimeZone(ctx context.Context, employeeID uint) (string, error) {
+	var timeZone string
+	result := r.db.WithContext(ctx).
+		Table("employees").
+		Select("COALESCE(NULLIF(employees.time_zone, ''), users.time_zone)").
+		Joins("JOIN users ON users.id = employees.user_id").
+		Where("employees.id = ?", employeeID).
+		Scan(&timeZone)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении часового пояса сотрудника: %d", employeeID)
+		return "", result.Error
+	}
+	return timeZone, nil
+}
@@ -0,0 +1,185 @@
+package holiday_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HolidayRepository interface {
+	GetCalendars(ctx context.Context, userID uint) ([]models.HolidayCalendar, error)
+	GetCalendarById(ctx context.Context, id uint) (*models.HolidayCalendar, error)
+	CreateCalendar(ctx context.Context, calendar *models.HolidayCalendar) (*models.HolidayCalendar, error)
+	UpdateCalendar(ctx context.Context, calendar *models.HolidayCalendar) (*models.HolidayCalendar, error)
+	DeleteCalendar(ctx context.Context, id uint) error
+
+	GetHolidays(ctx context.Context, calendarID uint) ([]models.Holiday, error)
+	// Праздники всех календарей владельца с даты from по дату to включительно
+	GetHolidaysByOwner(ctx context.Context, userID uint, from, to time.Time) ([]models.Holiday, error)
+	// Добавляет праздники, для уже существующих дат обновляет название
+	UpsertHolidays(ctx context.Context, calendarID uint, holidays []models.Holiday) error
+	DeleteHoliday(ctx context.Context, calendarID uint, holidayID uint) error
+}
+
+type holidayRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewHolidayRepository(db *gorm.DB, logger *zerolog.Logger) HolidayRepository {
+	return &holidayRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *holidayRepository) GetCalendars(ctx context.Context, userID uint) ([]models.HolidayCalendar, error) {
+	var calendars []models.HolidayCalendar
+	query := r.db.WithContext(ctx).Order("name")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	if err := query.Find(&calendars).Error; err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при получении календарей праздников пользователя: %d", userID)
+		return nil, err
+	}
+	return calendars, nil
+}
+
+func (r *holidayRepository) GetCalendarById(ctx context.Context, id uint) (*models.HolidayCalendar, error) {
+	calendar := &models.HolidayCalendar{}
+	result := r.db.WithContext(ctx).First(calendar, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении календаря праздников по id: %d", id)
+		return nil, result.Error
+	}
+	return calendar, nil
+}
+
+func (r *holidayRepository) CreateCalendar(ctx context.Context, calendar *models.HolidayCalendar) (*models.HolidayCalendar, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Create(calendar)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании календаря праздников: %s", calendar.Name)
+		return nil, result.Error
+	}
+	return calendar, nil
+}
+
+func (r *holidayRepository) UpdateCalendar(ctx context.Context, calendar *models.HolidayCalendar) (*models.HolidayCalendar, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Save(calendar)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при обновлении календаря праздников: %d", calendar.ID)
+		return nil, result.Error
+	}
+	return calendar, nil
+}
+
+// Удаляет календарь вместе с его днями
+func (r *holidayRepository) DeleteCalendar(ctx context.Context, id uint) error {
+	var rowsAffected int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("calendar_id = ?", id).Delete(&models.Holiday{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.HolidayCalendar{}, id)
+		rowsAffected = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при удалении календаря праздников по id: %d", id)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *holidayRepository) GetHolidays(ctx context.Context, calendarID uint) ([]models.Holiday, error) {
+	var holidays []models.Holiday
+	result := r.db.WithContext(ctx).
+		Where("calendar_id = ?", calendarID).
+		Order("date").
+		Find(&holidays)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении праздников календаря: %d", calendarID)
+		return nil, result.Error
+	}
+	return holidays, nil
+}
+
+func (r *holidayRepository) GetHolidaysByOwner(ctx context.Context, userID uint, from, to time.Time) ([]models.Holiday, error) {
+	var holidays []models.Holiday
+	result := r.db.WithContext(ctx).
+		Joins("JOIN holiday_calendars ON holiday_calendars.id = holidays.calendar_id AND holiday_calendars.deleted_at IS NULL").
+		Where("holiday_calendars.user_id = ? AND holidays.date BETWEEN ? AND ?", userID, from.Format(time.DateOnly), to.Format(time.DateOnly)).
+		Order("holidays.date").
+		Find(&holidays)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении праздников пользователя: %d", userID)
+		return nil, result.Error
+	}
+	return holidays, nil
+}
+
+func (r *holidayRepository) UpsertHolidays(ctx context.Context, calendarID uint, holidays []models.Holiday) error {
+	if len(holidays) == 0 {
+		return nil
+	}
+
+	// Одна дата дважды в одной вставке не пройдет ON CONFLICT, оставляем первую
+	unique := make([]models.Holiday, 0, len(holidays))
+	seen := make(map[string]bool, len(holidays))
+	for _, holiday := range holidays {
+		key := holiday.Date.Format(time.DateOnly)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		holiday.CalendarID = calendarID
+		unique = append(unique, holiday)
+	}
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "calendar_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name"}),
+	}).CreateInBatches(&unique, 500)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при сохранении праздников календаря: %d", calendarID)
+		return result.Error
+	}
+	return nil
+}
+
+func (r *holidayRepository) DeleteHoliday(ctx context.Context, calendarID uint, holidayID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("calendar_id = ?", calendarID).
+		Delete(&models.Holiday{}, holidayID)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при удалении праздника: %d", holidayID)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package job_repository
+
+import (
+	"context"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Очередь фоновых задач в БД. Задачи берутся с блокировкой строк
+// SKIP LOCKED, поэтому несколько процессов не выполнят одну задачу дважды
+type JobRepository interface {
+	// Отменяет ожидающие задачи с ключом key и создает jobs в одной транзакции
+	Replace(ctx context.Context, key string, jobs []models.Job) error
+	// Отменяет ожидающие задачи с ключом key
+	Cancel(ctx context.Context, key string) error
+	// Берет до limit задач, срок которых наступил к now, а также задачи,
+	// блокировка которых истекла, и блокирует их до now+lease. Задачи с
+	// истекшей блокировкой, исчерпавшие maxAttempts попыток, переводятся в failed
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int, maxAttempts int) ([]models.Job, error)
+	// Complete и Fail сохраняют результат попытки attempts. consts.ErrNotFound -
+	// блокировка истекла и задачу взяли снова
+	Complete(ctx context.Context, id uint, attempts int) error
+	// Возвращает задачу в очередь к retryAt, нулевой retryAt - задача не выполнена
+	Fail(ctx context.Context, id uint, attempts int, reason string, retryAt time.Time) error
+	// Удаляет выполненные и отмененные задачи, измененные раньше before
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type jobRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewJobRepository(db *gorm.DB, logger *zerolog.Logger) JobRepository {
+	return &jobRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *jobRepository) Replace(ctx context.Context, key string, jobs []models.Job) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := cancelPending(tx, key); err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+		for i := range jobs {
+			jobs[i].Key = key
+			jobs[i].Status = models.JobStatusPending
+		}
+		return tx.Create(&jobs).Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при планировании задач: %s", key)
+		return err
+	}
+	return nil
+}
+
+func (r *jobRepository) Cancel(ctx context.Context, key string) error {
+	if err := cancelPending(r.db.WithContext(ctx), key); err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при отмене задач: %s", key)
+		return err
+	}
+	return nil
+}
+
+func (r *jobRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int, maxAttempts int) ([]models.Job, error) {
+	var jobs []models.Job
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Процесс остановился или завис на последней попытке: повторять нельзя
+		if err := tx.Model(&models.Job{}).
+			Where("status = ? AND locked_until <= ? AND attempts >= ?", models.JobStatusRunning, now, maxAttempts).
+			Updates(map[string]any{
+				"status":       models.JobStatusFailed,
+				"last_error":   "блокировка истекла на последней попытке",
+				"locked_until": nil,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until <= ? AND attempts < ?)",
+				models.JobStatusPending, now, models.JobStatusRunning, now, maxAttempts).
+			Order("run_at").
+			Limit(limit).
+			Find(&jobs).Error; err != nil {
+			return err
+		}
+		if len(jobs) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(jobs))
+		lockedUntil := now.Add(lease)
+		for i := range jobs {
+			ids[i] = jobs[i].ID
+			jobs[i].Status = models.JobStatusRunning
+			jobs[i].Attempts++
+			jobs[i].LockedUntil = &lockedUntil
+		}
+
+		return tx.Model(&models.Job{}).Where("id IN ?", ids).Updates(map[string]any{
+			"status":       models.JobStatusRunning,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"locked_until": lockedUntil,
+		}).Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при получении фоновых задач")
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (r *jobRepository) Complete(ctx context.Context, id uint, attempts int) error {
+	result := r.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ? AND attempts = ?", id, models.JobStatusRunning, attempts).
+		Updates(map[string]any{
+			"status":       models.JobStatusDone,
+			"locked_until": nil,
+		})
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при завершении фоновой задачи: %d", id)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+	return nil
+}
+
+func (r *jobRepository) Fail(ctx context.Context, id uint, attempts int, reason string, retryAt time.Time) error {
+	updates := map[string]any{
+		"status":       models.JobStatusFailed,
+		"last_error":   reason,
+		"locked_until": nil,
+	}
+	if !retryAt.IsZero() {
+		updates["status"] = models.JobStatusPending
+		updates["run_at"] = retryAt
+	}
+
+	result := r.db.WithContext(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ? AND attempts = ?", id, models.JobStatusRunning, attempts).
+		Updates(updates)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при сохранении ошибки фоновой задачи: %d", id)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+	return nil
+}
+
+func (r *jobRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("status IN ? AND updated_at < ?", []models.JobStatus{models.JobStatusDone, models.JobStatusCancelled}, before).
+		Delete(&models.Job{})
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при удалении завершенных фоновых задач")
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func cancelPending(db *gorm.DB, key string) error {
+	return db.Model(&models.Job{}).
+		Where("key = ? AND status = ?", key, models.JobStatusPending).
+		Update("status", models.JobStatusCancelled).Error
+}
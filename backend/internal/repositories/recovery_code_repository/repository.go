@@ -0,0 +1,91 @@
+package recovery_code_repository
+
+import (
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type RecoveryCodeRepository interface {
+	ReplaceForUser(userID uint, codeHashes []string) error
+	Use(userID uint, codeHash string) error
+	CountUnused(userID uint) (int64, error)
+	DeleteByUser(userID uint) error
+}
+
+type recoveryCodeRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB, logger *zerolog.Logger) RecoveryCodeRepository {
+	return &recoveryCodeRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Заменяет все коды пользователя новым набором
+func (r *recoveryCodeRepository) ReplaceForUser(userID uint, codeHashes []string) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]models.RecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+		}
+
+		return tx.Create(&codes).Error
+	})
+
+	if err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при сохранении кодов восстановления пользователя: %d", userID)
+		return err
+	}
+	return nil
+}
+
+// Гасит код. consts.ErrNotFound - кода нет или он уже использован
+func (r *recoveryCodeRepository) Use(userID uint, codeHash string) error {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при использовании кода восстановления пользователя: %d", userID)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *recoveryCodeRepository) CountUnused(userID uint) (int64, error) {
+	var count int64
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при подсчете кодов восстановления пользователя: %d", userID)
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+func (r *recoveryCodeRepository) DeleteByUser(userID uint) error {
+	result := r.db.Unscoped().Where("user_id = ?", userID).Delete(&models.RecoveryCode{})
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при удалении кодов восстановления пользователя: %d", userID)
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package refresh_token_repository
+
+import (
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	Create(token *models.RefreshToken) (*models.RefreshToken, error)
+	GetByHash(tokenHash string) (*models.RefreshToken, error)
+	ListActiveByUser(userID uint) ([]models.RefreshToken, error)
+	MarkUsed(id uint) error
+	RevokeFamily(familyID string) error
+	RevokeAllByUser(userID uint) error
+}
+
+type refreshTokenRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewRefreshTokenRepository(db *gorm.DB, logger *zerolog.Logger) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *refreshTokenRepository) Create(token *models.RefreshToken) (*models.RefreshToken, error) {
+	result := r.db.Create(token)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании refresh токена пользователя: %d", token.UserID)
+		return nil, result.Error
+	}
+	return token, nil
+}
+
+func (r *refreshTokenRepository) GetByHash(tokenHash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	result := r.db.First(token, "token_hash = ?", tokenHash)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении refresh токена")
+		return nil, result.Error
+	}
+	return token, nil
+}
+
+// У каждой активной сессии ровно один неиспользованный и неотозванный токен
+func (r *refreshTokenRepository) ListActiveByUser(userID uint) ([]models.RefreshToken, error) {
+	var tokens []models.RefreshToken
+	result := r.db.
+		Where("user_id = ? AND used_at IS NULL AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("created_at DESC").
+		Find(&tokens)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении сессий пользователя: %d", userID)
+		return nil, result.Error
+	}
+	return tokens, nil
+}
+
+// Помечает токен использованным. Условие в UPDATE гарантирует, что из двух
+// параллельных запросов с одним токеном успешным будет только один,
+// второй получит consts.ErrNoRowsAffected
+func (r *refreshTokenRepository) MarkUsed(id uint) error {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при использовании refresh токена: %d", id)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(familyID string) error {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при отзыве семейства refresh токенов: %s", familyID)
+		return result.Error
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeAllByUser(userID uint) error {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при отзыве refresh токенов пользователя: %d", userID)
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package reminder_repository
+
+import (
+	"context"
+	"record-services/internal/models"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type ReminderRepository interface {
+	// Правила напоминаний владельца по получателю и времени до записи
+	GetRules(ctx context.Context, ownerID uint) ([]models.ReminderRule, error)
+	// Заменяет все правила владельца на rules
+	ReplaceRules(ctx context.Context, ownerID uint, rules []models.ReminderRule) error
+}
+
+type reminderRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewReminderRepository(db *gorm.DB, logger *zerolog.Logger) ReminderRepository {
+	return &reminderRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *reminderRepository) GetRules(ctx context.Context, ownerID uint) ([]models.ReminderRule, error) {
+	rules := make([]models.ReminderRule, 0)
+	result := r.db.WithContext(ctx).
+		Where("user_id = ?", ownerID).
+		Order("recipient, minutes_before DESC").
+		Find(&rules)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении правил напоминаний владельца: %d", ownerID)
+		return nil, result.Error
+	}
+	return rules, nil
+}
+
+func (r *reminderRepository) ReplaceRules(ctx context.Context, ownerID uint, rules []models.ReminderRule) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", ownerID).Delete(&models.ReminderRule{}).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		for i := range rules {
+			rules[i].UserID = ownerID
+		}
+		return tx.Create(&rules).Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при сохранении правил напоминаний владельца: %d", ownerID)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package resource_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type ResourceRepository interface {
+	GetById(ctx context.Context, id uint) (*models.Resource, error)
+	Create(ctx context.Context, resource *models.Resource) (*models.Resource, error)
+	Update(ctx context.Context, resource *models.Resource) (*models.Resource, error)
+	// Удаленный ресурс перестает требоваться секциям, новые записи его не занимают
+	Delete(ctx context.Context, id uint) error
+	GetAllWithPagination(ctx context.Context, limit, page int, filter models.ResourceFilter) (*models.PaginatedResources, error)
+}
+
+type resourceRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewResourceRepository(db *gorm.DB, logger *zerolog.Logger) ResourceRepository {
+	return &resourceRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *resourceRepository) GetById(ctx context.Context, id uint) (*models.Resource, error) {
+	resource := &models.Resource{}
+	result := r.db.WithContext(ctx).First(resource, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении ресурса по id: %d", id)
+		return nil, result.Error
+	}
+	return resource, nil
+}
+
+func (r *resourceRepository) Create(ctx context.Context, resource *models.Resource) (*models.Resource, error) {
+	result := r.db.WithContext(ctx).Omit("User").Create(resource)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании ресурса: %s", resource.Name)
+		return nil, result.Error
+	}
+	return resource, nil
+}
+
+func (r *resourceRepository) Update(ctx context.Context, resource *models.Resource) (*models.Resource, error) {
+	result := r.db.WithContext(ctx).Omit("User").Save(resource)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при обновлении ресурса: %d", resource.ID)
+		return nil, result.Error
+	}
+	return resource, nil
+}
+
+func (r *resourceRepository) Delete(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.Resource{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return consts.ErrNotFound
+		}
+
+		return tx.Exec("DELETE FROM section_resources WHERE resource_id = ?", id).Error
+	})
+	if err != nil {
+		if !errors.Is(err, consts.ErrNotFound) {
+			r.logger.Error().Err(err).Msgf("ошибка при удалении ресурса по id: %d", id)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *resourceRepository) GetAllWithPagination(ctx context.Context, limit, page int, filter models.ResourceFilter) (*models.PaginatedResources, error) {
+	var resources []models.Resource
+	var totalCount int64
+
+	db := r.db.WithContext(ctx)
+
+	if err := applyResourceFilter(db.Model(&models.Resource{}), filter).Count(&totalCount).Error; err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при подсчете ресурсов")
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 30
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	result := applyResourceFilter(db.Model(&models.Resource{}), filter).
+		Order("name").
+		Limit(limit).
+		Offset(offset).
+		Find(&resources)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении ресурсов")
+		return nil, result.Error
+	}
+
+	totalPages := int(totalCount) / limit
+	if int(totalCount)%limit > 0 {
+		totalPages++
+	}
+
+	resourcesResponse := make([]models.ResourceResponse, len(resources))
+	for i := range resources {
+		resourcesResponse[i] = resources[i].ToResponse()
+	}
+
+	return &models.PaginatedResources{
+		Resources:  resourcesResponse,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+		Page:       page,
+		Limit:      limit,
+		HasMore:    page < totalPages,
+	}, nil
+}
+
+func applyResourceFilter(query *gorm.DB, filter models.ResourceFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Name != "" {
+		query = query.Where("name ILIKE ?", "%"+filter.Name+"%")
+	}
+	return query
+}
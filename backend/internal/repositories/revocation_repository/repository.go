@@ -0,0 +1,306 @@
+package revocation_repository
+
+import (
+	"record-services/internal/models"
+	"record-services/pkg/utils"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Хранилище отозванных access токенов. Проверка выполняется на каждый запрос,
+// поэтому записи держим в памяти. Отзыв сначала сохраняется в БД: записи
+// загружаются при запуске и периодически подтягиваются оттуда, так что
+// отзыв переживает перезапуск и виден всем экземплярам сервера
+type RevocationRepository interface {
+	RevokeToken(jti string, expiresAt time.Time) error
+	RevokeSession(sessionID string) error
+	RevokeUser(userID uint) error
+	IsRevoked(claims *utils.UserClaims) bool
+	// Гасит MFA токен на время проверки кода. false - токен уже использован
+	UseMFAChallenge(jti string, expiresAt time.Time) (bool, error)
+	// Возвращает MFA токен после неверного кода, чтобы ввести код еще раз
+	ReleaseMFAChallenge(jti string) error
+}
+
+type revocationRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+	// Дольше этого времени access токен не живет
+	tokenTTL time.Duration
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // jti -> expiresAt
+	sessions map[string]time.Time // sid -> expiresAt
+	users    map[uint]revokedUser
+	// Время последней загрузки из БД
+	syncedAt time.Time
+}
+
+// Период загрузки отзывов, сделанных другими экземплярами сервера
+const syncInterval = 5 * time.Second
+
+type revokedUser struct {
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+func NewRevocationRepository(db *gorm.DB, logger *zerolog.Logger, tokenTTL time.Duration) (RevocationRepository, error) {
+	r := &revocationRepository{
+		db:       db,
+		logger:   logger,
+		tokenTTL: tokenTTL,
+		tokens:   make(map[string]time.Time),
+		sessions: make(map[string]time.Time),
+		users:    make(map[uint]revokedUser),
+	}
+
+	if err := r.load(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	// Запускаем фоновую загрузку и очистку
+	go r.startSync()
+	go r.startCleanup()
+
+	return r, nil
+}
+
+func (r *revocationRepository) RevokeToken(jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return nil
+	}
+
+	entry := &models.RevokedToken{
+		Kind:      models.RevocationKindToken,
+		Value:     jti,
+		RevokedAt: revocationTime(),
+		ExpiresAt: expiresAt,
+	}
+	if err := r.save(entry); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.tokens[jti] = expiresAt
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *revocationRepository) RevokeSession(sessionID string) error {
+	if sessionID == "" {
+		return nil
+	}
+
+	now := revocationTime()
+	entry := &models.RevokedToken{
+		Kind:      models.RevocationKindSession,
+		Value:     sessionID,
+		RevokedAt: now,
+		ExpiresAt: now.Add(r.tokenTTL),
+	}
+	if err := r.save(entry); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.sessions[sessionID] = entry.ExpiresAt
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *revocationRepository) RevokeUser(userID uint) error {
+	now := revocationTime()
+	entry := &models.RevokedToken{
+		Kind:      models.RevocationKindUser,
+		Value:     strconv.FormatUint(uint64(userID), 10),
+		RevokedAt: now,
+		ExpiresAt: now.Add(r.tokenTTL),
+	}
+	if err := r.save(entry); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.users[userID] = revokedUser{revokedAt: now, expiresAt: entry.ExpiresAt}
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *revocationRepository) IsRevoked(claims *utils.UserClaims) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	jti := claims.RegisteredClaims.ID
+	if _, ok := r.tokens[jti]; ok && jti != "" {
+		return true
+	}
+
+	if _, ok := r.sessions[claims.SessionID]; ok && claims.SessionID != "" {
+		return true
+	}
+
+	if user, ok := r.users[claims.ID]; ok {
+		// Время отзыва и iat хранятся с точностью до секунды: токены,
+		// выпущенные в ту же секунду, что и отзыв, тоже считаются отозванными
+		if claims.IssuedAt == nil || user.revokedAt.Compare(claims.IssuedAt.Time.Truncate(time.Second)) >= 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Запись вставляется без обновления при конфликте, поэтому из
+// параллельных запросов с одним токеном вход завершит только один
+func (r *revocationRepository) UseMFAChallenge(jti string, expiresAt time.Time) (bool, error) {
+	if jti == "" {
+		return false, nil
+	}
+
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		Kind:      models.RevocationKindMFAChallenge,
+		Value:     jti,
+		RevokedAt: revocationTime(),
+		ExpiresAt: expiresAt,
+	})
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при сохранении использованного MFA токена: %s", jti)
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *revocationRepository) ReleaseMFAChallenge(jti string) error {
+	result := r.db.Where("kind = ? AND value = ?", models.RevocationKindMFAChallenge, jti).
+		Delete(&models.RevokedToken{})
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при возврате MFA токена: %s", jti)
+		return result.Error
+	}
+	return nil
+}
+
+// Повторный отзыв того же значения продлевает запись
+func (r *revocationRepository) save(entry *models.RevokedToken) error {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "value"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "expires_at"}),
+	}).Create(entry)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при сохранении отзыва %s: %s", entry.Kind, entry.Value)
+		return result.Error
+	}
+	return nil
+}
+
+// Загружает действующие записи, отозванные начиная с since.
+// Нулевой since - все действующие записи
+func (r *revocationRepository) load(since time.Time) error {
+	// Время чтения фиксируется до запроса: запись, сохраненная во время
+	// запроса, попадет в следующую загрузку
+	now := time.Now()
+
+	var entries []models.RevokedToken
+	query := r.db.Where("expires_at > ?", now)
+	if !since.IsZero() {
+		query = query.Where("revoked_at >= ?", since)
+	}
+	if result := query.Find(&entries); result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при загрузке отозванных токенов")
+		return result.Error
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Следующая загрузка захватывает и предыдущий период: время отзыва
+	// округлено вниз до секунды, а запись могла быть сохранена с задержкой
+	r.syncedAt = now.Add(-syncInterval).Truncate(time.Second)
+
+	for _, entry := range entries {
+		switch entry.Kind {
+		case models.RevocationKindToken:
+			r.tokens[entry.Value] = entry.ExpiresAt
+		case models.RevocationKindSession:
+			r.sessions[entry.Value] = entry.ExpiresAt
+		case models.RevocationKindUser:
+			userID, err := strconv.ParseUint(entry.Value, 10, 64)
+			if err != nil {
+				continue
+			}
+			// Повторный отзыв пользователя сдвигает время отзыва только вперед
+			if user, ok := r.users[uint(userID)]; ok && user.revokedAt.After(entry.RevokedAt) {
+				continue
+			}
+			r.users[uint(userID)] = revokedUser{revokedAt: entry.RevokedAt, expiresAt: entry.ExpiresAt}
+		}
+	}
+
+	return nil
+}
+
+// Запускает фоновую загрузку записей, сохраненных другими экземплярами
+func (r *revocationRepository) startSync() {
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.mu.RLock()
+		since := r.syncedAt
+		r.mu.RUnlock()
+
+		_ = r.load(since)
+	}
+}
+
+// Запускает фоновую очистку устаревших записей
+func (r *revocationRepository) startCleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.clearExpired()
+	}
+}
+
+func (r *revocationRepository) clearExpired() {
+	now := time.Now()
+
+	r.mu.Lock()
+	for jti, expiresAt := range r.tokens {
+		if now.After(expiresAt) {
+			delete(r.tokens, jti)
+		}
+	}
+	for sid, expiresAt := range r.sessions {
+		if now.After(expiresAt) {
+			delete(r.sessions, sid)
+		}
+	}
+	for userID, user := range r.users {
+		if now.After(user.expiresAt) {
+			delete(r.users, userID)
+		}
+	}
+	r.mu.Unlock()
+
+	if err := r.db.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при удалении устаревших отзывов токенов")
+	}
+}
+
+// Время отзыва с точностью iat в JWT
+func revocationTime() time.Time {
+	return time.Now().Truncate(time.Second)
+}
@@ -0,0 +1,35 @@
+package role_repository
+
+import (
+	"record-services/internal/models"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type RoleRepository interface {
+	GetAll() ([]models.Role, error)
+}
+
+type roleRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewRoleRepository(db *gorm.DB, logger *zerolog.Logger) RoleRepository {
+	return &roleRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *roleRepository) GetAll() ([]models.Role, error) {
+	var roles []models.Role
+	result := r.db.Preload("Permissions").Order("name").Find(&roles)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении ролей")
+		return nil, result.Error
+	}
+	return roles, nil
+}
@@ -0,0 +1,123 @@
+package schedule_repository
+
+import (
+	"context"
+	"record-services/internal/models"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type ScheduleRepository interface {
+	GetWeekly(ctx context.Context, employeeID uint) ([]models.WeeklySchedule, error)
+	ReplaceWeekly(ctx context.Context, employeeID uint, entries []models.WeeklySchedule) error
+	// Исключения по датам в интервале [from, to]
+	GetOverrides(ctx context.Context, employeeID uint, from, to time.Time) ([]models.ScheduleOverride, error)
+	ReplaceOverride(ctx context.Context, employeeID uint, date time.Time, entries []models.ScheduleOverride) error
+	DeleteOverride(ctx context.Context, employeeID uint, date time.Time) error
+}
+
+type scheduleRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewScheduleRepository(db *gorm.DB, logger *zerolog.Logger) ScheduleRepository {
+	return &scheduleRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *scheduleRepository) GetWeekly(ctx context.Context, employeeID uint) ([]models.WeeklySchedule, error) {
+	var entries []models.WeeklySchedule
+	result := r.db.WithContext(ctx).
+		Where("employee_id = ?", employeeID).
+		Order("weekday, start_minute").
+		Find(&entries)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении графика сотрудника: %d", employeeID)
+		return nil, result.Error
+	}
+	return entries, nil
+}
+
+// Заменяет недельный график целиком. Старые строки удаляются физически:
+// история графика не нужна, а мягкое удаление копило бы мусор
+func (r *scheduleRepository) ReplaceWeekly(ctx context.Context, employeeID uint, entries []models.WeeklySchedule) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("employee_id = ?", employeeID).Delete(&models.WeeklySchedule{}).Error; err != nil {
+			return err
+		}
+
+		for i := range entries {
+			entries[i].EmployeeID = employeeID
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return tx.Create(&entries).Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при сохранении графика сотрудника: %d", employeeID)
+		return err
+	}
+	return nil
+}
+
+func (r *scheduleRepository) GetOverrides(ctx context.Context, employeeID uint, from, to time.Time) ([]models.ScheduleOverride, error) {
+	var entries []models.ScheduleOverride
+	result := r.db.WithContext(ctx).
+		Where("employee_id = ? AND date BETWEEN ? AND ?", employeeID, dateOnly(from), dateOnly(to)).
+		Order("date, start_minute").
+		Find(&entries)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении исключений графика сотрудника: %d", employeeID)
+		return nil, result.Error
+	}
+	return entries, nil
+}
+
+func (r *scheduleRepository) ReplaceOverride(ctx context.Context, employeeID uint, date time.Time, entries []models.ScheduleOverride) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := deleteOverride(tx, employeeID, date); err != nil {
+			return err
+		}
+
+		for i := range entries {
+			entries[i].EmployeeID = employeeID
+			entries[i].Date = date
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return tx.Create(&entries).Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при сохранении исключения графика сотрудника %d на %s", employeeID, dateOnly(date))
+		return err
+	}
+	return nil
+}
+
+func (r *scheduleRepository) DeleteOverride(ctx context.Context, employeeID uint, date time.Time) error {
+	if err := deleteOverride(r.db.WithContext(ctx), employeeID, date); err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при удалении исключения графика сотрудника %d на %s", employeeID, dateOnly(date))
+		return err
+	}
+	return nil
+}
+
+func deleteOverride(db *gorm.DB, employeeID uint, date time.Time) error {
+	return db.Unscoped().
+		Where("employee_id = ? AND date = ?", employeeID, dateOnly(date)).
+		Delete(&models.ScheduleOverride{}).Error
+}
+
+// Колонка date сравнивается со строкой, чтобы часовой пояс не сдвигал дату
+func dateOnly(t time.Time) string {
+	return t.Format(time.DateOnly)
+}
@@ -0,0 +1,183 @@
+package section_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SectionRepository interface {
+	GetById(ctx context.Context, id uint) (*models.Section, error)
+	Create(ctx context.Context, section *models.Section) (*models.Section, error)
+	Update(ctx context.Context, section *models.Section) (*models.Section, error)
+	Delete(ctx context.Context, id uint) error
+	GetAllWithPagination(ctx context.Context, limit, page int, filter models.SectionFilter) (*models.PaginatedSections, error)
+	// Заменяет ресурсы, нужные услуге. consts.ErrNotFound - нет секции,
+	// consts.ErrBadData - ресурс не найден у владельца секции
+	ReplaceResources(ctx context.Context, sectionID uint, resourceIDs []uint) error
+}
+
+type sectionRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewSectionRepository(db *gorm.DB, logger *zerolog.Logger) SectionRepository {
+	return &sectionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *sectionRepository) GetById(ctx context.Context, id uint) (*models.Section, error) {
+	section := &models.Section{}
+	result := r.db.WithContext(ctx).Preload("Resources").First(section, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении секции по id: %d", id)
+		return nil, result.Error
+	}
+	return section, nil
+}
+
+func (r *sectionRepository) Create(ctx context.Context, section *models.Section) (*models.Section, error) {
+	result := r.db.WithContext(ctx).Omit("User", "Employees", "Resources").Create(section)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании секции: %s", section.Name)
+		return nil, result.Error
+	}
+	return section, nil
+}
+
+// Ресурсы секции меняются только через ReplaceResources
+func (r *sectionRepository) Update(ctx context.Context, section *models.Section) (*models.Section, error) {
+	result := r.db.WithContext(ctx).Omit("User", "Employees", "Resources").Save(section)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при обновлении секции: %d", section.ID)
+		return nil, result.Error
+	}
+	return section, nil
+}
+
+func (r *sectionRepository) Delete(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&models.Section{}, id)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при удалении секции по id: %d", id)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *sectionRepository) GetAllWithPagination(ctx context.Context, limit, page int, filter models.SectionFilter) (*models.PaginatedSections, error) {
+	var sections []models.Section
+	var totalCount int64
+
+	db := r.db.WithContext(ctx)
+
+	if err := applySectionFilter(db.Model(&models.Section{}), filter).Count(&totalCount).Error; err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при подсчете секций")
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 30
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	result := applySectionFilter(db.Model(&models.Section{}), filter).
+		Preload("Resources").
+		Order("name").
+		Limit(limit).
+		Offset(offset).
+		Find(&sections)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении секций")
+		return nil, result.Error
+	}
+
+	totalPages := int(totalCount) / limit
+	if int(totalCount)%limit > 0 {
+		totalPages++
+	}
+
+	sectionsResponse := make([]models.SectionResponse, len(sections))
+	for i := range sections {
+		sectionsResponse[i] = sections[i].ToResponse()
+	}
+
+	return &models.PaginatedSections{
+		Sections:   sectionsResponse,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+		Page:       page,
+		Limit:      limit,
+		HasMore:    page < totalPages,
+	}, nil
+}
+
+func (r *sectionRepository) ReplaceResources(ctx context.Context, sectionID uint, resourceIDs []uint) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Блокировка строки секции упорядочивает параллельные замены
+		section := &models.Section{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(section, sectionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return consts.ErrNotFound
+			}
+			return err
+		}
+
+		resources := make([]models.Resource, 0, len(resourceIDs))
+		if len(resourceIDs) > 0 {
+			if err := tx.Where("id IN ? AND user_id = ?", resourceIDs, section.UserID).Find(&resources).Error; err != nil {
+				return err
+			}
+			if len(resources) != countUnique(resourceIDs) {
+				return consts.ErrBadData
+			}
+		}
+
+		return tx.Model(section).Omit("Resources.*").Association("Resources").Replace(resources)
+	})
+	if err != nil {
+		if !errors.Is(err, consts.ErrNotFound) && !errors.Is(err, consts.ErrBadData) {
+			r.logger.Error().Err(err).Msgf("ошибка при замене ресурсов секции: %d", sectionID)
+		}
+		return err
+	}
+	return nil
+}
+
+func applySectionFilter(query *gorm.DB, filter models.SectionFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Name != "" {
+		query = query.Where("name ILIKE ?", "%"+filter.Name+"%")
+	}
+	return query
+}
+
+func countUnique(ids []uint) int {
+	unique := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	return len(unique)
+}
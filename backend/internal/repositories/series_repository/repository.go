@@ -0,0 +1,163 @@
+package series_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/database"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Серии сохраняются вместе с вхождениями одной транзакцией. Create и Replace
+// возвращают consts.ErrConflict, если вхождение пересекается с другой
+// активной записью сотрудника или занимающей тот же ресурс, и тогда
+// ничего не сохраняется
+type SeriesRepository interface {
+	GetById(ctx context.Context, id uint) (*models.AppointmentSeries, error)
+	// Все записи серии, включая исключения и отмененные, по времени вхождения
+	GetOccurrences(ctx context.Context, seriesID uint) ([]models.Appointment, error)
+	Create(ctx context.Context, series *models.AppointmentSeries, occurrences []models.Appointment) error
+	// Сохраняет series и заменяет ее активные вхождения начиная с from,
+	// кроме исключений, на occurrences. Если задана next, серия разделяется:
+	// next создается, вхождения с from переходят к ней
+	Replace(ctx context.Context, series, next *models.AppointmentSeries, from time.Time, occurrences []models.Appointment) error
+	// Сохраняет series и отменяет ее активные вхождения начиная с from
+	Cancel(ctx context.Context, series *models.AppointmentSeries, from time.Time) (int64, error)
+}
+
+type seriesRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewSeriesRepository(db *gorm.DB, logger *zerolog.Logger) SeriesRepository {
+	return &seriesRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+var activeStatuses = []models.AppointmentStatus{models.AppointmentStatusPending, models.AppointmentStatusConfirmed}
+
+func (r *seriesRepository) GetById(ctx context.Context, id uint) (*models.AppointmentSeries, error) {
+	series := &models.AppointmentSeries{}
+	result := r.db.WithContext(ctx).First(series, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении серии записей по id: %d", id)
+		return nil, result.Error
+	}
+	return series, nil
+}
+
+func (r *seriesRepository) GetOccurrences(ctx context.Context, seriesID uint) ([]models.Appointment, error) {
+	var occurrences []models.Appointment
+	result := r.db.WithContext(ctx).
+		Preload("Employee").
+		Preload("Section").
+		Where("series_id = ?", seriesID).
+		Order("occurrence_at").
+		Find(&occurrences)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении записей серии: %d", seriesID)
+		return nil, result.Error
+	}
+	return occurrences, nil
+}
+
+func (r *seriesRepository) Create(ctx context.Context, series *models.AppointmentSeries, occurrences []models.Appointment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(series).Error; err != nil {
+			return err
+		}
+		return createOccurrences(tx, series.ID, occurrences)
+	})
+	if err != nil {
+		if database.IsExclusionViolation(err, models.AppointmentExclusionConstraints...) {
+			return consts.ErrConflict
+		}
+		r.logger.Error().Err(err).Msgf("ошибка при создании серии записей к сотруднику: %d", series.EmployeeID)
+		return err
+	}
+	return nil
+}
+
+func (r *seriesRepository) Replace(ctx context.Context, series, next *models.AppointmentSeries, from time.Time, occurrences []models.Appointment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(series).Error; err != nil {
+			return err
+		}
+
+		// Удаленные вхождения не участвуют в ограничении на пересечение,
+		// поэтому новые можно создавать на то же время
+		if err := tx.Where("series_id = ? AND occurrence_at >= ? AND status IN ? AND NOT series_exception",
+			series.ID, from, activeStatuses).
+			Delete(&models.Appointment{}).Error; err != nil {
+			return err
+		}
+
+		target := series
+		if next != nil {
+			if err := tx.Create(next).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Appointment{}).
+				Where("series_id = ? AND occurrence_at >= ?", series.ID, from).
+				Update("series_id", next.ID).Error; err != nil {
+				return err
+			}
+			target = next
+		}
+
+		return createOccurrences(tx, target.ID, occurrences)
+	})
+	if err != nil {
+		if database.IsExclusionViolation(err, models.AppointmentExclusionConstraints...) {
+			return consts.ErrConflict
+		}
+		r.logger.Error().Err(err).Msgf("ошибка при изменении серии записей: %d", series.ID)
+		return err
+	}
+	return nil
+}
+
+func (r *seriesRepository) Cancel(ctx context.Context, series *models.AppointmentSeries, from time.Time) (int64, error) {
+	var cancelled int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(series).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.Appointment{}).
+			Where("series_id = ? AND occurrence_at >= ? AND status IN ?", series.ID, from, activeStatuses).
+			Update("status", models.AppointmentStatusCancelled)
+		cancelled = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при отмене серии записей: %d", series.ID)
+		return 0, err
+	}
+	return cancelled, nil
+}
+
+func createOccurrences(tx *gorm.DB, seriesID uint, occurrences []models.Appointment) error {
+	if len(occurrences) == 0 {
+		return nil
+	}
+
+	for i := range occurrences {
+		occurrences[i].SeriesID = &seriesID
+	}
+	return tx.Omit(clause.Associations).Create(&occurrences).Error
+}
@@ -0,0 +1,220 @@
+package session_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/database"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Занятые места считаются по активным записям занятия. Запись на место
+// и изменение вместимости блокируют строку занятия, поэтому параллельные
+// запросы не запишут больше клиентов, чем мест
+type SessionRepository interface {
+	GetById(ctx context.Context, id uint) (*models.GroupSession, error)
+	// Занятие сотрудника по услуге, начинающееся в start
+	GetBySlot(ctx context.Context, employeeID, sectionID uint, start time.Time) (*models.GroupSession, error)
+	// Занятия по времени начала, с занятыми местами
+	GetAll(ctx context.Context, filter models.GroupSessionFilter) ([]models.GroupSession, error)
+	// Записи на места занятия, включая отмененные
+	GetAttendees(ctx context.Context, sessionID uint) ([]models.Appointment, error)
+	// Создает appointment как место в занятии ее сотрудника по ее услуге
+	// на ее время. Занятия еще нет - создается с вместимостью capacity.
+	// Окончание записи берется из занятия. consts.ErrNoSeats - мест нет,
+	// consts.ErrConflict - время сотрудника или ресурса занято другой записью
+	BookSeat(ctx context.Context, appointment *models.Appointment, capacity int) error
+	// consts.ErrConflict - мест меньше, чем уже занято
+	UpdateCapacity(ctx context.Context, session *models.GroupSession, capacity int) error
+}
+
+type sessionRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewSessionRepository(db *gorm.DB, logger *zerolog.Logger) SessionRepository {
+	return &sessionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+var activeStatuses = []models.AppointmentStatus{models.AppointmentStatusPending, models.AppointmentStatusConfirmed}
+
+// Занятия вместе с числом активных записей на места
+func (r *sessionRepository) withBooked(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.GroupSession{}).
+		Select("group_sessions.*, (SELECT COUNT(*) FROM appointments WHERE appointments.session_id = group_sessions.id "+
+			"AND appointments.status IN ? AND appointments.deleted_at IS NULL) AS booked", activeStatuses)
+}
+
+func (r *sessionRepository) GetById(ctx context.Context, id uint) (*models.GroupSession, error) {
+	session := &models.GroupSession{}
+	result := r.withBooked(r.db.WithContext(ctx)).
+		Preload("Employee").
+		Preload("Section").
+		First(session, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении занятия по id: %d", id)
+		return nil, result.Error
+	}
+	return session, nil
+}
+
+func (r *sessionRepository) GetBySlot(ctx context.Context, employeeID, sectionID uint, start time.Time) (*models.GroupSession, error) {
+	session := &models.GroupSession{}
+	result := r.withBooked(r.db.WithContext(ctx)).
+		Where("employee_id = ? AND section_id = ? AND start_at = ?", employeeID, sectionID, start).
+		First(session)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении занятия сотрудника: %d", employeeID)
+		return nil, result.Error
+	}
+	return session, nil
+}
+
+func (r *sessionRepository) GetAll(ctx context.Context, filter models.GroupSessionFilter) ([]models.GroupSession, error) {
+	var sessions []models.GroupSession
+	result := applySessionFilter(r.withBooked(r.db.WithContext(ctx)), filter).
+		Preload("Employee").
+		Preload("Section").
+		Order("start_at").
+		Find(&sessions)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении занятий")
+		return nil, result.Error
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) GetAttendees(ctx context.Context, sessionID uint) ([]models.Appointment, error) {
+	var attendees []models.Appointment
+	result := r.db.WithContext(ctx).
+		Where("session_id = ?", sessionID).
+		Order("created_at").
+		Find(&attendees)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении записей занятия: %d", sessionID)
+		return nil, result.Error
+	}
+	return attendees, nil
+}
+
+func (r *sessionRepository) BookSeat(ctx context.Context, appointment *models.Appointment, capacity int) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		session := &models.GroupSession{
+			UserID:     appointment.UserID,
+			EmployeeID: appointment.EmployeeID,
+			SectionID:  appointment.SectionID,
+			StartAt:    appointment.StartAt,
+			EndAt:      appointment.EndAt,
+			Capacity:   capacity,
+			TimeZone:   appointment.TimeZone,
+		}
+
+		// Занятие уже создано другим запросом - вставка пропускается
+		// после его фиксации, и ниже читается существующее
+		if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(session).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("employee_id = ? AND section_id = ? AND start_at = ?", appointment.EmployeeID, appointment.SectionID, appointment.StartAt).
+			First(session).Error; err != nil {
+			return err
+		}
+
+		var booked int64
+		if err := tx.Model(&models.Appointment{}).
+			Where("session_id = ? AND status IN ?", session.ID, activeStatuses).
+			Count(&booked).Error; err != nil {
+			return err
+		}
+
+		if int(booked) >= session.Capacity {
+			return consts.ErrNoSeats
+		}
+
+		appointment.SessionID = &session.ID
+		appointment.EndAt = session.EndAt
+		return tx.Omit(clause.Associations).Create(appointment).Error
+	})
+	if err != nil {
+		if database.IsExclusionViolation(err, models.AppointmentExclusionConstraints...) {
+			return consts.ErrConflict
+		}
+		if !errors.Is(err, consts.ErrNoSeats) {
+			r.logger.Error().Err(err).Msgf("ошибка при записи на занятие сотрудника: %d", appointment.EmployeeID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *sessionRepository) UpdateCapacity(ctx context.Context, session *models.GroupSession, capacity int) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&models.GroupSession{}, session.ID).Error; err != nil {
+			return err
+		}
+
+		var booked int64
+		if err := tx.Model(&models.Appointment{}).
+			Where("session_id = ? AND status IN ?", session.ID, activeStatuses).
+			Count(&booked).Error; err != nil {
+			return err
+		}
+
+		if capacity < int(booked) {
+			return consts.ErrConflict
+		}
+
+		session.Booked = int(booked)
+		return tx.Model(&models.GroupSession{}).Where("id = ?", session.ID).Update("capacity", capacity).Error
+	})
+	if err != nil {
+		if !errors.Is(err, consts.ErrConflict) {
+			r.logger.Error().Err(err).Msgf("ошибка при изменении вместимости занятия: %d", session.ID)
+		}
+		return err
+	}
+
+	session.Capacity = capacity
+	return nil
+}
+
+func applySessionFilter(query *gorm.DB, filter models.GroupSessionFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.SectionID != 0 {
+		query = query.Where("section_id = ?", filter.SectionID)
+	}
+	if len(filter.EmployeeIDs) > 0 {
+		query = query.Where("employee_id IN ?", filter.EmployeeIDs)
+	}
+	if filter.From != nil {
+		query = query.Where("start_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("start_at < ?", *filter.To)
+	}
+	return query
+}
@@ -8,6 +8,8 @@ import (
 
 type cachedUser struct {
     user      *models.User
+    // Email на момент добавления: объект пользователя могут изменить снаружи
+    email     string
     expiresAt time.Time
 }
 
@@ -50,7 +52,7 @@ func (c *cachedData) clearExpired() {
     for id, item := range c.usersById {
         if now.After(item.expiresAt) {
             delete(c.usersById, id)
-            delete(c.usersByEmail, item.user.Email)
+            delete(c.usersByEmail, item.email)
         }
     }
 }
@@ -86,12 +88,13 @@ func (c *cachedData) add(user *models.User) {
     
     cached := &cachedUser{
         user:      user,
+        email:     user.Email,
         expiresAt: time.Now().Add(c.ttl),
     }
     
     // Удаляем старые записи если они есть
     if old, ok := c.usersById[user.ID]; ok {
-        delete(c.usersByEmail, old.user.Email)
+        delete(c.usersByEmail, old.email)
     }
     
     c.usersById[user.ID] = cached
@@ -105,6 +108,6 @@ func (c *cachedData) delete(id uint) {
     
     if item, ok := c.usersById[id]; ok {
         delete(c.usersById, id)
-        delete(c.usersByEmail, item.user.Email)
+        delete(c.usersByEmail, item.email)
     }
 }
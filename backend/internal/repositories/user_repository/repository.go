@@ -4,10 +4,13 @@ import (
 	"errors"
 	"record-services/internal/models"
 	"record-services/pkg/consts"
+	"record-services/pkg/types"
+	"slices"
 	"time"
 
 	"github.com/rs/zerolog"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
@@ -15,24 +18,46 @@ type UserRepository interface {
 	GetByIdWithOutPassword(id uint) (*models.UserResponse, error)
 	GetByEmail(email string) (*models.User, error)
 	GetByEmailWithOutPassword(email string) (*models.UserResponse, error)
-	Create(user *models.User) (*models.User, error)
+	Create(user *models.User, roles ...consts.RoleName) (*models.User, error)
 	Update(user *models.User) (*models.User, error)
 	Delete(id uint) error
+	Restore(id uint) error
 	GetAll(limit, offset int, name string) ([]models.User, error)
-	GetAllWithPagination(limit, page int, name string) (*models.PaginatedUsers, error)
+	GetAllWithPagination(limit, page int, filter models.UserFilter) (*models.PaginatedUsers, error)
+	SetRoles(userID uint, roles []consts.RoleName) error
+	// Привязывает пользователя к организации владельца, nil - отвязывает.
+	// consts.ErrBadData - владельца нет или он сам работает в другой организации,
+	// consts.ErrConflict - у пользователя есть свои сотрудники
+	SetOwner(userID uint, ownerID *uint) error
+	// Запоминает использованный шаг TOTP, если он новее сохраненного.
+	// false - шаг уже использован
+	UseTOTPStep(userID uint, step int64) (bool, error)
+	// Атомарно увеличивает счетчик неудачных входов и возвращает новое значение
+	IncrementFailedLogins(userID uint) (int, error)
+	// Блокирует вход до until, если с момента подсчета attempts попыток
+	// счетчик не сбросили успешным входом
+	LockLogin(userID uint, attempts int, until time.Time) error
+	ResetFailedLogins(userID uint) error
+}
+
+// Отзывает выданные пользователю токены при его деактивации
+type SessionRevoker interface {
+	RevokeUser(userID uint) error
 }
 
 type userRepository struct {
-	db     *gorm.DB
-	logger *zerolog.Logger
-	cache  *cachedData
+	db      *gorm.DB
+	logger  *zerolog.Logger
+	cache   *cachedData
+	revoker SessionRevoker
 }
 
-func NewUserRepository(db *gorm.DB, logger *zerolog.Logger) UserRepository {
+func NewUserRepository(db *gorm.DB, logger *zerolog.Logger, revoker SessionRevoker) UserRepository {
 	return &userRepository{
-		db:     db,
-		logger: logger,
-		cache:  newCachedData(5 * time.Minute),
+		db:      db,
+		logger:  logger,
+		cache:   newCachedData(5 * time.Minute),
+		revoker: revoker,
 	}
 }
 
@@ -45,7 +70,7 @@ func (r *userRepository) GetById(id uint) (*models.User, error) {
 	}
 	// Если нет в кеше, получаем из БД
 	user = &models.User{}
-	result := r.db.First(user, id)
+	result := r.db.Preload("Roles.Permissions").First(user, id)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -62,16 +87,11 @@ func (r *userRepository) GetById(id uint) (*models.User, error) {
 
 func (r *userRepository) GetByIdWithOutPassword(id uint) (*models.UserResponse, error) {
 	user, err := r.GetById(id)
-	if err != nil {
+	if err != nil || user == nil {
 		return nil, err
 	}
-	return &models.UserResponse{
-		ID:       user.ID,
-		Name:     user.Name,
-		Email:    user.Email,
-		IsActive: user.IsActive,
-		IsAdmin:  user.IsAdmin,
-	}, nil
+	response := user.ToResponse()
+	return &response, nil
 }
 
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
@@ -83,7 +103,7 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	}
 	
 	user = &models.User{}
-	result := r.db.First(user, "email = ?", email)
+	result := r.db.Preload("Roles.Permissions").First(user, "email = ?", email)
 
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
@@ -101,26 +121,31 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 
 func (r *userRepository) GetByEmailWithOutPassword(email string) (*models.UserResponse, error) {
 	user, err := r.GetByEmail(email)
-	if err != nil {
+	if err != nil || user == nil {
 		return nil, err
 	}
-	return &models.UserResponse{
-		ID:       user.ID,
-		Name:     user.Name,
-		Email:    user.Email,
-		IsActive: user.IsActive,
-		IsAdmin:  user.IsAdmin,
-	}, nil
+	response := user.ToResponse()
+	return &response, nil
 }
 
-func (r *userRepository) Create(user *models.User) (*models.User, error) {
-	result := r.db.Create(user)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+func (r *userRepository) Create(user *models.User, roles ...consts.RoleName) (*models.User, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if len(roles) > 0 {
+			found, err := findRoles(tx, roles)
+			if err != nil {
+				return err
+			}
+			user.Roles = found
+		}
+		// Роли уже есть в БД, создаются только связи с ними
+		return tx.Omit("Roles.*").Create(user).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
 			return nil, consts.ErrAlreadyExists
 		}
-		r.logger.Error().Err(result.Error).Msgf("ошибка при создании пользователя: %v", user)
-		return nil, result.Error
+		r.logger.Error().Err(err).Msgf("ошибка при создании пользователя: %v", user)
+		return nil, err
 	}
 	
 	// Добавляем в кеш
@@ -130,16 +155,37 @@ func (r *userRepository) Create(user *models.User) (*models.User, error) {
 }
 
 func (r *userRepository) Update(user *models.User) (*models.User, error) {
-	result := r.db.Save(user)
+	// Прежнее состояние берем из БД: объект из кеша мог быть уже изменен вызывающим
+	var wasActive bool
+	if err := r.db.Model(&models.User{}).Select("is_active").Where("id = ?", user.ID).Scan(&wasActive).Error; err != nil {
+		r.logger.Error().Err(err).Msgf("ошибка при получении статуса пользователя: %d", user.ID)
+		return nil, err
+	}
+
+	// Роли меняются только через SetRoles
+	result := r.db.Omit(clause.Associations).Save(user)
+	// Объект мог прийти из кеша и уже изменен, сбрасываем и при ошибке
+	r.cache.delete(user.ID)
 	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return nil, consts.ErrAlreadyExists
+		}
 		r.logger.Error().Err(result.Error).Msgf("ошибка при обновлении пользователя: %v", user)
 		return nil, result.Error
 	}
-	r.cache.delete(user.ID)
+
+	// Деактивированный пользователь теряет доступ сразу, а не по истечении токенов
+	if wasActive && !user.IsActive && r.revoker != nil {
+		if err := r.revoker.RevokeUser(user.ID); err != nil {
+			r.logger.Error().Err(err).Msgf("ошибка при отзыве токенов деактивированного пользователя: %d", user.ID)
+			return nil, err
+		}
+	}
 	
 	return user, nil
 }
 
+// Мягкое удаление. Выданные токены отзываются сразу
 func (r *userRepository) Delete(id uint) error {
 	result := r.db.Delete(&models.User{}, id)
 	if result.Error != nil {
@@ -153,13 +199,104 @@ func (r *userRepository) Delete(id uint) error {
 
 	r.cache.delete(id)
 
+	if r.revoker != nil {
+		if err := r.revoker.RevokeUser(id); err != nil {
+			r.logger.Error().Err(err).Msgf("ошибка при отзыве токенов удаленного пользователя: %d", id)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Условное обновление: из параллельных входов с одним кодом шаг
+// запомнит только один, остальные получат false
+func (r *userRepository) UseTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+
+	r.cache.delete(userID)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при сохранении шага TOTP пользователя: %d", userID)
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *userRepository) IncrementFailedLogins(userID uint) (int, error) {
+	user := &models.User{}
+	user.ID = userID
+
+	result := r.db.Model(user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_attempts"}}}).
+		Update("failed_login_attempts", gorm.Expr("failed_login_attempts + 1"))
+
+	r.cache.delete(userID)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при подсчете неудачной попытки входа пользователя: %d", userID)
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, consts.ErrNotFound
+	}
+
+	return user.FailedLoginAttempts, nil
+}
+
+func (r *userRepository) LockLogin(userID uint, attempts int, until time.Time) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND failed_login_attempts = ?", userID, attempts).
+		Update("locked_until", until)
+
+	r.cache.delete(userID)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при блокировке входа пользователя: %d", userID)
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *userRepository) ResetFailedLogins(userID uint) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{"failed_login_attempts": 0, "locked_until": nil})
+
+	r.cache.delete(userID)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при сбросе неудачных попыток входа пользователя: %d", userID)
+		return result.Error
+	}
+
+	return nil
+}
+
+func (r *userRepository) Restore(id uint) error {
+	result := r.db.Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return consts.ErrAlreadyExists
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при восстановлении пользователя по id: %d", id)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+
 	return nil
 }
 
 func (r *userRepository) GetAll(limit, offset int, name string) ([]models.User, error) {
 	var users []models.User
 
-	query := r.db.Preload("User")
+	query := r.db.Model(&models.User{})
 
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -180,19 +317,13 @@ func (r *userRepository) GetAll(limit, offset int, name string) ([]models.User,
 	return users, nil
 }
 
-func (r *userRepository) GetAllWithPagination(limit, page int, name string) (*models.PaginatedUsers, error) {
+func (r *userRepository) GetAllWithPagination(limit, page int, filter models.UserFilter) (*models.PaginatedUsers, error) {
 	var users []models.User
 	var totalCount int64
 
-	countQuery := r.db.Model(&models.User{})
+	countQuery := applyUserFilter(r.db.Model(&models.User{}), filter)
 
-	dataQuery := r.db.Preload("User")
-
-	// Применяем фильтры к обоим запросам
-	if name != "" {
-		countQuery = countQuery.Where("name LIKE ?", "%"+name+"%")
-		dataQuery = dataQuery.Where("name LIKE ?", "%"+name+"%")
-	}	
+	dataQuery := applyUserFilter(r.db.Model(&models.User{}), filter)
 
 	// Получаем общее количество записей
 	if err := countQuery.Count(&totalCount).Error; err != nil {
@@ -211,6 +342,7 @@ func (r *userRepository) GetAllWithPagination(limit, page int, name string) (*mo
 
 	// Получаем данные с пагинацией
 	result := dataQuery.
+		Preload("Roles").
 		Order("name").
 		Limit(limit).
 		Offset(offset).
@@ -231,14 +363,8 @@ func (r *userRepository) GetAllWithPagination(limit, page int, name string) (*mo
 	hasMore := page < totalPages
 
 	usersResponse := make([]models.UserResponse, len(users))
-	for i, user := range users {
-		usersResponse[i] = models.UserResponse{
-			ID:       user.ID,
-			Name:     user.Name,
-			Email:    user.Email,
-			IsActive: user.IsActive,
-			IsAdmin:  user.IsAdmin,
-		}
+	for i := range users {
+		usersResponse[i] = users[i].ToResponse()
 	}
 
 	return &models.PaginatedUsers{
@@ -250,3 +376,158 @@ func (r *userRepository) GetAllWithPagination(limit, page int, name string) (*mo
 		HasMore:    hasMore,
 	}, nil
 }
+
+// Заменяет роли пользователя. Признак IsAdmin синхронизируется с ролью admin.
+// Выданные токены отзываются, чтобы новые права попали в claims при refresh
+func (r *userRepository) SetRoles(userID uint, roles []consts.RoleName) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		found, err := findRoles(tx, roles)
+		if err != nil {
+			return err
+		}
+
+		user := &models.User{}
+		user.ID = userID
+		if err := tx.Model(user).Omit("Roles.*").Association("Roles").Replace(found); err != nil {
+			return err
+		}
+
+		isAdmin := slices.Contains(roles, consts.RoleAdmin)
+		return tx.Model(user).Update("is_admin", isAdmin).Error
+	})
+	if err != nil {
+		if !errors.Is(err, consts.ErrBadData) {
+			r.logger.Error().Err(err).Msgf("ошибка при назначении ролей пользователю: %d", userID)
+		}
+		return err
+	}
+	r.cache.delete(userID)
+
+	if r.revoker != nil {
+		if err := r.revoker.RevokeUser(userID); err != nil {
+			r.logger.Error().Err(err).Msgf("ошибка при отзыве токенов пользователя после смены ролей: %d", userID)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Выданные токены отзываются, чтобы новый владелец попал в claims при refresh
+// Проверки выполняются в транзакции с блокировкой строк пользователя и
+// владельца: параллельное назначение не построит цепочку владельцев
+func (r *userRepository) SetOwner(userID uint, ownerID *uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids := []uint{userID}
+		if ownerID != nil {
+			ids = append(ids, *ownerID)
+		}
+
+		var users []models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", ids).Order("id").Find(&users).Error; err != nil {
+			return err
+		}
+
+		i := slices.IndexFunc(users, func(u models.User) bool { return u.ID == userID })
+		if i < 0 {
+			return consts.ErrNotFound
+		}
+
+		if ownerID != nil {
+			// Владелец должен существовать и сам не работать в другой организации
+			i := slices.IndexFunc(users, func(u models.User) bool { return u.ID == *ownerID })
+			if i < 0 || users[i].OwnerID != nil {
+				return consts.ErrBadData
+			}
+
+			// У пользователя не должно быть своих сотрудников
+			var staff int64
+			if err := tx.Model(&models.User{}).Where("owner_id = ?", userID).Count(&staff).Error; err != nil {
+				return err
+			}
+			if staff > 0 {
+				return consts.ErrConflict
+			}
+		}
+
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("owner_id", ownerID).Error
+	})
+
+	r.cache.delete(userID)
+	if err != nil {
+		if !errors.Is(err, consts.ErrNotFound) && !errors.Is(err, consts.ErrBadData) && !errors.Is(err, consts.ErrConflict) {
+			r.logger.Error().Err(err).Msgf("ошибка при назначении владельца пользователю: %d", userID)
+		}
+		return err
+	}
+
+	if r.revoker != nil {
+		if err := r.revoker.RevokeUser(userID); err != nil {
+			r.logger.Error().Err(err).Msgf("ошибка при отзыве токенов пользователя после смены владельца: %d", userID)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Возвращает consts.ErrBadData, если какой-то роли нет в справочнике
+func findRoles(tx *gorm.DB, names []consts.RoleName) ([]models.Role, error) {
+	roles := make([]models.Role, 0, len(names))
+	if len(names) == 0 {
+		return roles, nil
+	}
+
+	if err := tx.Where("name IN ?", names).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+
+	unique := make(map[consts.RoleName]bool, len(names))
+	for _, name := range names {
+		unique[name] = true
+	}
+	if len(roles) != len(unique) {
+		return nil, consts.ErrBadData
+	}
+
+	return roles, nil
+}
+
+// Применяет фильтры к запросу, общий для подсчета и выборки
+func applyUserFilter(query *gorm.DB, filter models.UserFilter) *gorm.DB {
+	if filter.Name != "" {
+		query = query.Where("name LIKE ?", "%"+filter.Name+"%")
+	}
+	if filter.Email != "" {
+		query = query.Where("email LIKE ?", "%"+filter.Email+"%")
+	}
+	if filter.ApprovalStatus != "" {
+		query = query.Where("approval_status = ?", filter.ApprovalStatus)
+	}
+	switch filter.EmailVerified {
+	case types.CheckValueTrue:
+		query = query.Where("email_verified_at IS NOT NULL")
+	case types.CheckValueFalse:
+		query = query.Where("email_verified_at IS NULL")
+	}
+	switch filter.IsActive {
+	case types.CheckValueTrue:
+		query = query.Where("is_active = ?", true)
+	case types.CheckValueFalse:
+		query = query.Where("is_active = ?", false)
+	}
+	switch filter.IsAdmin {
+	case types.CheckValueTrue:
+		query = query.Where("is_admin = ?", true)
+	case types.CheckValueFalse:
+		query = query.Where("is_admin = ?", false)
+	}
+	switch filter.Deleted {
+	case types.CheckValueTrue:
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	case types.CheckValueAll:
+		query = query.Unscoped()
+	}
+	return query
+}
@@ -0,0 +1,81 @@
+package user_token_repository
+
+import (
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+)
+
+type UserTokenRepository interface {
+	Create(token *models.UserToken) (*models.UserToken, error)
+	GetByHash(tokenHash string) (*models.UserToken, error)
+	MarkUsed(id uint) error
+	DeleteByUser(userID uint, purpose models.UserTokenPurpose) error
+}
+
+type userTokenRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewUserTokenRepository(db *gorm.DB, logger *zerolog.Logger) UserTokenRepository {
+	return &userTokenRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *userTokenRepository) Create(token *models.UserToken) (*models.UserToken, error) {
+	result := r.db.Create(token)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании токена %s пользователя: %d", token.Purpose, token.UserID)
+		return nil, result.Error
+	}
+	return token, nil
+}
+
+func (r *userTokenRepository) GetByHash(tokenHash string) (*models.UserToken, error) {
+	token := &models.UserToken{}
+	result := r.db.First(token, "token_hash = ?", tokenHash)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении токена пользователя")
+		return nil, result.Error
+	}
+	return token, nil
+}
+
+// Токен одноразовый: при параллельном использовании успешным будет
+// только один запрос, второй получит consts.ErrNoRowsAffected
+func (r *userTokenRepository) MarkUsed(id uint) error {
+	result := r.db.Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при использовании токена: %d", id)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return consts.ErrNoRowsAffected
+	}
+
+	return nil
+}
+
+func (r *userTokenRepository) DeleteByUser(userID uint, purpose models.UserTokenPurpose) error {
+	result := r.db.Where("user_id = ? AND purpose = ?", userID, purpose).Delete(&models.UserToken{})
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при удалении токенов %s пользователя: %d", purpose, userID)
+		return result.Error
+	}
+	return nil
+}
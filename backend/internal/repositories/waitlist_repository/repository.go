@@ -0,0 +1,364 @@
+package waitlist_repository
+
+import (
+	"context"
+	"errors"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/database"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WaitlistRepository interface {
+	GetById(ctx context.Context, id uint) (*models.WaitlistEntry, error)
+	Create(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error)
+	Update(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error)
+	GetAllWithPagination(ctx context.Context, limit, page int, filter models.WaitlistFilter) (*models.PaginatedWaitlist, error)
+	// Первая по очереди ожидающая заявка, которой подходит начало start
+	// у сотрудника. Заявки с действующим предложением и заявки, которым
+	// это время уже предлагалось, пропускаются
+	NextCandidate(ctx context.Context, ownerID, sectionID, employeeID uint, start time.Time) (*models.WaitlistEntry, error)
+	GetOffers(ctx context.Context, entryID uint) ([]models.WaitlistOffer, error)
+	CreateOffer(ctx context.Context, offer *models.WaitlistOffer) error
+	GetOfferByTokenHash(ctx context.Context, tokenHash string) (*models.WaitlistOffer, error)
+	// Закрывает действующее предложение со статусом status.
+	// consts.ErrNotFound - предложение уже не действует
+	CloseOffer(ctx context.Context, offerID uint, status models.WaitlistOfferStatus) error
+	// Создает запись по действующему предложению и закрывает предложение
+	// и заявку. consts.ErrNotFound - предложение уже не действует,
+	// consts.ErrConflict - время занято другой записью
+	Claim(ctx context.Context, offerID uint, appointment *models.Appointment) error
+	// Отменяет ожидающую заявку и переводит ее действующие предложения
+	// в expired, возвращает их. consts.ErrNotFound - заявка уже закрыта
+	Cancel(ctx context.Context, entryID uint) ([]models.WaitlistOffer, error)
+	// Переводит просроченные предложения в expired и возвращает их с заявками
+	ExpireOffers(ctx context.Context, now time.Time) ([]models.WaitlistOffer, error)
+	// Переводит в expired ожидающие заявки с прошедшим интервалом
+	ExpireEntries(ctx context.Context, now time.Time) (int64, error)
+}
+
+type waitlistRepository struct {
+	db     *gorm.DB
+	logger *zerolog.Logger
+}
+
+func NewWaitlistRepository(db *gorm.DB, logger *zerolog.Logger) WaitlistRepository {
+	return &waitlistRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *waitlistRepository) GetById(ctx context.Context, id uint) (*models.WaitlistEntry, error) {
+	entry := &models.WaitlistEntry{}
+	result := r.db.WithContext(ctx).First(entry, id)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении заявки листа ожидания по id: %d", id)
+		return nil, result.Error
+	}
+	return entry, nil
+}
+
+func (r *waitlistRepository) Create(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Create(entry)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании заявки листа ожидания на секцию: %d", entry.SectionID)
+		return nil, result.Error
+	}
+	return entry, nil
+}
+
+func (r *waitlistRepository) Update(ctx context.Context, entry *models.WaitlistEntry) (*models.WaitlistEntry, error) {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Save(entry)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при обновлении заявки листа ожидания: %d", entry.ID)
+		return nil, result.Error
+	}
+	return entry, nil
+}
+
+func (r *waitlistRepository) GetAllWithPagination(ctx context.Context, limit, page int, filter models.WaitlistFilter) (*models.PaginatedWaitlist, error) {
+	var entries []models.WaitlistEntry
+	var totalCount int64
+
+	db := r.db.WithContext(ctx)
+
+	if err := applyWaitlistFilter(db.Model(&models.WaitlistEntry{}), filter).Count(&totalCount).Error; err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при подсчете заявок листа ожидания")
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = 30
+	}
+	if page <= 0 {
+		page = 1
+	}
+	offset := (page - 1) * limit
+
+	result := applyWaitlistFilter(db.Model(&models.WaitlistEntry{}), filter).
+		Order("created_at, id").
+		Limit(limit).
+		Offset(offset).
+		Find(&entries)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении заявок листа ожидания")
+		return nil, result.Error
+	}
+
+	totalPages := int(totalCount) / limit
+	if int(totalCount)%limit > 0 {
+		totalPages++
+	}
+
+	entriesResponse := make([]models.WaitlistEntryResponse, len(entries))
+	for i := range entries {
+		entriesResponse[i] = entries[i].ToResponse()
+	}
+
+	return &models.PaginatedWaitlist{
+		Entries:    entriesResponse,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+		Page:       page,
+		Limit:      limit,
+		HasMore:    page < totalPages,
+	}, nil
+}
+
+func (r *waitlistRepository) NextCandidate(ctx context.Context, ownerID, sectionID, employeeID uint, start time.Time) (*models.WaitlistEntry, error) {
+	entry := &models.WaitlistEntry{}
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND section_id = ? AND status = ? AND (employee_id IS NULL OR employee_id = ?)",
+			ownerID, sectionID, models.WaitlistStatusWaiting, employeeID).
+		Where("window_start <= ? AND window_end > ?", start, start).
+		Where(`NOT EXISTS (SELECT 1 FROM waitlist_offers o WHERE o.entry_id = waitlist_entries.id
+			AND o.deleted_at IS NULL AND (o.status = ? OR (o.employee_id = ? AND o.start_at = ?)))`,
+			models.WaitlistOfferPending, employeeID, start).
+		Order("created_at, id").
+		First(entry)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msgf("ошибка при поиске заявки листа ожидания на секцию: %d", sectionID)
+		return nil, result.Error
+	}
+	return entry, nil
+}
+
+func (r *waitlistRepository) GetOffers(ctx context.Context, entryID uint) ([]models.WaitlistOffer, error) {
+	var offers []models.WaitlistOffer
+	result := r.db.WithContext(ctx).
+		Where("entry_id = ?", entryID).
+		Order("created_at").
+		Find(&offers)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при получении предложений заявки: %d", entryID)
+		return nil, result.Error
+	}
+	return offers, nil
+}
+
+func (r *waitlistRepository) CreateOffer(ctx context.Context, offer *models.WaitlistOffer) error {
+	result := r.db.WithContext(ctx).Omit(clause.Associations).Create(offer)
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при создании предложения заявке: %d", offer.EntryID)
+		return result.Error
+	}
+	return nil
+}
+
+func (r *waitlistRepository) GetOfferByTokenHash(ctx context.Context, tokenHash string) (*models.WaitlistOffer, error) {
+	offer := &models.WaitlistOffer{}
+	result := r.db.WithContext(ctx).
+		Preload("Entry").
+		Preload("Entry.Section.Resources").
+		Preload("Employee").
+		Where("token_hash = ?", tokenHash).
+		First(offer)
+
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error().Err(result.Error).Msg("ошибка при получении предложения по токену")
+		return nil, result.Error
+	}
+	return offer, nil
+}
+
+func (r *waitlistRepository) CloseOffer(ctx context.Context, offerID uint, status models.WaitlistOfferStatus) error {
+	result := r.db.WithContext(ctx).Model(&models.WaitlistOffer{}).
+		Where("id = ? AND status = ?", offerID, models.WaitlistOfferPending).
+		Update("status", status)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msgf("ошибка при закрытии предложения: %d", offerID)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return consts.ErrNotFound
+	}
+	return nil
+}
+
+func (r *waitlistRepository) Claim(ctx context.Context, offerID uint, appointment *models.Appointment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		offer := &models.WaitlistOffer{}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ? AND expires_at > ?", offerID, models.WaitlistOfferPending, time.Now()).
+			First(offer).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return consts.ErrNotFound
+			}
+			return err
+		}
+
+		if err := tx.Omit(clause.Associations).Create(appointment).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(offer).Update("status", models.WaitlistOfferClaimed).Error; err != nil {
+			return err
+		}
+
+		// Отмененная заявка не может воспользоваться предложением
+		result := tx.Model(&models.WaitlistEntry{}).
+			Where("id = ? AND status = ?", offer.EntryID, models.WaitlistStatusWaiting).
+			Updates(map[string]any{
+				"status":         models.WaitlistStatusBooked,
+				"appointment_id": appointment.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return consts.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if database.IsExclusionViolation(err, models.AppointmentExclusionConstraints...) {
+			return consts.ErrConflict
+		}
+		if !errors.Is(err, consts.ErrNotFound) {
+			r.logger.Error().Err(err).Msgf("ошибка при записи по предложению: %d", offerID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *waitlistRepository) Cancel(ctx context.Context, entryID uint) ([]models.WaitlistOffer, error) {
+	var offers []models.WaitlistOffer
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.WaitlistEntry{}).
+			Where("id = ? AND status = ?", entryID, models.WaitlistStatusWaiting).
+			Update("status", models.WaitlistStatusCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return consts.ErrNotFound
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("entry_id = ? AND status = ?", entryID, models.WaitlistOfferPending).
+			Find(&offers).Error; err != nil {
+			return err
+		}
+		if len(offers) == 0 {
+			return nil
+		}
+
+		return tx.Model(&models.WaitlistOffer{}).Where("id IN ?", offerIDs(offers)).
+			Update("status", models.WaitlistOfferExpired).Error
+	})
+	if err != nil {
+		if !errors.Is(err, consts.ErrNotFound) {
+			r.logger.Error().Err(err).Msgf("ошибка при отмене заявки листа ожидания: %d", entryID)
+		}
+		return nil, err
+	}
+	return offers, nil
+}
+
+func (r *waitlistRepository) ExpireOffers(ctx context.Context, now time.Time) ([]models.WaitlistOffer, error) {
+	var offers []models.WaitlistOffer
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND expires_at <= ?", models.WaitlistOfferPending, now).
+			Find(&offers).Error; err != nil {
+			return err
+		}
+		if len(offers) == 0 {
+			return nil
+		}
+
+		return tx.Model(&models.WaitlistOffer{}).Where("id IN ?", offerIDs(offers)).
+			Update("status", models.WaitlistOfferExpired).Error
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Msg("ошибка при закрытии просроченных предложений")
+		return nil, err
+	}
+
+	// Заявки нужны, чтобы предложить время следующей по очереди
+	if len(offers) > 0 {
+		if err := r.db.WithContext(ctx).Preload("Entry").Find(&offers, offerIDs(offers)).Error; err != nil {
+			r.logger.Error().Err(err).Msg("ошибка при получении просроченных предложений")
+			return nil, err
+		}
+	}
+	return offers, nil
+}
+
+func (r *waitlistRepository) ExpireEntries(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.WaitlistEntry{}).
+		Where("status = ? AND window_end <= ?", models.WaitlistStatusWaiting, now).
+		Update("status", models.WaitlistStatusExpired)
+
+	if result.Error != nil {
+		r.logger.Error().Err(result.Error).Msg("ошибка при закрытии прошедших заявок листа ожидания")
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+func offerIDs(offers []models.WaitlistOffer) []uint {
+	ids := make([]uint, len(offers))
+	for i, offer := range offers {
+		ids[i] = offer.ID
+	}
+	return ids
+}
+
+func applyWaitlistFilter(query *gorm.DB, filter models.WaitlistFilter) *gorm.DB {
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.SectionID != 0 {
+		query = query.Where("section_id = ?", filter.SectionID)
+	}
+	if filter.EmployeeID != 0 {
+		query = query.Where("employee_id = ?", filter.EmployeeID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	return query
+}
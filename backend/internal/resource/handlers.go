@@ -0,0 +1,163 @@
+package resource
+
+import (
+	"errors"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/repositories/resource_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+// Ресурсы настраиваются вместе с услугами, поэтому доступ к ним
+// определяют права на секции
+type ResourceHandlers struct {
+	mux        *http.ServeMux
+	logger     *zerolog.Logger
+	repository resource_repository.ResourceRepository
+	validator  *validator.Validate
+}
+
+func NewResourceHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository resource_repository.ResourceRepository, validator *validator.Validate) *ResourceHandlers {
+	resourceHandlers := &ResourceHandlers{
+		mux:        mux,
+		logger:     logger,
+		repository: repository,
+		validator:  validator,
+	}
+
+	read := middleware.RequirePermission(consts.PermSectionsRead)
+	write := middleware.RequirePermission(consts.PermSectionsWrite)
+
+	resourceHandlers.mux.Handle("GET /api/resources", read(http.HandlerFunc(resourceHandlers.list)))
+	resourceHandlers.mux.Handle("GET /api/resources/{id}", read(http.HandlerFunc(resourceHandlers.get)))
+	resourceHandlers.mux.Handle("POST /api/resources", write(http.HandlerFunc(resourceHandlers.create)))
+	resourceHandlers.mux.Handle("PUT /api/resources/{id}", write(http.HandlerFunc(resourceHandlers.update)))
+	resourceHandlers.mux.Handle("DELETE /api/resources/{id}", write(http.HandlerFunc(resourceHandlers.delete)))
+
+	return resourceHandlers
+}
+
+type resourceData struct {
+	Name    string `json:"name" validate:"required,min=1,max=255"`
+	Comment string `json:"comment" validate:"max=5000"`
+}
+
+func (h *ResourceHandlers) list(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	resources, err := h.repository.GetAllWithPagination(r.Context(),
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.ResourceFilter{
+			UserID: claims.OwnerScope(),
+			Name:   r.URL.Query().Get("name"),
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении ресурсов", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, resources)
+}
+
+func (h *ResourceHandlers) get(w http.ResponseWriter, r *http.Request) {
+	resource, ok := h.getResource(w, r)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, resource.ToResponse())
+}
+
+func (h *ResourceHandlers) create(w http.ResponseWriter, r *http.Request) {
+	var data resourceData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	resource := &models.Resource{UserID: claims.Owner()}
+	applyData(resource, &data)
+
+	if _, err := h.repository.Create(r.Context(), resource); err != nil {
+		httputils.SendError(w, "Ошибка при создании ресурса", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONWithStatus(w, resource.ToResponse(), http.StatusCreated)
+}
+
+func (h *ResourceHandlers) update(w http.ResponseWriter, r *http.Request) {
+	var data resourceData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	resource, ok := h.getResource(w, r)
+	if !ok {
+		return
+	}
+
+	applyData(resource, &data)
+
+	if _, err := h.repository.Update(r.Context(), resource); err != nil {
+		httputils.SendError(w, "Ошибка при обновлении ресурса", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, resource.ToResponse())
+}
+
+func (h *ResourceHandlers) delete(w http.ResponseWriter, r *http.Request) {
+	resource, ok := h.getResource(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repository.Delete(r.Context(), resource.ID); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Ресурс не найден", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при удалении ресурса", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Ресурс по {id} из пути, доступный текущему пользователю.
+// Чужие ресурсы неотличимы от несуществующих. При ошибке сам отправляет ответ
+func (h *ResourceHandlers) getResource(w http.ResponseWriter, r *http.Request) (*models.Resource, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	resource, err := h.repository.GetById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении ресурса", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if resource == nil || !claims.CanAccess(resource.UserID) {
+		httputils.SendError(w, "Ресурс не найден", http.StatusNotFound)
+		return nil, false
+	}
+
+	return resource, true
+}
+
+func applyData(resource *models.Resource, data *resourceData) {
+	resource.Name = data.Name
+	resource.Comment = data.Comment
+}
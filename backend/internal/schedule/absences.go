@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"errors"
+	"net/http"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"time"
+)
+
+// Отсутствия сотрудника, пересекающиеся с периодом from..to (YYYY-MM-DD)
+// в его часовом поясе, по умолчанию - ближайшие 30 дней
+func (h *ScheduleHandlers) listAbsences(w http.ResponseWriter, r *http.Request) {
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	loc, ok := h.employeeLocation(w, r, employee)
+	if !ok {
+		return
+	}
+
+	from, err := httputils.QueryDate(r, "from", loc)
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата from", http.StatusBadRequest)
+		return
+	}
+	if from == nil {
+		today := today(loc)
+		from = &today
+	}
+
+	to, err := httputils.QueryDate(r, "to", loc)
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата to", http.StatusBadRequest)
+		return
+	}
+	if to == nil {
+		end := from.AddDate(0, 0, 30)
+		to = &end
+	}
+
+	if to.Before(*from) || to.After(from.AddDate(0, 0, maxOverridesRangeDays)) {
+		httputils.SendError(w, "Некорректный период", http.StatusBadRequest)
+		return
+	}
+
+	absences, err := h.absences.GetByEmployee(r.Context(), employee.ID, *from, to.AddDate(0, 0, 1))
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении отсутствий", http.StatusInternalServerError)
+		return
+	}
+
+	responses := make([]models.AbsenceResponse, len(absences))
+	for i := range absences {
+		responses[i] = absences[i].ToResponse()
+	}
+
+	httputils.SendJSONResponse(w, responses)
+}
+
+func (h *ScheduleHandlers) createAbsence(w http.ResponseWriter, r *http.Request) {
+	var absenceData struct {
+		Kind    models.AbsenceKind `json:"kind" validate:"required"`
+		StartAt time.Time          `json:"startAt" validate:"required"`
+		EndAt   time.Time          `json:"endAt" validate:"required,gtfield=StartAt"`
+		Comment string             `json:"comment" validate:"max=1000"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &absenceData) {
+		return
+	}
+
+	if !absenceData.Kind.IsValid() {
+		httputils.SendError(w, "Некорректный тип отсутствия", http.StatusBadRequest)
+		return
+	}
+
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	absence, err := h.absences.Create(r.Context(), &models.Absence{
+		EmployeeID: employee.ID,
+		Kind:       absenceData.Kind,
+		StartAt:    absenceData.StartAt.UTC(),
+		EndAt:      absenceData.EndAt.UTC(),
+		Comment:    absenceData.Comment,
+	})
+	if err != nil {
+		httputils.SendError(w, "Ошибка при создании отсутствия", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONWithStatus(w, absence.ToResponse(), http.StatusCreated)
+}
+
+func (h *ScheduleHandlers) deleteAbsence(w http.ResponseWriter, r *http.Request) {
+	absenceID, err := httputils.PathID(r, "absenceId")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return
+	}
+
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	absence, err := h.absences.GetById(r.Context(), absenceID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении отсутствия", http.StatusInternalServerError)
+		return
+	}
+	if absence == nil || absence.EmployeeID != employee.ID {
+		httputils.SendError(w, "Отсутствие не найдено", http.StatusNotFound)
+		return
+	}
+
+	if err := h.absences.Delete(r.Context(), absence.ID); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Отсутствие не найдено", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при удалении отсутствия", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,288 @@
+package schedule
+
+import (
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/repositories/absence_repository"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/schedule_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/timezone"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+const maxOverridesRangeDays = 366
+
+type ScheduleHandlers struct {
+	mux        *http.ServeMux
+	logger     *zerolog.Logger
+	repository schedule_repository.ScheduleRepository
+	absences   absence_repository.AbsenceRepository
+	employees  employee_repository.EmployeeRepository
+	validator  *validator.Validate
+}
+
+func NewScheduleHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository schedule_repository.ScheduleRepository, absences absence_repository.AbsenceRepository, employees employee_repository.EmployeeRepository, validator *validator.Validate) *ScheduleHandlers {
+	scheduleHandlers := &ScheduleHandlers{
+		mux:        mux,
+		logger:     logger,
+		repository: repository,
+		absences:   absences,
+		employees:  employees,
+		validator:  validator,
+	}
+
+	read := middleware.RequirePermission(consts.PermEmployeesRead)
+	write := middleware.RequirePermission(consts.PermEmployeesWrite)
+
+	scheduleHandlers.mux.Handle("GET /api/employees/{id}/schedule", read(http.HandlerFunc(scheduleHandlers.getWeekly)))
+	scheduleHandlers.mux.Handle("PUT /api/employees/{id}/schedule", write(http.HandlerFunc(scheduleHandlers.replaceWeekly)))
+	scheduleHandlers.mux.Handle("GET /api/employees/{id}/schedule/overrides", read(http.HandlerFunc(scheduleHandlers.listOverrides)))
+	scheduleHandlers.mux.Handle("PUT /api/employees/{id}/schedule/overrides/{date}", write(http.HandlerFunc(scheduleHandlers.replaceOverride)))
+	scheduleHandlers.mux.Handle("DELETE /api/employees/{id}/schedule/overrides/{date}", write(http.HandlerFunc(scheduleHandlers.deleteOverride)))
+	scheduleHandlers.mux.Handle("GET /api/employees/{id}/absences", read(http.HandlerFunc(scheduleHandlers.listAbsences)))
+	scheduleHandlers.mux.Handle("POST /api/employees/{id}/absences", write(http.HandlerFunc(scheduleHandlers.createAbsence)))
+	scheduleHandlers.mux.Handle("DELETE /api/employees/{id}/absences/{absenceId}", write(http.HandlerFunc(scheduleHandlers.deleteAbsence)))
+
+	return scheduleHandlers
+}
+
+type intervalData struct {
+	Start   models.ClockTime `json:"start" validate:"min=0,max=1440"`
+	End     models.ClockTime `json:"end" validate:"min=0,max=1440,gtfield=Start"`
+	IsBreak bool             `json:"isBreak"`
+}
+
+func (h *ScheduleHandlers) getWeekly(w http.ResponseWriter, r *http.Request) {
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.repository.GetWeekly(r.Context(), employee.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении графика", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, toWeeklyResponses(entries))
+}
+
+// Заменяет недельный график целиком
+func (h *ScheduleHandlers) replaceWeekly(w http.ResponseWriter, r *http.Request) {
+	var scheduleData struct {
+		Intervals []struct {
+			Weekday time.Weekday `json:"weekday" validate:"min=0,max=6"`
+			intervalData
+		} `json:"intervals" validate:"max=100,dive"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &scheduleData) {
+		return
+	}
+
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	entries := make([]models.WeeklySchedule, len(scheduleData.Intervals))
+	for i, interval := range scheduleData.Intervals {
+		entries[i] = models.WeeklySchedule{
+			Weekday: interval.Weekday,
+			Start:   interval.Start,
+			End:     interval.End,
+			IsBreak: interval.IsBreak,
+		}
+	}
+
+	if err := h.repository.ReplaceWeekly(r.Context(), employee.ID, entries); err != nil {
+		httputils.SendError(w, "Ошибка при сохранении графика", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, toWeeklyResponses(entries))
+}
+
+// Исключения графика за период from..to (YYYY-MM-DD), по умолчанию - ближайшие 30 дней
+func (h *ScheduleHandlers) listOverrides(w http.ResponseWriter, r *http.Request) {
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	from, err := httputils.QueryDate(r, "from", time.UTC)
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата from", http.StatusBadRequest)
+		return
+	}
+	if from == nil {
+		loc, ok := h.employeeLocation(w, r, employee)
+		if !ok {
+			return
+		}
+		// Даты исключений хранятся полночью UTC, сегодня - по часам сотрудника
+		local := today(loc)
+		date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		from = &date
+	}
+
+	to, err := httputils.QueryDate(r, "to", time.UTC)
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата to", http.StatusBadRequest)
+		return
+	}
+	if to == nil {
+		end := from.AddDate(0, 0, 30)
+		to = &end
+	}
+
+	if to.Before(*from) || to.After(from.AddDate(0, 0, maxOverridesRangeDays)) {
+		httputils.SendError(w, "Некорректный период", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.repository.GetOverrides(r.Context(), employee.ID, *from, *to)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении исключений графика", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, toOverrideResponses(entries))
+}
+
+// График на дату: выходной или собственный набор интервалов
+func (h *ScheduleHandlers) replaceOverride(w http.ResponseWriter, r *http.Request) {
+	var overrideData struct {
+		DayOff    bool           `json:"dayOff"`
+		Intervals []intervalData `json:"intervals" validate:"max=50,dive"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &overrideData) {
+		return
+	}
+
+	date, ok := pathDate(w, r)
+	if !ok {
+		return
+	}
+
+	if !overrideData.DayOff && len(overrideData.Intervals) == 0 {
+		httputils.SendError(w, "Укажите интервалы или выходной день", http.StatusBadRequest)
+		return
+	}
+
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	var entries []models.ScheduleOverride
+	if overrideData.DayOff {
+		entries = []models.ScheduleOverride{{IsDayOff: true}}
+	} else {
+		for _, interval := range overrideData.Intervals {
+			entries = append(entries, models.ScheduleOverride{
+				Start:   interval.Start,
+				End:     interval.End,
+				IsBreak: interval.IsBreak,
+			})
+		}
+	}
+
+	if err := h.repository.ReplaceOverride(r.Context(), employee.ID, date, entries); err != nil {
+		httputils.SendError(w, "Ошибка при сохранении исключения графика", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, toOverrideResponses(entries))
+}
+
+func toWeeklyResponses(entries []models.WeeklySchedule) []models.WeeklyScheduleResponse {
+	responses := make([]models.WeeklyScheduleResponse, len(entries))
+	for i := range entries {
+		responses[i] = entries[i].ToResponse()
+	}
+	return responses
+}
+
+func toOverrideResponses(entries []models.ScheduleOverride) []models.ScheduleOverrideResponse {
+	responses := make([]models.ScheduleOverrideResponse, len(entries))
+	for i := range entries {
+		responses[i] = entries[i].ToResponse()
+	}
+	return responses
+}
+
+func (h *ScheduleHandlers) deleteOverride(w http.ResponseWriter, r *http.Request) {
+	date, ok := pathDate(w, r)
+	if !ok {
+		return
+	}
+
+	employee, ok := h.getEmployee(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repository.DeleteOverride(r.Context(), employee.ID, date); err != nil {
+		httputils.SendError(w, "Ошибка при удалении исключения графика", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Сотрудник по {id} из пути, доступный текущему пользователю. При ошибке сам отправляет ответ
+func (h *ScheduleHandlers) getEmployee(w http.ResponseWriter, r *http.Request) (*models.Employee, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	employee, err := h.employees.GetById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении сотрудника", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if employee == nil || !claims.CanAccess(employee.UserID) {
+		httputils.SendError(w, "Сотрудник не найден", http.StatusNotFound)
+		return nil, false
+	}
+
+	return employee, true
+}
+
+// Часовой пояс графика сотрудника. При ошибке сам отправляет ответ
+func (h *ScheduleHandlers) employeeLocation(w http.ResponseWriter, r *http.Request, employee *models.Employee) (*time.Location, bool) {
+	name, err := h.employees.GetTimeZone(r.Context(), employee.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении часового пояса сотрудника", http.StatusInternalServerError)
+		return nil, false
+	}
+	return timezone.LoadOrUTC(name), true
+}
+
+// Полночь текущего дня в часовом поясе loc
+func today(loc *time.Location) time.Time {
+	year, month, day := time.Now().In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// Дата {date} из пути в формате YYYY-MM-DD. При ошибке сам отправляет ответ
+func pathDate(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	date, err := time.Parse(time.DateOnly, r.PathValue("date"))
+	if err != nil {
+		httputils.SendError(w, "Некорректная дата", http.StatusBadRequest)
+		return time.Time{}, false
+	}
+	return date, true
+}
@@ -0,0 +1,225 @@
+package section
+
+import (
+	"errors"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/repositories/section_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+type SectionHandlers struct {
+	mux        *http.ServeMux
+	logger     *zerolog.Logger
+	repository section_repository.SectionRepository
+	validator  *validator.Validate
+}
+
+func NewSectionHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository section_repository.SectionRepository, validator *validator.Validate) *SectionHandlers {
+	sectionHandlers := &SectionHandlers{
+		mux:        mux,
+		logger:     logger,
+		repository: repository,
+		validator:  validator,
+	}
+
+	read := middleware.RequirePermission(consts.PermSectionsRead)
+	write := middleware.RequirePermission(consts.PermSectionsWrite)
+
+	sectionHandlers.mux.Handle("GET /api/sections", read(http.HandlerFunc(sectionHandlers.list)))
+	sectionHandlers.mux.Handle("GET /api/sections/{id}", read(http.HandlerFunc(sectionHandlers.get)))
+	sectionHandlers.mux.Handle("POST /api/sections", write(http.HandlerFunc(sectionHandlers.create)))
+	sectionHandlers.mux.Handle("PUT /api/sections/{id}", write(http.HandlerFunc(sectionHandlers.update)))
+	sectionHandlers.mux.Handle("DELETE /api/sections/{id}", write(http.HandlerFunc(sectionHandlers.delete)))
+	sectionHandlers.mux.Handle("PUT /api/sections/{id}/resources", write(http.HandlerFunc(sectionHandlers.setResources)))
+
+	return sectionHandlers
+}
+
+// Незаданные длительность, валюта и вместимость получают значения по умолчанию
+type sectionData struct {
+	Name                string `json:"name" validate:"required,min=1,max=255"`
+	Comment             string `json:"comment" validate:"max=5000"`
+	DurationMinutes     int    `json:"durationMinutes" validate:"omitempty,min=5,max=720"`
+	Price               int64  `json:"price" validate:"min=0"`
+	Currency            string `json:"currency" validate:"omitempty,len=3,uppercase"`
+	BufferBeforeMinutes int    `json:"bufferBeforeMinutes" validate:"min=0,max=240"`
+	BufferAfterMinutes  int    `json:"bufferAfterMinutes" validate:"min=0,max=240"`
+	MaxAdvanceDays      int    `json:"maxAdvanceDays" validate:"min=0,max=730"`
+	MinNoticeMinutes    int    `json:"minNoticeMinutes" validate:"min=0,max=43200"`
+	Capacity            int    `json:"capacity" validate:"omitempty,min=1,max=500"`
+}
+
+func (h *SectionHandlers) list(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	sections, err := h.repository.GetAllWithPagination(r.Context(),
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.SectionFilter{
+			UserID: claims.OwnerScope(),
+			Name:   r.URL.Query().Get("name"),
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении секций", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, sections)
+}
+
+func (h *SectionHandlers) get(w http.ResponseWriter, r *http.Request) {
+	section, ok := h.getSection(w, r)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, section.ToResponse())
+}
+
+func (h *SectionHandlers) create(w http.ResponseWriter, r *http.Request) {
+	var data sectionData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	section := &models.Section{UserID: claims.Owner()}
+	applyData(section, &data)
+
+	if _, err := h.repository.Create(r.Context(), section); err != nil {
+		httputils.SendError(w, "Ошибка при создании секции", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONWithStatus(w, section.ToResponse(), http.StatusCreated)
+}
+
+func (h *SectionHandlers) update(w http.ResponseWriter, r *http.Request) {
+	var data sectionData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	section, ok := h.getSection(w, r)
+	if !ok {
+		return
+	}
+
+	applyData(section, &data)
+
+	if _, err := h.repository.Update(r.Context(), section); err != nil {
+		httputils.SendError(w, "Ошибка при обновлении секции", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, section.ToResponse())
+}
+
+func (h *SectionHandlers) delete(w http.ResponseWriter, r *http.Request) {
+	section, ok := h.getSection(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repository.Delete(r.Context(), section.ID); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Секция не найдена", http.StatusNotFound)
+			return
+		}
+		httputils.SendError(w, "Ошибка при удалении секции", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, map[string]string{"status": "ok"})
+}
+
+// Ресурсы, которые занимает каждая новая запись на услугу.
+// Уже созданные записи сохраняют ресурсы на момент записи
+func (h *SectionHandlers) setResources(w http.ResponseWriter, r *http.Request) {
+	var resourcesData struct {
+		ResourceIDs []uint `json:"resourceIds" validate:"max=20,dive,gt=0"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &resourcesData) {
+		return
+	}
+
+	section, ok := h.getSection(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.repository.ReplaceResources(r.Context(), section.ID, resourcesData.ResourceIDs); err != nil {
+		switch {
+		case errors.Is(err, consts.ErrNotFound):
+			httputils.SendError(w, "Секция не найдена", http.StatusNotFound)
+		case errors.Is(err, consts.ErrBadData):
+			httputils.SendError(w, "Ресурс не найден", http.StatusBadRequest)
+		default:
+			httputils.SendError(w, "Ошибка при назначении ресурсов", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	section, err := h.repository.GetById(r.Context(), section.ID)
+	if err != nil || section == nil {
+		httputils.SendError(w, "Ошибка при получении секции", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, section.ToResponse())
+}
+
+// Секция по {id} из пути, доступная текущему пользователю.
+// Чужие секции неотличимы от несуществующих. При ошибке сам отправляет ответ
+func (h *SectionHandlers) getSection(w http.ResponseWriter, r *http.Request) (*models.Section, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	section, err := h.repository.GetById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении секции", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if section == nil || !claims.CanAccess(section.UserID) {
+		httputils.SendError(w, "Секция не найдена", http.StatusNotFound)
+		return nil, false
+	}
+
+	return section, true
+}
+
+func applyData(section *models.Section, data *sectionData) {
+	section.Name = data.Name
+	section.Comment = data.Comment
+	section.DurationMinutes = data.DurationMinutes
+	if section.DurationMinutes == 0 {
+		section.DurationMinutes = models.DefaultSectionDuration
+	}
+	section.Price = data.Price
+	section.Currency = data.Currency
+	if section.Currency == "" {
+		section.Currency = models.DefaultCurrency
+	}
+	section.BufferBeforeMinutes = data.BufferBeforeMinutes
+	section.BufferAfterMinutes = data.BufferAfterMinutes
+	section.MaxAdvanceDays = data.MaxAdvanceDays
+	section.MinNoticeMinutes = data.MinNoticeMinutes
+	section.Capacity = data.Capacity
+	if section.Capacity == 0 {
+		section.Capacity = models.DefaultSectionCapacity
+	}
+}
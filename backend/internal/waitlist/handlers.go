@@ -0,0 +1,347 @@
+package waitlist
+
+import (
+	"errors"
+	"net/http"
+	"record-services/internal/middleware"
+	"record-services/internal/models"
+	"record-services/internal/reminder"
+	"record-services/internal/repositories/booking_repository"
+	"record-services/internal/repositories/client_repository"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/section_repository"
+	"record-services/internal/repositories/waitlist_repository"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/mailer"
+	"record-services/pkg/phone"
+	"record-services/pkg/ratelimit"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog"
+)
+
+// Самый длинный интервал ожидания заявки
+const maxWindow = 60 * 24 * time.Hour
+
+type WaitlistHandlers struct {
+	mux        *http.ServeMux
+	logger     *zerolog.Logger
+	repository waitlist_repository.WaitlistRepository
+	bookings   booking_repository.BookingRepository
+	sections   section_repository.SectionRepository
+	employees  employee_repository.EmployeeRepository
+	clients    client_repository.ClientRepository
+	worker     *Worker
+	reminders  *reminder.Scheduler
+	validator  *validator.Validate
+	mailer     mailer.Mailer
+	appURL     string
+}
+
+func NewWaitlistHandlers(mux *http.ServeMux, logger *zerolog.Logger, repository waitlist_repository.WaitlistRepository, bookings booking_repository.BookingRepository, sections section_repository.SectionRepository, employees employee_repository.EmployeeRepository, clients client_repository.ClientRepository, worker *Worker, reminders *reminder.Scheduler, validator *validator.Validate, mailer mailer.Mailer, limiterStore ratelimit.Store, appURL string) *WaitlistHandlers {
+	waitlistHandlers := &WaitlistHandlers{
+		mux:        mux,
+		logger:     logger,
+		repository: repository,
+		bookings:   bookings,
+		sections:   sections,
+		employees:  employees,
+		clients:    clients,
+		worker:     worker,
+		reminders:  reminders,
+		validator:  validator,
+		mailer:     mailer,
+		appURL:     appURL,
+	}
+
+	read := middleware.RequirePermission(consts.PermAppointmentsRead)
+	write := middleware.RequirePermission(consts.PermAppointmentsWrite)
+	limits := newWaitlistLimits(limiterStore)
+
+	waitlistHandlers.mux.Handle("GET /api/waitlist", read(http.HandlerFunc(waitlistHandlers.list)))
+	waitlistHandlers.mux.Handle("GET /api/waitlist/{id}", read(http.HandlerFunc(waitlistHandlers.get)))
+	waitlistHandlers.mux.Handle("POST /api/waitlist", write(http.HandlerFunc(waitlistHandlers.create)))
+	waitlistHandlers.mux.Handle("DELETE /api/waitlist/{id}", write(http.HandlerFunc(waitlistHandlers.cancel)))
+
+	// Публичная часть, авторизация не требуется
+	waitlistHandlers.mux.Handle("POST /api/public/{slug}/waitlist", limits.join(http.HandlerFunc(waitlistHandlers.publicJoin)))
+	waitlistHandlers.mux.Handle("GET /api/public/waitlist/offers/{token}", limits.offer(http.HandlerFunc(waitlistHandlers.getOffer)))
+	waitlistHandlers.mux.Handle("POST /api/public/waitlist/offers/{token}/claim", limits.offer(http.HandlerFunc(waitlistHandlers.claimOffer)))
+	waitlistHandlers.mux.Handle("POST /api/public/waitlist/offers/{token}/decline", limits.offer(http.HandlerFunc(waitlistHandlers.declineOffer)))
+
+	return waitlistHandlers
+}
+
+// Клиент задается карточкой clientId или именем и контактами.
+// Email обязателен в запросе или в карточке: на него отправляются предложения
+type entryData struct {
+	SectionID   uint      `json:"sectionId" validate:"required"`
+	EmployeeID  *uint     `json:"employeeId" validate:"omitempty,gt=0"`
+	ClientID    *uint     `json:"clientId" validate:"omitempty,gt=0"`
+	ClientName  string    `json:"clientName" validate:"required_without=ClientID,max=255"`
+	ClientPhone string    `json:"clientPhone" validate:"max=20"`
+	ClientEmail string    `json:"clientEmail" validate:"omitempty,email,max=255"`
+	WindowStart time.Time `json:"windowStart" validate:"required"`
+	WindowEnd   time.Time `json:"windowEnd" validate:"required,gtfield=WindowStart"`
+	Comment     string    `json:"comment" validate:"max=5000"`
+}
+
+// Фильтры: section_id, employee_id, status. Заявки по очереди создания
+func (h *WaitlistHandlers) list(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetUserFromContext(r.Context())
+
+	entries, err := h.repository.GetAllWithPagination(r.Context(),
+		httputils.QueryInt(r, "limit", 0),
+		httputils.QueryInt(r, "page", 1),
+		models.WaitlistFilter{
+			UserID:     claims.OwnerScope(),
+			SectionID:  uint(max(httputils.QueryInt(r, "section_id", 0), 0)),
+			EmployeeID: uint(max(httputils.QueryInt(r, "employee_id", 0), 0)),
+			Status:     models.WaitlistStatus(r.URL.Query().Get("status")),
+		},
+	)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении листа ожидания", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONResponse(w, entries)
+}
+
+// Заявка с историей предложений
+func (h *WaitlistHandlers) get(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.getEntry(w, r)
+	if !ok {
+		return
+	}
+
+	offers, err := h.repository.GetOffers(r.Context(), entry.ID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении предложений", http.StatusInternalServerError)
+		return
+	}
+
+	response := entry.ToResponse()
+	response.Offers = make([]models.WaitlistOfferResponse, len(offers))
+	for i := range offers {
+		response.Offers[i] = offers[i].ToResponse()
+	}
+
+	httputils.SendJSONResponse(w, response)
+}
+
+func (h *WaitlistHandlers) create(w http.ResponseWriter, r *http.Request) {
+	var data entryData
+	if !httputils.DecodeAndValidate(w, r, h.validator, &data) {
+		return
+	}
+
+	if !validWindow(w, data.WindowStart, data.WindowEnd) {
+		return
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+
+	section, err := h.sections.GetById(r.Context(), data.SectionID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении секции", http.StatusInternalServerError)
+		return
+	}
+
+	if section == nil || !claims.CanAccess(section.UserID) {
+		httputils.SendError(w, "Секция не найдена", http.StatusBadRequest)
+		return
+	}
+
+	if !groupAllowed(w, section) {
+		return
+	}
+
+	if data.EmployeeID != nil && !h.checkEmployee(w, r, section, *data.EmployeeID, http.StatusBadRequest) {
+		return
+	}
+
+	entry := &models.WaitlistEntry{
+		UserID:      section.UserID,
+		SectionID:   section.ID,
+		EmployeeID:  data.EmployeeID,
+		WindowStart: data.WindowStart.UTC(),
+		WindowEnd:   data.WindowEnd.UTC(),
+		Status:      models.WaitlistStatusWaiting,
+		Comment:     data.Comment,
+	}
+
+	if !h.resolveClient(w, r, entry, &data) {
+		return
+	}
+
+	if _, err := h.repository.Create(r.Context(), entry); err != nil {
+		httputils.SendError(w, "Ошибка при добавлении в лист ожидания", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.SendJSONWithStatus(w, entry.ToResponse(), http.StatusCreated)
+}
+
+// Заявка отменяется, действующее предложение по ней больше не принимается
+func (h *WaitlistHandlers) cancel(w http.ResponseWriter, r *http.Request) {
+	entry, ok := h.getEntry(w, r)
+	if !ok {
+		return
+	}
+
+	if entry.Status != models.WaitlistStatusWaiting {
+		httputils.SendError(w, "Заявка уже закрыта", http.StatusConflict)
+		return
+	}
+
+	offers, err := h.repository.Cancel(r.Context(), entry.ID)
+	if err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Заявка уже закрыта", http.StatusConflict)
+			return
+		}
+		httputils.SendError(w, "Ошибка при отмене заявки", http.StatusInternalServerError)
+		return
+	}
+
+	// Время действующих предложений заявки предлагается следующим
+	for i := range offers {
+		offers[i].Entry = *entry
+		h.worker.Reoffer(&offers[i])
+	}
+
+	entry.Status = models.WaitlistStatusCancelled
+	httputils.SendJSONResponse(w, entry.ToResponse())
+}
+
+// Заполняет клиента заявки по карточке или контактам из data.
+// При ошибке сам отправляет ответ
+func (h *WaitlistHandlers) resolveClient(w http.ResponseWriter, r *http.Request, entry *models.WaitlistEntry, data *entryData) bool {
+	normalizedPhone, err := phone.Normalize(data.ClientPhone)
+	if err != nil {
+		httputils.SendError(w, "Некорректный номер телефона", http.StatusBadRequest)
+		return false
+	}
+
+	entry.ClientName = data.ClientName
+	entry.ClientPhone = normalizedPhone
+	entry.ClientEmail = strings.ToLower(strings.TrimSpace(data.ClientEmail))
+
+	var client *models.Client
+	switch {
+	case data.ClientID != nil:
+		client, err = h.clients.GetById(r.Context(), *data.ClientID)
+		if err != nil {
+			httputils.SendError(w, "Ошибка при получении клиента", http.StatusInternalServerError)
+			return false
+		}
+		if client == nil || client.UserID != entry.UserID {
+			httputils.SendError(w, "Клиент не найден", http.StatusBadRequest)
+			return false
+		}
+
+	case entry.ClientPhone != "" || entry.ClientEmail != "":
+		client, err = h.clients.FindOrCreate(r.Context(), &models.Client{
+			UserID: entry.UserID,
+			Name:   entry.ClientName,
+			Phone:  entry.ClientPhone,
+			Email:  entry.ClientEmail,
+		})
+		if err != nil {
+			httputils.SendError(w, "Ошибка при сохранении клиента", http.StatusInternalServerError)
+			return false
+		}
+	}
+
+	if client != nil {
+		entry.ClientID = &client.ID
+		if entry.ClientName == "" {
+			entry.ClientName = client.Name
+		}
+		if entry.ClientPhone == "" {
+			entry.ClientPhone = client.Phone
+		}
+		if entry.ClientEmail == "" {
+			entry.ClientEmail = client.Email
+		}
+	}
+
+	if entry.ClientEmail == "" {
+		httputils.SendError(w, "Для листа ожидания нужен email клиента", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
+
+// Активный сотрудник владельца секции, работающий в ней.
+// При ошибке сам отправляет ответ с кодом notFoundStatus
+func (h *WaitlistHandlers) checkEmployee(w http.ResponseWriter, r *http.Request, section *models.Section, employeeID uint, notFoundStatus int) bool {
+	employee, err := h.employees.GetById(r.Context(), employeeID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении сотрудника", http.StatusInternalServerError)
+		return false
+	}
+
+	if employee == nil || employee.UserID != section.UserID || !employee.IsActive || !employee.HasSection(section.ID) {
+		httputils.SendError(w, "Сотрудник не найден", notFoundStatus)
+		return false
+	}
+
+	return true
+}
+
+// Заявка по {id} из пути, доступная текущему пользователю.
+// При ошибке сам отправляет ответ
+func (h *WaitlistHandlers) getEntry(w http.ResponseWriter, r *http.Request) (*models.WaitlistEntry, bool) {
+	id, err := httputils.PathID(r, "id")
+	if err != nil {
+		httputils.SendError(w, "Некорректный идентификатор", http.StatusBadRequest)
+		return nil, false
+	}
+
+	entry, err := h.repository.GetById(r.Context(), id)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении заявки", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims := middleware.GetUserFromContext(r.Context())
+	if entry == nil || !claims.CanAccess(entry.UserID) {
+		httputils.SendError(w, "Заявка не найдена", http.StatusNotFound)
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// Лист ожидания ведется только для индивидуальных услуг: место
+// в групповом занятии не освобождает время сотрудника.
+// При ошибке сам отправляет ответ
+func groupAllowed(w http.ResponseWriter, section *models.Section) bool {
+	if section.IsGroup() {
+		httputils.SendError(w, "Лист ожидания для групповых услуг не ведется", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// Интервал ожидания еще не прошел и не слишком длинный.
+// При ошибке сам отправляет ответ
+func validWindow(w http.ResponseWriter, start, end time.Time) bool {
+	if !end.After(time.Now()) {
+		httputils.SendError(w, "Интервал ожидания уже прошел", http.StatusBadRequest)
+		return false
+	}
+
+	if end.Sub(start) > maxWindow {
+		httputils.SendError(w, "Слишком длинный интервал ожидания", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
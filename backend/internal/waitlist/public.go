@@ -0,0 +1,354 @@
+package waitlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"record-services/internal/models"
+	"record-services/pkg/consts"
+	"record-services/pkg/httputils"
+	"record-services/pkg/mailer"
+	"record-services/pkg/phone"
+	"record-services/pkg/timezone"
+	"record-services/pkg/types"
+	"record-services/pkg/utils"
+	"strings"
+	"time"
+)
+
+type publicEntryResponse struct {
+	Status      models.WaitlistStatus `json:"status"`
+	SectionID   uint                  `json:"section_id"`
+	EmployeeID  *uint                 `json:"employee_id"`
+	WindowStart time.Time             `json:"window_start"`
+	WindowEnd   time.Time             `json:"window_end"`
+}
+
+type offerResponse struct {
+	Status       models.WaitlistOfferStatus `json:"status"`
+	StartAt      time.Time                  `json:"start_at"`
+	EndAt        time.Time                  `json:"end_at"`
+	ExpiresAt    time.Time                  `json:"expires_at"`
+	TimeZone     string                     `json:"time_zone"`
+	SectionName  string                     `json:"section_name"`
+	EmployeeName string                     `json:"employee_name"`
+	ClientName   string                     `json:"client_name"`
+	// Только в ответе на принятие предложения: токен ссылки управления записью
+	ManageToken string `json:"manage_token,omitempty"`
+}
+
+// Время - в часовом поясе loc сотрудника
+func toOfferResponse(offer *models.WaitlistOffer, loc *time.Location) offerResponse {
+	return offerResponse{
+		Status:       offer.Status,
+		StartAt:      offer.StartAt.In(loc),
+		EndAt:        offer.EndAt.In(loc),
+		ExpiresAt:    offer.ExpiresAt.In(loc),
+		TimeZone:     loc.String(),
+		SectionName:  offer.Entry.Section.Name,
+		EmployeeName: offer.Employee.Name,
+		ClientName:   offer.Entry.ClientName,
+	}
+}
+
+// Запись в лист ожидания со страницы онлайн-записи
+func (h *WaitlistHandlers) publicJoin(w http.ResponseWriter, r *http.Request) {
+	var joinData struct {
+		SectionID        uint      `json:"sectionId" validate:"required"`
+		EmployeeID       uint      `json:"employeeId"`
+		ClientName       string    `json:"clientName" validate:"required,min=1,max=255"`
+		ClientPhone      string    `json:"clientPhone" validate:"required,max=32"`
+		ClientEmail      string    `json:"clientEmail" validate:"required,email,max=255"`
+		WindowStart      time.Time `json:"windowStart" validate:"required"`
+		WindowEnd        time.Time `json:"windowEnd" validate:"required,gtfield=WindowStart"`
+		RemindersConsent bool      `json:"remindersConsent"`
+	}
+
+	if !httputils.DecodeAndValidate(w, r, h.validator, &joinData) {
+		return
+	}
+
+	if !validWindow(w, joinData.WindowStart, joinData.WindowEnd) {
+		return
+	}
+
+	clientPhone, err := phone.Normalize(joinData.ClientPhone)
+	if err != nil {
+		httputils.SendError(w, "Некорректный номер телефона", http.StatusBadRequest)
+		return
+	}
+	clientEmail := strings.ToLower(strings.TrimSpace(joinData.ClientEmail))
+
+	page, err := h.bookings.GetBySlug(r.Context(), r.PathValue("slug"))
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении страницы записи", http.StatusInternalServerError)
+		return
+	}
+
+	if page == nil || !page.IsEnabled {
+		httputils.SendError(w, "Страница записи не найдена", http.StatusNotFound)
+		return
+	}
+
+	section, err := h.sections.GetById(r.Context(), joinData.SectionID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении услуги", http.StatusInternalServerError)
+		return
+	}
+
+	if section == nil || section.UserID != page.UserID {
+		httputils.SendError(w, "Услуга не найдена", http.StatusNotFound)
+		return
+	}
+
+	if !groupAllowed(w, section) {
+		return
+	}
+
+	var employeeID *uint
+	if joinData.EmployeeID != 0 {
+		if !h.checkEmployee(w, r, section, joinData.EmployeeID, http.StatusNotFound) {
+			return
+		}
+		employeeID = &joinData.EmployeeID
+	}
+
+	// Контакты не подтверждены: заявка привязывается только к новой карточке
+	client, err := h.clients.CreateIfUnknown(r.Context(), &models.Client{
+		UserID:           page.UserID,
+		Name:             joinData.ClientName,
+		Phone:            clientPhone,
+		Email:            clientEmail,
+		RemindersConsent: joinData.RemindersConsent,
+	})
+	if err != nil {
+		httputils.SendError(w, "Ошибка при сохранении клиента", http.StatusInternalServerError)
+		return
+	}
+
+	var clientID *uint
+	if client != nil {
+		clientID = &client.ID
+	}
+
+	entry := &models.WaitlistEntry{
+		UserID:           page.UserID,
+		SectionID:        section.ID,
+		EmployeeID:       employeeID,
+		ClientID:         clientID,
+		ClientName:       joinData.ClientName,
+		ClientPhone:      clientPhone,
+		ClientEmail:      clientEmail,
+		RemindersConsent: joinData.RemindersConsent,
+		WindowStart:      joinData.WindowStart.UTC(),
+		WindowEnd:        joinData.WindowEnd.UTC(),
+		Status:           models.WaitlistStatusWaiting,
+	}
+
+	if _, err := h.repository.Create(r.Context(), entry); err != nil {
+		httputils.SendError(w, "Ошибка при добавлении в лист ожидания", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Онлайн-заявка %d в лист ожидания на секцию %d", entry.ID, entry.SectionID)
+
+	httputils.SendJSONWithStatus(w, publicEntryResponse{
+		Status:      entry.Status,
+		SectionID:   entry.SectionID,
+		EmployeeID:  entry.EmployeeID,
+		WindowStart: entry.WindowStart,
+		WindowEnd:   entry.WindowEnd,
+	}, http.StatusCreated)
+}
+
+func (h *WaitlistHandlers) getOffer(w http.ResponseWriter, r *http.Request) {
+	offer, ok := h.resolveOffer(w, r)
+	if !ok {
+		return
+	}
+
+	httputils.SendJSONResponse(w, toOfferResponse(offer, h.employeeLocation(r.Context(), offer.EmployeeID)))
+}
+
+// Принятие предложения: создается запись в статусе pending, которой
+// клиент управляет так же, как записью со страницы онлайн-записи
+func (h *WaitlistHandlers) claimOffer(w http.ResponseWriter, r *http.Request) {
+	offer, ok := h.resolveOffer(w, r)
+	if !ok {
+		return
+	}
+
+	if !isOpen(offer) || offer.Entry.Section.ID == 0 || !offer.Employee.IsActive {
+		httputils.SendError(w, "Предложение больше не действует", http.StatusGone)
+		return
+	}
+
+	override, err := h.employees.GetSectionSettings(r.Context(), offer.EmployeeID, offer.Entry.SectionID)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении параметров услуги", http.StatusInternalServerError)
+		return
+	}
+
+	if override == nil {
+		httputils.SendError(w, "Предложение больше не действует", http.StatusGone)
+		return
+	}
+
+	// Пока предложение ждало ответа, время могли закрыть отсутствием или
+	// праздником, а рядом могли появиться записи, чьи буферы его задевают,
+	// и записи с теми же ресурсами. Ограничение БД сравнивает только время
+	// записей сотрудника, поэтому проверяем все это заново
+	entry := &offer.Entry
+	terms := entry.Section.Terms(override)
+	free, err := h.worker.isFree(r.Context(), entry.UserID, offer.EmployeeID, offer.StartAt, offer.EndAt, terms)
+	if err != nil {
+		httputils.SendError(w, "Ошибка при проверке занятости сотрудника", http.StatusInternalServerError)
+		return
+	}
+	if !free {
+		h.closeTaken(r, offer)
+		httputils.SendError(w, "Это время уже занято", http.StatusConflict)
+		return
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("Ошибка при генерации токена управления записью")
+		httputils.SendError(w, "Ошибка при создании записи", http.StatusInternalServerError)
+		return
+	}
+	tokenHash := utils.HashToken(token)
+
+	loc := h.employeeLocation(r.Context(), offer.EmployeeID)
+	appointment := &models.Appointment{
+		UserID:              entry.UserID,
+		EmployeeID:          offer.EmployeeID,
+		SectionID:           entry.SectionID,
+		ClientID:            entry.ClientID,
+		ClientName:          entry.ClientName,
+		ClientPhone:         entry.ClientPhone,
+		ClientEmail:         entry.ClientEmail,
+		RemindersConsent:    entry.RemindersConsent,
+		StartAt:             offer.StartAt,
+		EndAt:               offer.EndAt,
+		Status:              models.AppointmentStatusPending,
+		Comment:             entry.Comment,
+		TimeZone:            loc.String(),
+		Price:               terms.Price,
+		Currency:            terms.Currency,
+		BufferBeforeMinutes: int(terms.BufferBefore / time.Minute),
+		BufferAfterMinutes:  int(terms.BufferAfter / time.Minute),
+		ResourceIDs:         types.IDList(terms.Resources),
+		ManageTokenHash:     &tokenHash,
+	}
+
+	err = h.repository.Claim(r.Context(), offer.ID, appointment)
+	switch {
+	case errors.Is(err, consts.ErrNotFound):
+		httputils.SendError(w, "Предложение больше не действует", http.StatusGone)
+		return
+	case errors.Is(err, consts.ErrConflict):
+		h.closeTaken(r, offer)
+		httputils.SendError(w, "Это время уже занято", http.StatusConflict)
+		return
+	case err != nil:
+		httputils.SendError(w, "Ошибка при создании записи", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info().Msgf("Заявка %d записана по предложению %d, запись %d", entry.ID, offer.ID, appointment.ID)
+
+	h.reminders.Schedule(r.Context(), appointment)
+	h.sendManageLink(r, offer, loc, token)
+
+	offer.Status = models.WaitlistOfferClaimed
+	response := toOfferResponse(offer, loc)
+	response.ManageToken = token
+
+	httputils.SendJSONWithStatus(w, response, http.StatusCreated)
+}
+
+// Отказ от предложения: время сразу предлагается следующей заявке,
+// сама заявка остается в листе ожидания
+func (h *WaitlistHandlers) declineOffer(w http.ResponseWriter, r *http.Request) {
+	offer, ok := h.resolveOffer(w, r)
+	if !ok {
+		return
+	}
+
+	if !isOpen(offer) {
+		httputils.SendError(w, "Предложение больше не действует", http.StatusGone)
+		return
+	}
+
+	if err := h.repository.CloseOffer(r.Context(), offer.ID, models.WaitlistOfferDeclined); err != nil {
+		if errors.Is(err, consts.ErrNotFound) {
+			httputils.SendError(w, "Предложение больше не действует", http.StatusGone)
+			return
+		}
+		httputils.SendError(w, "Ошибка при отказе от предложения", http.StatusInternalServerError)
+		return
+	}
+
+	h.worker.Reoffer(offer)
+
+	offer.Status = models.WaitlistOfferDeclined
+	httputils.SendJSONResponse(w, toOfferResponse(offer, h.employeeLocation(r.Context(), offer.EmployeeID)))
+}
+
+// Время заняли раньше: предложение закрывается без передачи следующим
+func (h *WaitlistHandlers) closeTaken(r *http.Request, offer *models.WaitlistOffer) {
+	if err := h.repository.CloseOffer(r.Context(), offer.ID, models.WaitlistOfferExpired); err != nil && !errors.Is(err, consts.ErrNotFound) {
+		h.logger.Error().Err(err).Msgf("Ошибка при закрытии предложения: %d", offer.ID)
+	}
+}
+
+// Предложение по токену {token} из пути. При ошибке сам отправляет ответ
+func (h *WaitlistHandlers) resolveOffer(w http.ResponseWriter, r *http.Request) (*models.WaitlistOffer, bool) {
+	token := r.PathValue("token")
+	if token == "" {
+		httputils.SendError(w, "Предложение не найдено", http.StatusNotFound)
+		return nil, false
+	}
+
+	offer, err := h.repository.GetOfferByTokenHash(r.Context(), utils.HashToken(token))
+	if err != nil {
+		httputils.SendError(w, "Ошибка при получении предложения", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if offer == nil {
+		httputils.SendError(w, "Предложение не найдено", http.StatusNotFound)
+		return nil, false
+	}
+
+	return offer, true
+}
+
+// Часовой пояс графика сотрудника, UTC - если его не удалось получить
+func (h *WaitlistHandlers) employeeLocation(ctx context.Context, employeeID uint) *time.Location {
+	name, err := h.employees.GetTimeZone(ctx, employeeID)
+	if err != nil {
+		return time.UTC
+	}
+	return timezone.LoadOrUTC(name)
+}
+
+func (h *WaitlistHandlers) sendManageLink(r *http.Request, offer *models.WaitlistOffer, loc *time.Location, token string) {
+	err := h.mailer.Send(r.Context(), mailer.Message{
+		To:      offer.Entry.ClientEmail,
+		Subject: "Запись: " + offer.Entry.Section.Name,
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nВы записаны на «%s» %s. Запись ожидает подтверждения.\n\nОтменить или перенести запись можно по ссылке:\n%s/booking/manage?token=%s",
+			offer.Entry.ClientName, offer.Entry.Section.Name, timezone.Format(offer.StartAt, loc), h.appURL, token),
+	})
+	if err != nil {
+		h.logger.Error().Err(err).Msgf("Ошибка при отправке ссылки управления записью по предложению: %d", offer.ID)
+	}
+}
+
+// Предложение ожидает ответа и еще не просрочено
+func isOpen(offer *models.WaitlistOffer) bool {
+	return offer.Status == models.WaitlistOfferPending && offer.ExpiresAt.After(time.Now()) &&
+		offer.Entry.Status == models.WaitlistStatusWaiting
+}
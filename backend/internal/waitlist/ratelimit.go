@@ -0,0 +1,25 @@
+package waitlist
+
+import (
+	"net/http"
+	"record-services/pkg/ratelimit"
+	"time"
+)
+
+type waitlistLimits struct {
+	join  func(http.Handler) http.Handler
+	offer func(http.Handler) http.Handler
+}
+
+func newWaitlistLimits(store ratelimit.Store) *waitlistLimits {
+	return &waitlistLimits{
+		join: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("waitlist-join-ip", store, 10, time.Hour, 5), Key: ratelimit.KeyByIP},
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("waitlist-join-phone", store, 5, time.Hour, 3), Key: ratelimit.KeyByJSONField("clientPhone")},
+		),
+		// Защита от перебора токенов предложений
+		offer: ratelimit.Middleware(
+			ratelimit.Rule{Limiter: ratelimit.NewLimiter("waitlist-offer-ip", store, 30, time.Minute, 10), Key: ratelimit.KeyByIP},
+		),
+	}
+}
@@ -0,0 +1,263 @@
+package waitlist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"record-services/internal/availability"
+	"record-services/internal/models"
+	"record-services/internal/repositories/employee_repository"
+	"record-services/internal/repositories/job_repository"
+	"record-services/internal/repositories/section_repository"
+	"record-services/internal/repositories/waitlist_repository"
+	"record-services/pkg/mailer"
+	"record-services/pkg/timezone"
+	"record-services/pkg/utils"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Вид фоновой задачи предложения освободившегося времени
+const JobKind = "waitlist_released"
+
+const (
+	// Время, за которое клиент должен принять предложение
+	offerTTL = 30 * time.Minute
+	// Проверка просроченных предложений и прошедших заявок
+	expireInterval = time.Minute
+)
+
+// Освободившееся время сотрудника по услуге, данные фоновой задачи
+type releasedSlot struct {
+	OwnerID    uint      `json:"owner_id"`
+	SectionID  uint      `json:"section_id"`
+	EmployeeID uint      `json:"employee_id"`
+	Start      time.Time `json:"start"`
+}
+
+// Фоновое распределение освободившегося времени по листу ожидания.
+// Время предлагается первой по очереди подходящей заявке, а если клиент
+// отказался или не принял предложение вовремя - следующей. Освободившееся
+// время сохраняется фоновой задачей в БД и предлагается Runner из пакета
+// jobs, поэтому не теряется при перезапуске
+type Worker struct {
+	logger     *zerolog.Logger
+	repository waitlist_repository.WaitlistRepository
+	jobs       job_repository.JobRepository
+	employees  employee_repository.EmployeeRepository
+	sections   section_repository.SectionRepository
+	engine     *availability.Engine
+	mailer     mailer.Mailer
+	appURL     string
+}
+
+func NewWorker(logger *zerolog.Logger, repository waitlist_repository.WaitlistRepository, jobs job_repository.JobRepository, employees employee_repository.EmployeeRepository, sections section_repository.SectionRepository, engine *availability.Engine, mailer mailer.Mailer, appURL string) *Worker {
+	return &Worker{
+		logger:     logger,
+		repository: repository,
+		jobs:       jobs,
+		employees:  employees,
+		sections:   sections,
+		engine:     engine,
+		mailer:     mailer,
+		appURL:     appURL,
+	}
+}
+
+// Запускает периодическое закрытие прошедших заявок и просроченных предложений
+func (w *Worker) Start() {
+	go w.run()
+}
+
+// Сообщает, что время записи освободилось: запись отменена или перенесена.
+// Ошибки только логируются: запись уже сохранена
+func (w *Worker) Released(appointment models.Appointment) {
+	w.enqueue(releasedSlot{
+		OwnerID:    appointment.UserID,
+		SectionID:  appointment.SectionID,
+		EmployeeID: appointment.EmployeeID,
+		Start:      appointment.StartAt,
+	})
+}
+
+// Предложение закрыто без записи: клиент отказался или заявка отменена.
+// Время предлагается следующей заявке
+func (w *Worker) Reoffer(offer *models.WaitlistOffer) {
+	w.enqueue(releasedSlot{
+		OwnerID:    offer.Entry.UserID,
+		SectionID:  offer.Entry.SectionID,
+		EmployeeID: offer.EmployeeID,
+		Start:      offer.StartAt,
+	})
+}
+
+// Сохраняет освободившееся время задачей. Повторное освобождение
+// того же времени заменяет еще не обработанную задачу
+func (w *Worker) enqueue(slot releasedSlot) {
+	if !slot.Start.After(time.Now()) {
+		return
+	}
+
+	payload, err := json.Marshal(slot)
+	if err != nil {
+		w.logger.Error().Err(err).Msgf("Ошибка при подготовке предложения времени сотрудника %d", slot.EmployeeID)
+		return
+	}
+
+	key := fmt.Sprintf("waitlist:%d:%d", slot.EmployeeID, slot.Start.Unix())
+	_ = w.jobs.Replace(context.Background(), key, []models.Job{{Kind: JobKind, Payload: string(payload), RunAt: time.Now()}})
+}
+
+func (w *Worker) run() {
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		w.expire()
+	}
+}
+
+// Обработчик задачи освободившегося времени. Ошибка - задача повторяется позже
+func (w *Worker) Offer(ctx context.Context, job *models.Job) error {
+	var slot releasedSlot
+	if err := json.Unmarshal([]byte(job.Payload), &slot); err != nil {
+		return fmt.Errorf("некорректные данные задачи: %w", err)
+	}
+	return w.offer(ctx, slot)
+}
+
+// Закрывает прошедшие заявки и просроченные предложения.
+// Время просроченных предложений предлагается следующим заявкам
+func (w *Worker) expire() {
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, err := w.repository.ExpireEntries(ctx, now); err != nil {
+		return
+	}
+
+	offers, err := w.repository.ExpireOffers(ctx, now)
+	if err != nil {
+		return
+	}
+
+	for i := range offers {
+		w.Reoffer(&offers[i])
+	}
+}
+
+// Предлагает время первой подходящей заявке, если оно все еще свободно.
+// Ошибка - время не удалось проверить или предложить
+func (w *Worker) offer(ctx context.Context, slot releasedSlot) error {
+	employee, err := w.employees.GetById(ctx, slot.EmployeeID)
+	if err != nil {
+		return err
+	}
+	if employee == nil || !employee.IsActive {
+		return nil
+	}
+
+	// В групповых занятиях место освобождается без освобождения времени,
+	// лист ожидания для них не ведется
+	section, err := w.sections.GetById(ctx, slot.SectionID)
+	if err != nil {
+		return err
+	}
+	if section == nil || section.IsGroup() {
+		return nil
+	}
+
+	override, err := w.employees.GetSectionSettings(ctx, employee.ID, section.ID)
+	if err != nil {
+		return err
+	}
+	if override == nil {
+		return nil
+	}
+
+	now := time.Now()
+	terms := section.Terms(override)
+	start, end := slot.Start, slot.Start.Add(terms.Duration)
+
+	if notBefore, _ := terms.BookingWindow(now); start.Before(notBefore) {
+		return nil
+	}
+
+	free, err := w.isFree(ctx, slot.OwnerID, employee.ID, start, end, terms)
+	if err != nil || !free {
+		return err
+	}
+
+	entry, err := w.repository.NextCandidate(ctx, slot.OwnerID, section.ID, employee.ID, start)
+	if err != nil || entry == nil {
+		return err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		w.logger.Error().Err(err).Msg("Ошибка при генерации токена предложения")
+		return err
+	}
+
+	// Предложение не действует дольше начала записи
+	expiresAt := now.Add(offerTTL)
+	if start.Before(expiresAt) {
+		expiresAt = start
+	}
+
+	offer := &models.WaitlistOffer{
+		EntryID:    entry.ID,
+		EmployeeID: employee.ID,
+		StartAt:    start,
+		EndAt:      end,
+		ExpiresAt:  expiresAt,
+		Status:     models.WaitlistOfferPending,
+		TokenHash:  utils.HashToken(token),
+	}
+
+	if err := w.repository.CreateOffer(ctx, offer); err != nil {
+		return err
+	}
+
+	w.logger.Info().Msgf("Заявке %d предложено время %s у сотрудника %d", entry.ID, start.Format(time.RFC3339), employee.ID)
+
+	loc, err := w.engine.Location(ctx, employee.ID)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	err = w.mailer.Send(ctx, mailer.Message{
+		To:      entry.ClientEmail,
+		Subject: "Освободилось время: " + section.Name,
+		Body: fmt.Sprintf("Здравствуйте, %s!\n\nОсвободилось время на «%s» %s, сотрудник %s.\n\nЧтобы записаться, подтвердите до %s по ссылке:\n%s/waitlist/offer?token=%s\n\nПосле этого время будет предложено следующему клиенту.",
+			entry.ClientName, section.Name, timezone.Format(start, loc), employee.Name,
+			timezone.Format(offer.ExpiresAt, loc), w.appURL, token),
+	})
+	// Предложение уже сохранено: повтор задачи предложил бы время
+	// следующей заявке, поэтому ошибка отправки только логируется
+	if err != nil {
+		w.logger.Error().Err(err).Msgf("Ошибка при отправке предложения заявке: %d", entry.ID)
+	}
+	return nil
+}
+
+// Время не закрыто праздником или отсутствием, сотрудник и нужные
+// услуге ресурсы свободны с учетом буферов
+func (w *Worker) isFree(ctx context.Context, ownerID, employeeID uint, start, end time.Time, terms models.ServiceTerms) (bool, error) {
+	blocked, err := w.engine.IsBlocked(ctx, ownerID, employeeID, start, end)
+	if err != nil || blocked {
+		return false, err
+	}
+
+	busy, err := w.engine.IsBusy(ctx, employeeID, start, end, terms, 0, 0)
+	if err != nil || busy {
+		return false, err
+	}
+
+	busy, err = w.engine.IsResourceBusy(ctx, start, end, terms, 0, 0)
+	if err != nil || busy {
+		return false, err
+	}
+	return true, nil
+}
@@ -6,6 +6,7 @@ type ContextKey string
 const (
 	CookieTokenKey    CookieKey = "token"
 	CookieUserDataKey CookieKey = "user_data"
+	CookieRefreshKey  CookieKey = "refresh_token"
 
 	ContextUserKey ContextKey = "user"
 )
@@ -7,4 +7,6 @@ var (
 	ErrBadData        = errors.New("некорректные данные")
 	ErrAlreadyExists  = errors.New("уже существует")
 	ErrNoRowsAffected = errors.New("ни одна запись не была обработана")
+	ErrConflict       = errors.New("конфликт с существующими данными")
+	ErrNoSeats        = errors.New("нет свободных мест")
 )
@@ -0,0 +1,30 @@
+package consts
+
+type Permission string
+
+const (
+	PermUsersManage Permission = "users.manage"
+	PermRolesManage Permission = "roles.manage"
+
+	PermSectionsRead  Permission = "sections.read"
+	PermSectionsWrite Permission = "sections.write"
+
+	PermEmployeesRead  Permission = "employees.read"
+	PermEmployeesWrite Permission = "employees.write"
+
+	PermAppointmentsRead  Permission = "appointments.read"
+	PermAppointmentsWrite Permission = "appointments.write"
+
+	PermClientsRead  Permission = "clients.read"
+	PermClientsWrite Permission = "clients.write"
+)
+
+type RoleName string
+
+const (
+	RoleOwner        RoleName = "owner"
+	RoleManager      RoleName = "manager"
+	RoleReceptionist RoleName = "receptionist"
+	RoleEmployee     RoleName = "employee"
+	RoleAdmin        RoleName = "admin"
+)
@@ -6,7 +6,10 @@ import (
 )
 
 func New(dbDsn string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(dbDsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dbDsn), &gorm.Config{
+		// Нарушения уникальности приходят как gorm.ErrDuplicatedKey
+		TranslateError: true,
+	})
 	if err != nil {
 		return nil, err
 	}
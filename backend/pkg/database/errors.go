@@ -0,0 +1,20 @@
+package database
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Код ошибки PostgreSQL exclusion_violation, gorm его не транслирует
+const exclusionViolationCode = "23P01"
+
+// Ошибка нарушения одного из exclusion constraints с указанными именами
+func IsExclusionViolation(err error, constraints ...string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == exclusionViolationCode && slices.Contains(constraints, pgErr.ConstraintName)
+}
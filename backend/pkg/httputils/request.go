@@ -0,0 +1,107 @@
+package httputils
+
+import (
+	"net"
+	"net/http"
+	"record-services/pkg/consts"
+	"record-services/pkg/timezone"
+	"record-services/pkg/types"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IP клиента из соединения. Заголовки прокси не учитываются,
+// так как клиент может их подделать
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// User-Agent, обрезанный до размера колонки в БД
+func UserAgent(r *http.Request) string {
+	ua := r.UserAgent()
+	if len(ua) > 255 {
+		ua = ua[:255]
+	}
+	return ua
+}
+
+// Идентификатор из пути запроса, например {id} в /api/admin/users/{id}
+func PathID(r *http.Request, name string) (uint, error) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 64)
+	if err != nil || id == 0 {
+		return 0, consts.ErrBadData
+	}
+	return uint(id), nil
+}
+
+// Целое число из query параметра или defaultValue, если параметр не задан или некорректен
+func QueryInt(r *http.Request, name string, defaultValue int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// Значение тройного фильтра true/false/all, по умолчанию all
+func QueryCheckValue(r *http.Request, name string) types.CheckValue {
+	return QueryCheckValueOr(r, name, types.CheckValueAll)
+}
+
+// Значение тройного фильтра true/false/all или defaultValue, если параметр не задан или некорректен
+func QueryCheckValueOr(r *http.Request, name string, defaultValue types.CheckValue) types.CheckValue {
+	switch value := types.CheckValue(r.URL.Query().Get(name)); value {
+	case types.CheckValueTrue, types.CheckValueFalse, types.CheckValueAll:
+		return value
+	default:
+		return defaultValue
+	}
+}
+
+// Время в формате RFC3339 со смещением из query параметра. nil, если параметр
+// не задан. Незакодированный "+" смещения приходит пробелом и восстанавливается
+func QueryTime(r *http.Request, name string) (*time.Time, error) {
+	value := strings.ReplaceAll(r.URL.Query().Get(name), " ", "+")
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, consts.ErrBadData
+	}
+	return &t, nil
+}
+
+// Дата YYYY-MM-DD из query параметра, полночь в часовом поясе loc. nil, если параметр не задан
+func QueryDate(r *http.Request, name string, loc *time.Location) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return nil, consts.ErrBadData
+	}
+	return &t, nil
+}
+
+// Часовой пояс IANA из query параметра, fallback - если параметр не задан
+func QueryLocation(r *http.Request, name string, fallback *time.Location) (*time.Location, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	loc, err := timezone.Load(value)
+	if err != nil {
+		return nil, consts.ErrBadData
+	}
+	return loc, nil
+}
@@ -0,0 +1,42 @@
+package httputils
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func SendError(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{
+		"error":   http.StatusText(statusCode),
+		"message": message,
+	})
+}
+
+func SendJSONResponse(w http.ResponseWriter, data interface{}) {
+	SendJSONWithStatus(w, data, http.StatusOK)
+}
+
+func SendJSONWithStatus(w http.ResponseWriter, data interface{}, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(data)
+}
+
+// Декодирует JSON тело в data и валидирует его. При ошибке сам отправляет ответ
+func DecodeAndValidate(w http.ResponseWriter, r *http.Request, validate *validator.Validate, data interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(data); err != nil {
+		SendError(w, "Невалидный запрос", http.StatusBadRequest)
+		return false
+	}
+
+	if err := validate.Struct(data); err != nil {
+		SendError(w, "Невалидные данные", http.StatusBadRequest)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,180 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"record-services/pkg/timezone"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCalendar = errors.New("некорректный файл календаря")
+
+// Самое длинное событие на весь день. Событие разворачивается в даты,
+// поэтому длинный интервал в маленьком файле дал бы миллионы дат
+const MaxEventDays = 366
+
+type Event struct {
+	UID     string
+	Summary string
+	// Для событий на весь день - полночь UTC, End не включается
+	Start  time.Time
+	End    time.Time
+	AllDay bool
+}
+
+// Дни, которые занимает событие на весь день. Для событий со временем -
+// день начала. Длительность событий из Parse не больше MaxEventDays
+func (e Event) Dates() []time.Time {
+	start := time.Date(e.Start.Year(), e.Start.Month(), e.Start.Day(), 0, 0, 0, 0, time.UTC)
+	if !e.AllDay || !e.End.After(e.Start) {
+		return []time.Time{start}
+	}
+
+	var dates []time.Time
+	for date := start; date.Before(e.End); date = date.AddDate(0, 0, 1) {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+// Минимальный разбор iCalendar (RFC 5545): только события VEVENT
+// с UID, SUMMARY, DTSTART и DTEND. Повторения (RRULE) не поддерживаются.
+// События длиннее MaxEventDays и неизвестные TZID - ошибка ErrInvalidCalendar
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	inCalendar := false
+
+	for _, line := range lines {
+		name, params, value, ok := splitLine(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VCALENDAR":
+			inCalendar = true
+		case name == "BEGIN" && value == "VEVENT":
+			current = &Event{}
+		case name == "END" && value == "VEVENT":
+			if current == nil || current.Start.IsZero() {
+				return nil, fmt.Errorf("%w: событие без DTSTART", ErrInvalidCalendar)
+			}
+			if current.End.IsZero() {
+				current.End = current.Start
+				if current.AllDay {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+			}
+			if current.End.After(current.Start.AddDate(0, 0, MaxEventDays)) {
+				return nil, fmt.Errorf("%w: событие длиннее %d дней", ErrInvalidCalendar, MaxEventDays)
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescape(value)
+		case name == "DTSTART":
+			current.Start, current.AllDay, err = parseTime(value, params)
+			if err != nil {
+				return nil, err
+			}
+		case name == "DTEND":
+			current.End, _, err = parseTime(value, params)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !inCalendar {
+		return nil, fmt.Errorf("%w: нет VCALENDAR", ErrInvalidCalendar)
+	}
+
+	return events, nil
+}
+
+// Склеивает строки, перенесенные по RFC 5545 (продолжение начинается с пробела или табуляции)
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+	return lines, nil
+}
+
+// "DTSTART;VALUE=DATE:20260101" -> "DTSTART", {"VALUE": "DATE"}, "20260101"
+func splitLine(line string) (string, map[string]string, string, bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		if key, value, ok := strings.Cut(part, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+func parseTime(value string, params map[string]string) (time.Time, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.Parse("20060102", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: дата %q", ErrInvalidCalendar, value)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("%w: время %q", ErrInvalidCalendar, value)
+		}
+		return t, false, nil
+	}
+
+	// Без TZID время плавающее, считаем его UTC. Неизвестный пояс
+	// не угадываем: сдвиг на часы перенес бы событие на другой день
+	loc, err := timezone.Load(params["TZID"])
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: часовой пояс %q", ErrInvalidCalendar, params["TZID"])
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: время %q", ErrInvalidCalendar, value)
+	}
+	return t, false, nil
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,147 @@
+package ical
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func calendar(lines ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+}
+
+func TestParse(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []Event
+	}{
+		{
+			name: "событие на весь день",
+			input: calendar("BEGIN:VEVENT", "UID:1", "SUMMARY:Новый год",
+				"DTSTART;VALUE=DATE:20260101", "DTEND;VALUE=DATE:20260103", "END:VEVENT"),
+			want: []Event{{UID: "1", Summary: "Новый год", AllDay: true,
+				Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 1, 3, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:  "без DTEND - один день",
+			input: calendar("BEGIN:VEVENT", "DTSTART:20260308", "END:VEVENT"),
+			want: []Event{{AllDay: true,
+				Start: time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:  "время в UTC",
+			input: calendar("BEGIN:VEVENT", "DTSTART:20260101T090000Z", "DTEND:20260101T100000Z", "END:VEVENT"),
+			want: []Event{{
+				Start: time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC), End: time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:  "время в поясе TZID",
+			input: calendar("BEGIN:VEVENT", "DTSTART;TZID=Europe/Moscow:20260101T090000", "END:VEVENT"),
+			want: []Event{{
+				Start: time.Date(2026, 1, 1, 9, 0, 0, 0, moscow), End: time.Date(2026, 1, 1, 9, 0, 0, 0, moscow)}},
+		},
+		{
+			name:  "перенос строк и экранирование",
+			input: calendar("BEGIN:VEVENT", "SUMMARY:День\\, который", " переносится", "DTSTART:20260501", "END:VEVENT"),
+			want: []Event{{Summary: "День, которыйпереносится", AllDay: true,
+				Start: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC)}},
+		},
+		{
+			name:  "без событий",
+			input: calendar(),
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+
+			if len(events) != len(tt.want) {
+				t.Fatalf("получено %d событий, ожидалось %d", len(events), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := events[i]
+				if got.UID != want.UID || got.Summary != want.Summary || got.AllDay != want.AllDay ||
+					!got.Start.Equal(want.Start) || !got.End.Equal(want.End) {
+					t.Fatalf("получено %+v, ожидалось %+v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"нет VCALENDAR", "BEGIN:VEVENT\r\nDTSTART:20260101\r\nEND:VEVENT\r\n"},
+		{"нет DTSTART", calendar("BEGIN:VEVENT", "SUMMARY:x", "END:VEVENT")},
+		{"некорректная дата", calendar("BEGIN:VEVENT", "DTSTART:2026-01-01", "END:VEVENT")},
+		{"неизвестный TZID", calendar("BEGIN:VEVENT", "DTSTART;TZID=Mars/Olympus:20260101T090000", "END:VEVENT")},
+		{"TZID сервера", calendar("BEGIN:VEVENT", "DTSTART;TZID=Local:20260101T090000", "END:VEVENT")},
+		{"слишком длинное событие", calendar("BEGIN:VEVENT", "DTSTART:00010101", "DTEND:99991231", "END:VEVENT")},
+		{"событие длиннее года", calendar("BEGIN:VEVENT", "DTSTART:20260101", "DTEND:20270103", "END:VEVENT")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if !errors.Is(err, ErrInvalidCalendar) {
+				t.Fatalf("ошибка %v, ожидалась ErrInvalidCalendar", err)
+			}
+		})
+	}
+}
+
+func TestEventDates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name  string
+		event Event
+		want  []time.Time
+	}{
+		{"один день", Event{AllDay: true, Start: day(1), End: day(2)}, []time.Time{day(1)}},
+		{"три дня", Event{AllDay: true, Start: day(1), End: day(4)}, []time.Time{day(1), day(2), day(3)}},
+		{"со временем - день начала", Event{Start: day(5).Add(22 * time.Hour), End: day(6).Add(2 * time.Hour)}, []time.Time{day(5)}},
+		{"пустой интервал", Event{AllDay: true, Start: day(1), End: day(1)}, []time.Time{day(1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.event.Dates()
+			if len(got) != len(tt.want) {
+				t.Fatalf("получены даты %v, ожидались %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("получены даты %v, ожидались %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMaxEvent(t *testing.T) {
+	input := calendar("BEGIN:VEVENT", "DTSTART:20240101", "DTEND:20250101", "END:VEVENT")
+
+	events, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("високосный год целиком отклонен: %v", err)
+	}
+	if got := len(events[0].Dates()); got != MaxEventDays {
+		t.Fatalf("получено %d дат, ожидалось %d", got, MaxEventDays)
+	}
+}
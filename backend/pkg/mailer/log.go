@@ -0,0 +1,24 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// Пишет письма в лог вместо отправки. Для разработки
+type logMailer struct {
+	logger *zerolog.Logger
+}
+
+func NewLogMailer(logger *zerolog.Logger) Mailer {
+	return &logMailer{logger: logger}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Info().
+		Str("to", msg.To).
+		Str("subject", msg.Subject).
+		Msg(msg.Body)
+	return nil
+}
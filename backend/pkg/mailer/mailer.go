@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Отправка писем пользователям. Реализация выбирается в конфигурации
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type Config struct {
+	Driver   string // log или smtp
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func New(cfg Config, logger *zerolog.Logger) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return NewLogMailer(logger), nil
+	case "smtp":
+		return NewSMTPMailer(cfg), nil
+	default:
+		return nil, fmt.Errorf("неизвестный драйвер почты: %s", cfg.Driver)
+	}
+}
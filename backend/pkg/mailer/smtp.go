@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+type smtpMailer struct {
+	cfg Config
+}
+
+func NewSMTPMailer(cfg Config) Mailer {
+	return &smtpMailer{cfg: cfg}
+}
+
+func (m *smtpMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+
+	return smtp.SendMail(addr, auth, m.cfg.From, []string{msg.To}, []byte(b.String()))
+}
@@ -0,0 +1,122 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const Argon2idID = "argon2id"
+
+type Argon2idParams struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Рекомендованные OWASP параметры
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+type argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2id(params Argon2idParams) Hasher {
+	return &argon2idHasher{params: params}
+}
+
+func (h *argon2idHasher) ID() string {
+	return Argon2idID
+}
+
+// Хеш в формате PHC: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2idID,
+		argon2.Version,
+		h.params.Memory,
+		h.params.Iterations,
+		h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *argon2idHasher) Verify(password string, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+func (h *argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, _, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		params.KeyLength != h.params.KeyLength
+}
+
+func decodeArgon2id(encoded string) (*Argon2idParams, []byte, []byte, error) {
+	var (
+		id      string
+		version int
+		params  Argon2idParams
+		saltB64 string
+		keyB64  string
+	)
+
+	parts := splitEncoded(encoded)
+	if len(parts) != 5 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	id = parts[0]
+	if id != Argon2idID {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[1], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[2], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	saltB64, keyB64 = parts[3], parts[4]
+
+	salt, err := base64.RawStdEncoding.DecodeString(saltB64)
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(keyB64)
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return &params, salt, key, nil
+}
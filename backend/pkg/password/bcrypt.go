@@ -0,0 +1,56 @@
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	BcryptID          = "bcrypt"
+	DefaultBcryptCost = 12
+)
+
+type bcryptHasher struct {
+	cost int
+}
+
+func NewBcrypt(cost int) Hasher {
+	return &bcryptHasher{cost: cost}
+}
+
+func (h *bcryptHasher) ID() string {
+	return BcryptID
+}
+
+// bcrypt сам кодирует алгоритм, стоимость и соль: $2a$12$...
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h *bcryptHasher) Verify(password string, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (h *bcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+	return cost != h.cost
+}
+
+func isBcryptPrefix(id string) bool {
+	return id == "2a" || id == "2b" || id == "2y"
+}
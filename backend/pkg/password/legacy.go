@@ -0,0 +1,23 @@
+package password
+
+import (
+	"record-services/pkg/utils"
+	"strings"
+)
+
+// Хеши, созданные до перехода на KDF: hex от HMAC-SHA256 без префикса
+func isLegacyHash(encoded string) bool {
+	return !strings.HasPrefix(encoded, "$")
+}
+
+func verifyLegacy(password string, encoded string, secret string) bool {
+	if secret == "" {
+		return false
+	}
+	return utils.VerifyHash(password, encoded, secret)
+}
+
+// Разбивает "$id$a$b" на ["id", "a", "b"]
+func splitEncoded(encoded string) []string {
+	return strings.Split(strings.TrimPrefix(encoded, "$"), "$")
+}
@@ -0,0 +1,108 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrUnknownAlgorithm = errors.New("неизвестный алгоритм хеширования пароля")
+	ErrInvalidHash      = errors.New("некорректный формат хеша пароля")
+)
+
+// Алгоритм хеширования паролей
+type Hasher interface {
+	// Идентификатор алгоритма, с которого начинается закодированный хеш
+	ID() string
+	// Хеширует пароль со случайной солью и возвращает закодированную строку
+	Hash(password string) (string, error)
+	// Проверяет пароль по закодированному хешу
+	Verify(password string, encoded string) (bool, error)
+	// Сообщает, что хеш создан с устаревшими параметрами
+	NeedsRehash(encoded string) bool
+}
+
+// Хеширует новые пароли текущим алгоритмом и проверяет хеши
+// всех поддерживаемых форматов, включая устаревший HMAC
+type Service struct {
+	current      Hasher
+	hashers      map[string]Hasher
+	legacySecret string
+}
+
+func NewService(current Hasher, legacySecret string, others ...Hasher) *Service {
+	s := &Service{
+		current:      current,
+		hashers:      make(map[string]Hasher),
+		legacySecret: legacySecret,
+	}
+
+	s.hashers[current.ID()] = current
+	for _, h := range others {
+		if _, ok := s.hashers[h.ID()]; !ok {
+			s.hashers[h.ID()] = h
+		}
+	}
+
+	return s
+}
+
+// Создает хеш по имени алгоритма из конфигурации
+func NewHasher(name string) (Hasher, error) {
+	switch name {
+	case Argon2idID:
+		return NewArgon2id(DefaultArgon2idParams), nil
+	case BcryptID:
+		return NewBcrypt(DefaultBcryptCost), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, name)
+	}
+}
+
+func (s *Service) Hash(password string) (string, error) {
+	return s.current.Hash(password)
+}
+
+// Проверяет пароль. needsRehash = true, если хеш нужно пересоздать
+// текущим алгоритмом (устаревший формат, другой алгоритм или параметры)
+func (s *Service) Verify(password string, encoded string) (ok bool, needsRehash bool, err error) {
+	if isLegacyHash(encoded) {
+		return verifyLegacy(password, encoded, s.legacySecret), true, nil
+	}
+
+	hasher, err := s.hasherFor(encoded)
+	if err != nil {
+		return false, false, err
+	}
+
+	ok, err = hasher.Verify(password, encoded)
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	if hasher.ID() != s.current.ID() {
+		return true, true, nil
+	}
+
+	return true, hasher.NeedsRehash(encoded), nil
+}
+
+func (s *Service) hasherFor(encoded string) (Hasher, error) {
+	// Формат: $<id>$...
+	parts := strings.SplitN(encoded, "$", 3)
+	if len(parts) < 3 || parts[0] != "" {
+		return nil, ErrInvalidHash
+	}
+
+	id := parts[1]
+	if isBcryptPrefix(id) {
+		id = BcryptID
+	}
+
+	hasher, ok := s.hashers[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, id)
+	}
+	return hasher, nil
+}
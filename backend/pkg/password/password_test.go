@@ -0,0 +1,194 @@
+package password
+
+import (
+	"errors"
+	"record-services/pkg/utils"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Облегченные параметры, чтобы тесты не тратили время на KDF
+var testArgon2idParams = Argon2idParams{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestHashVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher Hasher
+	}{
+		{"argon2id", NewArgon2id(testArgon2idParams)},
+		{"bcrypt", NewBcrypt(bcrypt.MinCost)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.hasher.Hash("секрет")
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+
+			other, err := tt.hasher.Hash("секрет")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other == encoded {
+				t.Fatal("хеши одного пароля совпали, соль не используется")
+			}
+
+			if ok, err := tt.hasher.Verify("секрет", encoded); !ok || err != nil {
+				t.Fatalf("верный пароль отклонен: %v, %v", ok, err)
+			}
+			if ok, err := tt.hasher.Verify("другой", encoded); ok || err != nil {
+				t.Fatalf("неверный пароль: %v, %v", ok, err)
+			}
+			if tt.hasher.NeedsRehash(encoded) {
+				t.Fatal("свежий хеш требует пересоздания")
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon2idHash, err := NewArgon2id(testArgon2idParams).Hash("секрет")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := NewBcrypt(bcrypt.MinCost).Hash("секрет")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(change func(p *Argon2idParams)) Hasher {
+		params := testArgon2idParams
+		change(&params)
+		return NewArgon2id(params)
+	}
+
+	tests := []struct {
+		name    string
+		hasher  Hasher
+		encoded string
+		want    bool
+	}{
+		{"argon2id, те же параметры", NewArgon2id(testArgon2idParams), argon2idHash, false},
+		{"argon2id, другая соль", with(func(p *Argon2idParams) { p.SaltLength = 32 }), argon2idHash, false},
+		{"argon2id, память", with(func(p *Argon2idParams) { p.Memory = 2048 }), argon2idHash, true},
+		{"argon2id, итерации", with(func(p *Argon2idParams) { p.Iterations = 2 }), argon2idHash, true},
+		{"argon2id, потоки", with(func(p *Argon2idParams) { p.Parallelism = 2 }), argon2idHash, true},
+		{"argon2id, длина ключа", with(func(p *Argon2idParams) { p.KeyLength = 64 }), argon2idHash, true},
+		{"argon2id, некорректный хеш", NewArgon2id(testArgon2idParams), "$argon2id$v=19$m=1024", true},
+		{"bcrypt, та же стоимость", NewBcrypt(bcrypt.MinCost), bcryptHash, false},
+		{"bcrypt, выше стоимость", NewBcrypt(bcrypt.MinCost + 1), bcryptHash, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.encoded); got != tt.want {
+				t.Fatalf("NeedsRehash = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceVerify(t *testing.T) {
+	const secret = "legacy-secret"
+
+	current := NewArgon2id(testArgon2idParams)
+	old := NewBcrypt(bcrypt.MinCost)
+	service := NewService(current, secret, old)
+
+	currentHash, err := current.Hash("секрет")
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakHash, err := NewArgon2id(Argon2idParams{Memory: 512, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}).Hash("секрет")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := old.Hash("секрет")
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyHash := utils.CreateHash("секрет", secret)
+
+	tests := []struct {
+		name        string
+		service     *Service
+		password    string
+		encoded     string
+		ok          bool
+		needsRehash bool
+		err         error
+	}{
+		{"текущий алгоритм", service, "секрет", currentHash, true, false, nil},
+		{"текущий алгоритм, неверный пароль", service, "другой", currentHash, false, false, nil},
+		{"устаревшие параметры", service, "секрет", weakHash, true, true, nil},
+		{"другой алгоритм", service, "секрет", bcryptHash, true, true, nil},
+		{"другой алгоритм, неверный пароль", service, "другой", bcryptHash, false, false, nil},
+		{"HMAC", service, "секрет", legacyHash, true, true, nil},
+		{"HMAC, неверный пароль", service, "другой", legacyHash, false, true, nil},
+		{"HMAC без секрета", NewService(current, ""), "секрет", legacyHash, false, true, nil},
+		{"алгоритм не подключен", NewService(current, secret), "секрет", bcryptHash, false, false, ErrUnknownAlgorithm},
+		{"неизвестный алгоритм", service, "секрет", "$scrypt$ln=15$abc", false, false, ErrUnknownAlgorithm},
+		{"некорректный формат", service, "секрет", "$argon2id", false, false, ErrInvalidHash},
+		{"битый argon2id", service, "секрет", "$argon2id$v=19$m=1024,t=1,p=1$!!!$!!!", false, false, ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := tt.service.Verify(tt.password, tt.encoded)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ошибка %v, ожидалась %v", err, tt.err)
+			}
+			if ok != tt.ok || needsRehash != tt.needsRehash {
+				t.Fatalf("Verify = %v, %v, ожидалось %v, %v", ok, needsRehash, tt.ok, tt.needsRehash)
+			}
+		})
+	}
+}
+
+func TestServiceHash(t *testing.T) {
+	service := NewService(NewArgon2id(testArgon2idParams), "", NewBcrypt(bcrypt.MinCost))
+
+	encoded, err := service.Hash("секрет")
+	if err != nil {
+		t.Fatalf("ошибка: %v", err)
+	}
+
+	// Новые хеши всегда создаются текущим алгоритмом
+	ok, needsRehash, err := service.Verify("секрет", encoded)
+	if !ok || needsRehash || err != nil {
+		t.Fatalf("Verify = %v, %v, %v", ok, needsRehash, err)
+	}
+}
+
+func TestNewHasher(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		err  error
+	}{
+		{Argon2idID, Argon2idID, nil},
+		{BcryptID, BcryptID, nil},
+		{"md5", "", ErrUnknownAlgorithm},
+		{"", "", ErrUnknownAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewHasher(tt.name)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ошибка %v, ожидалась %v", err, tt.err)
+			}
+			if err == nil && hasher.ID() != tt.want {
+				t.Fatalf("алгоритм %s, ожидался %s", hasher.ID(), tt.want)
+			}
+		})
+	}
+}
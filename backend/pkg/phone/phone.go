@@ -0,0 +1,54 @@
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPhone = errors.New("некорректный номер телефона")
+
+// Код страны для номеров, введенных без него
+const DefaultCountryCode = "7"
+
+// Приводит номер к формату E.164: "+" и от 8 до 15 цифр.
+// Пробелы, скобки, точки и дефисы отбрасываются. Российские номера
+// с ведущей 8 и номера без кода страны получают код DefaultCountryCode,
+// префикс международного вызова 00 заменяется на "+".
+// "+" допускается только первым символом.
+// Пустая строка остается пустой
+func Normalize(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+
+	international := strings.HasPrefix(raw, "+")
+
+	var digits strings.Builder
+	for i, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.' || (r == '+' && i == 0):
+		default:
+			return "", ErrInvalidPhone
+		}
+	}
+
+	number := digits.String()
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case len(number) == 11 && number[0] == '8':
+		number = DefaultCountryCode + number[1:]
+	case len(number) == 10:
+		number = DefaultCountryCode + number
+	}
+
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", ErrInvalidPhone
+	}
+
+	return "+" + number, nil
+}
@@ -0,0 +1,62 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"пустой", "", ""},
+		{"только пробелы", "   ", ""},
+		{"E.164", "+79161234567", "+79161234567"},
+		{"с разделителями", "+7 (916) 123-45-67", "+79161234567"},
+		{"ведущая 8", "8 916 123 45 67", "+79161234567"},
+		{"без кода страны", "9161234567", "+79161234567"},
+		{"префикс 00", "00 49 30 1234567", "+49301234567"},
+		{"международный", "+1.202.555.0143", "+12025550143"},
+		{"одиннадцать цифр не с 8", "79161234567", "+79161234567"},
+		{"минимальная длина", "+12345678", "+12345678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.raw)
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Normalize(%q) = %q, ожидалось %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+	}{
+		{"буквы", "+7 916 ABC 45 67"},
+		{"плюс в середине", "7916+1234567"},
+		{"двойной плюс", "++79161234567"},
+		{"плюс после разделителя", "+ +79161234567"},
+		{"плюс после скобки", "(+7) 916 123-45-67"},
+		{"короткий", "12345"},
+		{"длинный", "+1234567890123456"},
+		{"код страны с нуля", "+0123456789"},
+		{"добавочный", "+7 916 123-45-67 доб. 12"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Normalize(tt.raw); !errors.Is(err, ErrInvalidPhone) {
+				t.Fatalf("Normalize(%q): ошибка %v, ожидалась ErrInvalidPhone", tt.raw, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package ratelimit
+
+import "time"
+
+// Token bucket: burst запросов сразу, далее count запросов за period
+type Limiter struct {
+	name  string
+	store Store
+	rate  float64
+	burst int
+}
+
+func NewLimiter(name string, store Store, count int, period time.Duration, burst int) *Limiter {
+	return &Limiter{
+		name:  name,
+		store: store,
+		rate:  float64(count) / period.Seconds(),
+		burst: burst,
+	}
+}
+
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	// Имя лимитера в ключе разделяет корзины разных эндпоинтов в общем хранилище
+	return l.store.Take(l.name+":"+key, l.rate, l.burst, time.Now())
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Корзины не используются дольше этого времени - удаляем
+const idleBucketTTL = time.Hour
+
+type bucket struct {
+	tokens   float64
+	updateAt time.Time
+}
+
+// Хранилище в памяти процесса. Подходит для одного экземпляра сервера
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		buckets: make(map[string]*bucket),
+	}
+
+	// Запускаем фоновую очистку
+	go s.startCleanup()
+
+	return s
+}
+
+func (s *MemoryStore) Take(key string, rate float64, burst int, now time.Time) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updateAt: now}
+		s.buckets[key] = b
+	}
+
+	// Пополняем корзину за прошедшее время
+	elapsed := now.Sub(b.updateAt).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+		b.updateAt = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := (1 - b.tokens) / rate
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+func (s *MemoryStore) startCleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		s.clearIdle()
+	}
+}
+
+func (s *MemoryStore) clearIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, b := range s.buckets {
+		if now.Sub(b.updateAt) > idleBucketTTL {
+			delete(s.buckets, key)
+		}
+	}
+}
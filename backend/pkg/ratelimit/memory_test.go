@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Один токен в секунду, сразу доступно три
+	type take struct {
+		after   time.Duration
+		allowed bool
+		wait    time.Duration
+	}
+
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{"burst сразу", []take{{0, true, 0}, {0, true, 0}, {0, true, 0}, {0, false, time.Second}}},
+		{"пополнение со временем", []take{{0, true, 0}, {0, true, 0}, {0, true, 0}, {time.Second, true, 0}, {time.Second, false, time.Second}}},
+		{"ожидание части токена", []take{{0, true, 0}, {0, true, 0}, {0, true, 0}, {250 * time.Millisecond, false, 750 * time.Millisecond}}},
+		{"не больше burst после простоя", []take{{time.Hour, true, 0}, {time.Hour, true, 0}, {time.Hour, true, 0}, {time.Hour, false, time.Second}}},
+		{"время назад не пополняет", []take{{0, true, 0}, {0, true, 0}, {0, true, 0}, {-time.Minute, false, time.Second}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i, take := range tt.takes {
+				allowed, wait := store.Take("key", 1, 3, start.Add(take.after))
+				if allowed != take.allowed || wait != take.wait {
+					t.Fatalf("запрос %d: получено %v, %s, ожидалось %v, %s", i+1, allowed, wait, take.allowed, take.wait)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeys(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+
+	if allowed, _ := store.Take("a", 1, 1, now); !allowed {
+		t.Fatal("первый запрос отклонен")
+	}
+	if allowed, _ := store.Take("a", 1, 1, now); allowed {
+		t.Fatal("лимит ключа a не применен")
+	}
+	if allowed, _ := store.Take("b", 1, 1, now); !allowed {
+		t.Fatal("лимит ключа a применен к ключу b")
+	}
+}
+
+func TestLimiterNames(t *testing.T) {
+	store := NewMemoryStore()
+	login := NewLimiter("login", store, 1, time.Minute, 1)
+	register := NewLimiter("register", store, 1, time.Minute, 1)
+
+	if allowed, _ := login.Allow("ip:1.2.3.4"); !allowed {
+		t.Fatal("первый вход отклонен")
+	}
+	allowed, wait := login.Allow("ip:1.2.3.4")
+	if allowed || wait <= 0 || wait > time.Minute {
+		t.Fatalf("второй вход: %v, ожидание %s", allowed, wait)
+	}
+	if allowed, _ := register.Allow("ip:1.2.3.4"); !allowed {
+		t.Fatal("лимит входа применен к регистрации")
+	}
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"record-services/pkg/httputils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Тело читается целиком, чтобы достать поле, поэтому ограничиваем размер
+const maxPeekBodySize = 1 << 20
+
+// Ключ корзины для запроса. Пустой ключ - лимит не применяется
+type KeyFunc func(r *http.Request) string
+
+type Rule struct {
+	Limiter *Limiter
+	Key     KeyFunc
+}
+
+func KeyByIP(r *http.Request) string {
+	return "ip:" + httputils.ClientIP(r)
+}
+
+// Ключ по строковому полю JSON тела, например email. Тело восстанавливается
+// для следующего обработчика
+func KeyByJSONField(field string) KeyFunc {
+	return func(r *http.Request) string {
+		if r.Body == nil {
+			return ""
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBodySize))
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ""
+		}
+
+		var data map[string]interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			return ""
+		}
+
+		value, ok := data[field].(string)
+		if !ok || value == "" {
+			return ""
+		}
+
+		return field + ":" + strings.ToLower(strings.TrimSpace(value))
+	}
+}
+
+// Отклоняет запрос с 429 и Retry-After, если исчерпан лимит хотя бы одного правила
+func Middleware(rules ...Rule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, rule := range rules {
+				key := rule.Key(r)
+				if key == "" {
+					continue
+				}
+
+				if allowed, retryAfter := rule.Limiter.Allow(key); !allowed {
+					SendTooManyRequests(w, retryAfter)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func SendTooManyRequests(w http.ResponseWriter, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	httputils.SendError(w, "Слишком много запросов, повторите через "+strconv.Itoa(seconds)+" с", http.StatusTooManyRequests)
+}
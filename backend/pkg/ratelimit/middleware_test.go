@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyByJSONField(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"поле", `{"email":"user@example.com"}`, "email:user@example.com"},
+		{"регистр и пробелы", `{"email":"  User@Example.COM "}`, "email:user@example.com"},
+		{"нет поля", `{"name":"user"}`, ""},
+		{"пустое поле", `{"email":""}`, ""},
+		{"не строка", `{"email":42}`, ""},
+		{"не JSON", `email=user@example.com`, ""},
+		{"пустое тело", ``, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if got := KeyByJSONField("email")(r); got != tt.want {
+				t.Fatalf("ключ %q, ожидался %q", got, tt.want)
+			}
+
+			// Тело остается доступным обработчику
+			body, err := io.ReadAll(r.Body)
+			if err != nil || string(body) != tt.body {
+				t.Fatalf("тело после чтения ключа %q, ошибка %v", body, err)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	store := NewMemoryStore()
+	byIP := NewLimiter("ip", store, 1, time.Minute, 2)
+	byEmail := NewLimiter("email", store, 1, time.Minute, 1)
+
+	handler := Middleware(
+		Rule{Limiter: byIP, Key: KeyByIP},
+		Rule{Limiter: byEmail, Key: KeyByJSONField("email")},
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name       string
+		ip         string
+		body       string
+		wantStatus int
+	}{
+		{"первый запрос", "10.0.0.1:1000", `{"email":"a@example.com"}`, http.StatusNoContent},
+		{"тот же email", "10.0.0.2:1000", `{"email":"a@example.com"}`, http.StatusTooManyRequests},
+		{"другой email", "10.0.0.1:1000", `{"email":"b@example.com"}`, http.StatusNoContent},
+		{"исчерпан лимит по IP", "10.0.0.1:1000", `{"email":"c@example.com"}`, http.StatusTooManyRequests},
+		{"без ключа email", "10.0.0.3:1000", `{}`, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			r.RemoteAddr = tt.ip
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ответ %d, ожидался %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Fatal("нет заголовка Retry-After")
+			}
+		})
+	}
+}
+
+func TestSendTooManyRequests(t *testing.T) {
+	tests := []struct {
+		retryAfter time.Duration
+		want       string
+	}{
+		{0, "1"},
+		{300 * time.Millisecond, "1"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+		{time.Minute, "60"},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		SendTooManyRequests(w, tt.retryAfter)
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != tt.want {
+			t.Fatalf("%s: ответ %d, Retry-After %q, ожидалось %q", tt.retryAfter, w.Code, w.Header().Get("Retry-After"), tt.want)
+		}
+	}
+}
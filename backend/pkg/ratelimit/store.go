@@ -0,0 +1,11 @@
+package ratelimit
+
+import "time"
+
+// Хранилище состояния корзин. Реализация должна выполнять Take атомарно,
+// чтобы лимит соблюдался и при параллельных запросах (например, в Redis)
+type Store interface {
+	// Забирает один токен из корзины key. Если токенов нет, возвращает
+	// false и время, через которое токен появится
+	Take(key string, rate float64, burst int, now time.Time) (bool, time.Duration)
+}
@@ -0,0 +1,234 @@
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("некорректное правило повторения")
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+const untilLayout = "20060102T150405Z"
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Правило повторения iCalendar (RFC 5545, RRULE). Поддерживается подмножество:
+// FREQ=DAILY|WEEKLY|MONTHLY, INTERVAL, COUNT, UNTIL и BYDAY без номеров недель
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// 0 - количество не ограничено
+	Count int
+	// Включительно, нулевое - без даты окончания
+	Until time.Time
+	// Для DAILY - фильтр дней недели, для WEEKLY - дни внутри недели.
+	// Для MONTHLY не поддерживается
+	ByDay []time.Weekday
+}
+
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	rule := &Rule{Interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRule, part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(val))
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return nil, fmt.Errorf("%w: частота %s не поддерживается", ErrInvalidRule, val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 365 {
+				return nil, fmt.Errorf("%w: INTERVAL=%s", ErrInvalidRule, val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: COUNT=%s", ErrInvalidRule, val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, name := range strings.Split(strings.ToUpper(val), ",") {
+				day := slices.Index(weekdayNames, name)
+				if day < 0 {
+					return nil, fmt.Errorf("%w: BYDAY=%s", ErrInvalidRule, val)
+				}
+				if !slices.Contains(rule.ByDay, time.Weekday(day)) {
+					rule.ByDay = append(rule.ByDay, time.Weekday(day))
+				}
+			}
+		case "WKST":
+			// Недели всегда начинаются с понедельника
+		default:
+			return nil, fmt.Errorf("%w: параметр %s не поддерживается", ErrInvalidRule, key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("%w: не задан FREQ", ErrInvalidRule)
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return nil, fmt.Errorf("%w: COUNT и UNTIL не могут быть заданы вместе", ErrInvalidRule)
+	}
+	if rule.Freq == Monthly && len(rule.ByDay) > 0 {
+		return nil, fmt.Errorf("%w: BYDAY для MONTHLY не поддерживается", ErrInvalidRule)
+	}
+
+	return rule, nil
+}
+
+// UNTIL в UTC или датой. Дата без времени включает весь день
+func parseUntil(value string) (time.Time, error) {
+	if until, err := time.Parse(untilLayout, value); err == nil {
+		return until, nil
+	}
+
+	date, err := time.Parse("20060102", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: UNTIL=%s", ErrInvalidRule, value)
+	}
+	return date.Add(24*time.Hour - time.Second), nil
+}
+
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		names := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			names[i] = weekdayNames[day]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(names, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Правило ограничено количеством или датой окончания
+func (r *Rule) IsFinite() bool {
+	return r.Count > 0 || !r.Until.IsZero()
+}
+
+// Копия правила, заканчивающаяся не позже until. COUNT заменяется датой
+func (r *Rule) EndingAt(until time.Time) *Rule {
+	truncated := *r
+	truncated.Count = 0
+	if truncated.Until.IsZero() || until.Before(truncated.Until) {
+		truncated.Until = until
+	}
+	return &truncated
+}
+
+// Вхождения правила с первым вхождением start, не позже limit и не более maxCount.
+// Время суток и день месяца берутся из start, расчет идет в его часовом поясе,
+// поэтому переход на летнее время не сдвигает записи. Дни, которых нет
+// в месяце (31 число), пропускаются
+func (r *Rule) Expand(start, limit time.Time, maxCount int) []time.Time {
+	interval := max(r.Interval, 1)
+	result := make([]time.Time, 0)
+
+	for period := 0; ; period++ {
+		anchor := r.periodStart(start, period*interval)
+		if anchor.After(limit) || (!r.Until.IsZero() && anchor.After(r.Until)) {
+			return result
+		}
+
+		for _, candidate := range r.candidates(start, anchor) {
+			if candidate.Before(start) {
+				continue
+			}
+			if candidate.After(limit) || (!r.Until.IsZero() && candidate.After(r.Until)) {
+				return result
+			}
+
+			result = append(result, candidate)
+			if len(result) >= maxCount || (r.Count > 0 && len(result) >= r.Count) {
+				return result
+			}
+		}
+	}
+}
+
+// Начало периода со сдвигом offset от периода start. Не позже вхождений периода
+func (r *Rule) periodStart(start time.Time, offset int) time.Time {
+	switch r.Freq {
+	case Weekly:
+		monday := start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+		return monday.AddDate(0, 0, 7*offset)
+	case Monthly:
+		return time.Date(start.Year(), start.Month()+time.Month(offset), 1,
+			start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	default:
+		return start.AddDate(0, 0, offset)
+	}
+}
+
+// Вхождения периода в хронологическом порядке
+func (r *Rule) candidates(start, anchor time.Time) []time.Time {
+	switch r.Freq {
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+
+		offsets := make([]int, len(days))
+		for i, day := range days {
+			offsets[i] = (int(day) + 6) % 7
+		}
+		slices.Sort(offsets)
+
+		result := make([]time.Time, len(offsets))
+		for i, offset := range offsets {
+			result[i] = anchor.AddDate(0, 0, offset)
+		}
+		return result
+
+	case Monthly:
+		candidate := time.Date(anchor.Year(), anchor.Month(), start.Day(),
+			start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+		if candidate.Month() != anchor.Month() {
+			return nil
+		}
+		return []time.Time{candidate}
+
+	default:
+		if len(r.ByDay) > 0 && !slices.Contains(r.ByDay, anchor.Weekday()) {
+			return nil
+		}
+		return []time.Time{anchor}
+	}
+}
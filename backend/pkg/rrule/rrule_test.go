@@ -0,0 +1,178 @@
+package rrule
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  Rule
+	}{
+		{"ежедневно", "FREQ=DAILY", Rule{Freq: Daily, Interval: 1}},
+		{"префикс и регистр", "RRULE:freq=weekly;byday=mo,we", Rule{Freq: Weekly, Interval: 1, ByDay: []time.Weekday{time.Monday, time.Wednesday}}},
+		{"повтор дня", "FREQ=WEEKLY;BYDAY=MO,MO", Rule{Freq: Weekly, Interval: 1, ByDay: []time.Weekday{time.Monday}}},
+		{"интервал и количество", "FREQ=MONTHLY;INTERVAL=2;COUNT=6", Rule{Freq: Monthly, Interval: 2, Count: 6}},
+		{"UNTIL со временем", "FREQ=DAILY;UNTIL=20260115T120000Z",
+			Rule{Freq: Daily, Interval: 1, Until: time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)}},
+		{"UNTIL датой - весь день", "FREQ=DAILY;UNTIL=20260115",
+			Rule{Freq: Daily, Interval: 1, Until: time.Date(2026, 1, 15, 23, 59, 59, 0, time.UTC)}},
+		{"WKST игнорируется", "FREQ=WEEKLY;WKST=SU", Rule{Freq: Weekly, Interval: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if got.Freq != tt.want.Freq || got.Interval != tt.want.Interval || got.Count != tt.want.Count ||
+				!got.Until.Equal(tt.want.Until) || !slices.Equal(got.ByDay, tt.want.ByDay) {
+				t.Fatalf("получено %+v, ожидалось %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"пусто", ""},
+		{"без FREQ", "COUNT=3"},
+		{"неизвестная частота", "FREQ=YEARLY"},
+		{"без значения", "FREQ=DAILY;COUNT="},
+		{"нулевой интервал", "FREQ=DAILY;INTERVAL=0"},
+		{"большой интервал", "FREQ=DAILY;INTERVAL=366"},
+		{"нулевое количество", "FREQ=DAILY;COUNT=0"},
+		{"некорректный UNTIL", "FREQ=DAILY;UNTIL=2026-01-15"},
+		{"COUNT вместе с UNTIL", "FREQ=DAILY;COUNT=3;UNTIL=20260115"},
+		{"номер недели в BYDAY", "FREQ=WEEKLY;BYDAY=1MO"},
+		{"BYDAY для MONTHLY", "FREQ=MONTHLY;BYDAY=MO"},
+		{"неподдерживаемый параметр", "FREQ=DAILY;BYHOUR=9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.value); !errors.Is(err, ErrInvalidRule) {
+				t.Fatalf("Parse(%q): ошибка %v, ожидалась ErrInvalidRule", tt.value, err)
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	for _, value := range []string{
+		"FREQ=DAILY",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=10",
+		"FREQ=MONTHLY;UNTIL=20261231T235959Z",
+	} {
+		rule, err := Parse(value)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", value, err)
+		}
+		if got := rule.String(); got != value {
+			t.Fatalf("String() = %q, ожидалось %q", got, value)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Понедельник
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, berlin)
+	limit := time.Date(2027, 1, 1, 0, 0, 0, 0, berlin)
+
+	tests := []struct {
+		name     string
+		value    string
+		start    time.Time
+		limit    time.Time
+		maxCount int
+		// Вхождения по местному времени
+		want []string
+	}{
+		{"ежедневно с COUNT", "FREQ=DAILY;COUNT=3", start, limit, 100,
+			[]string{"2026-03-02 10:00", "2026-03-03 10:00", "2026-03-04 10:00"}},
+		{"через день до UNTIL", "FREQ=DAILY;INTERVAL=2;UNTIL=20260306", start, limit, 100,
+			[]string{"2026-03-02 10:00", "2026-03-04 10:00", "2026-03-06 10:00"}},
+		{"ежедневно по будням", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=6", start, limit, 100,
+			[]string{"2026-03-02 10:00", "2026-03-03 10:00", "2026-03-04 10:00", "2026-03-05 10:00", "2026-03-06 10:00", "2026-03-09 10:00"}},
+		{"еженедельно по дням", "FREQ=WEEKLY;BYDAY=FR,MO;COUNT=4", start, limit, 100,
+			[]string{"2026-03-02 10:00", "2026-03-06 10:00", "2026-03-09 10:00", "2026-03-13 10:00"}},
+		{"дни недели раньше начала пропускаются", "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=3", start.AddDate(0, 0, 1), limit, 100,
+			[]string{"2026-03-04 10:00", "2026-03-09 10:00", "2026-03-11 10:00"}},
+		{"раз в две недели", "FREQ=WEEKLY;INTERVAL=2;COUNT=3", start, limit, 100,
+			[]string{"2026-03-02 10:00", "2026-03-16 10:00", "2026-03-30 10:00"}},
+		{"переход на летнее время", "FREQ=WEEKLY;COUNT=3", time.Date(2026, 3, 23, 10, 0, 0, 0, berlin), limit, 100,
+			[]string{"2026-03-23 10:00", "2026-03-30 10:00", "2026-04-06 10:00"}},
+		{"31 число пропускается", "FREQ=MONTHLY;COUNT=3", time.Date(2026, 1, 31, 10, 0, 0, 0, berlin), limit, 100,
+			[]string{"2026-01-31 10:00", "2026-03-31 10:00", "2026-05-31 10:00"}},
+		{"ограничение limit", "FREQ=DAILY", start, start.AddDate(0, 0, 2), 100,
+			[]string{"2026-03-02 10:00", "2026-03-03 10:00", "2026-03-04 10:00"}},
+		{"ограничение maxCount", "FREQ=DAILY;COUNT=10", start, limit, 2,
+			[]string{"2026-03-02 10:00", "2026-03-03 10:00"}},
+		{"UNTIL раньше начала", "FREQ=DAILY;UNTIL=20260301", start, limit, 100, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			occurrences := rule.Expand(tt.start, tt.limit, tt.maxCount)
+			got := make([]string, len(occurrences))
+			for i, occurrence := range occurrences {
+				got[i] = occurrence.In(berlin).Format("2006-01-02 15:04")
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("получено %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndingAt(t *testing.T) {
+	until := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+	}{
+		{"без окончания", "FREQ=DAILY", until},
+		{"COUNT заменяется датой", "FREQ=DAILY;COUNT=5", until},
+		{"более раннее UNTIL сохраняется", "FREQ=DAILY;UNTIL=20260301T000000Z", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"более позднее UNTIL сокращается", "FREQ=DAILY;UNTIL=20261231T000000Z", until},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.value)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			original := *rule
+			truncated := rule.EndingAt(until)
+			if truncated.Count != 0 || !truncated.Until.Equal(tt.want) || !truncated.IsFinite() {
+				t.Fatalf("получено %+v, ожидалось UNTIL %s", *truncated, tt.want)
+			}
+			if rule.Count != original.Count || !rule.Until.Equal(original.Until) {
+				t.Fatalf("исходное правило изменено: %+v", *rule)
+			}
+		})
+	}
+}
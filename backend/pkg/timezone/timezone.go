@@ -0,0 +1,48 @@
+package timezone
+
+import (
+	"errors"
+	"time"
+
+	// База часовых поясов встраивается в бинарник, чтобы расчет
+	// не зависел от tzdata на сервере
+	_ "time/tzdata"
+)
+
+var ErrInvalidTimeZone = errors.New("некорректный часовой пояс")
+
+// Часовой пояс владельцев и сотрудников, для которых он не задан
+const Default = "UTC"
+
+// Часовой пояс по имени IANA, например "Europe/Moscow". Пустое имя - UTC.
+// "Local" не принимается: расчет не должен зависеть от настроек сервера
+func Load(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, ErrInvalidTimeZone
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+	return loc, nil
+}
+
+// Часовой пояс по имени из базы. Некорректное имя заменяется на UTC,
+// имена проверяются при сохранении
+func LoadOrUTC(name string) *time.Location {
+	loc, err := Load(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Время для писем и уведомлений: "02.01.2006 15:04 (Europe/Moscow, UTC+03:00)"
+func Format(t time.Time, loc *time.Location) string {
+	local := t.In(loc)
+	return local.Format("02.01.2006 15:04") + " (" + loc.String() + ", UTC" + local.Format("-07:00") + ")"
+}
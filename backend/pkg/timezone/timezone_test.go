@@ -0,0 +1,83 @@
+package timezone
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		zone string
+		want string
+	}{
+		{"пустое имя", "", "UTC"},
+		{"UTC", "UTC", "UTC"},
+		{"IANA", "Europe/Moscow", "Europe/Moscow"},
+		{"полушаговое смещение", "Asia/Kolkata", "Asia/Kolkata"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := Load(tt.zone)
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if loc.String() != tt.want {
+				t.Fatalf("Load(%q) = %s, ожидалось %s", tt.zone, loc, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		zone string
+	}{
+		{"пояс сервера", "Local"},
+		{"неизвестный", "Mars/Olympus"},
+		{"путь", "../../etc/passwd"},
+		{"смещение", "+03:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.zone); !errors.Is(err, ErrInvalidTimeZone) {
+				t.Fatalf("Load(%q): ошибка %v, ожидалась ErrInvalidTimeZone", tt.zone, err)
+			}
+			if loc := LoadOrUTC(tt.zone); loc != time.UTC {
+				t.Fatalf("LoadOrUTC(%q) = %s, ожидалось UTC", tt.zone, loc)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	moment := time.Date(2026, 7, 1, 6, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		zone string
+		want string
+	}{
+		{"UTC", "UTC", "01.07.2026 06:30 (UTC, UTC+00:00)"},
+		{"восток", "Europe/Moscow", "01.07.2026 09:30 (Europe/Moscow, UTC+03:00)"},
+		{"запад, летнее время", "America/New_York", "01.07.2026 02:30 (America/New_York, UTC-04:00)"},
+		{"полушаговое смещение", "Asia/Kolkata", "01.07.2026 12:00 (Asia/Kolkata, UTC+05:30)"},
+		{"другие сутки", "Pacific/Auckland", "01.07.2026 18:30 (Pacific/Auckland, UTC+12:00)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := Load(tt.zone)
+			if err != nil {
+				t.Fatalf("пояс %s: %v", tt.zone, err)
+			}
+			if got := Format(moment, loc); got != tt.want {
+				t.Fatalf("Format = %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Параметры по умолчанию из RFC 6238, их поддерживают все приложения-аутентификаторы
+const (
+	Digits     = 6
+	Period     = 30 * time.Second
+	secretSize = 20
+	// Допускаем расхождение часов на один шаг в каждую сторону
+	skewSteps = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Генерирует секрет в base32 для передачи в приложение
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Номер 30-секундного шага для момента времени
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// Код для шага по алгоритму HOTP (RFC 4226)
+func GenerateCode(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Проверяет код и возвращает шаг, которому он соответствует. Шаги не больше
+// lastStep отклоняются: один и тот же код нельзя использовать дважды
+func Validate(secret string, code string, now time.Time, lastStep int64) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := Step(now)
+	for delta := int64(-skewSteps); delta <= skewSteps; delta++ {
+		step := current + delta
+		if step <= lastStep {
+			continue
+		}
+
+		expected, err := GenerateCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// otpauth:// URI для QR кода (формат Google Authenticator Key Uri)
+func ProvisioningURI(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
@@ -0,0 +1,108 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// Секрет "12345678901234567890" из тестовых векторов RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCode(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		unix   int64
+		want   string
+	}{
+		{"RFC 6238, 59 секунд", rfcSecret, 59, "287082"},
+		{"RFC 6238, 1111111109", rfcSecret, 1111111109, "081804"},
+		{"RFC 6238, 1234567890", rfcSecret, 1234567890, "005924"},
+		{"секрет в нижнем регистре", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 59, "287082"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GenerateCode(tt.secret, Step(time.Unix(tt.unix, 0)))
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("GenerateCode = %s, ожидалось %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	code := func(step int64) string {
+		c, err := GenerateCode(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		// Шаг, которому соответствует код, 0 - код отклоняется
+		want int64
+	}{
+		{"текущий шаг", code(current), 0, current},
+		{"пробелы по краям", " " + code(current) + " ", 0, current},
+		{"предыдущий шаг", code(current - 1), 0, current - 1},
+		{"следующий шаг", code(current + 1), 0, current + 1},
+		{"вне окна в прошлом", code(current - 2), 0, 0},
+		{"вне окна в будущем", code(current + 2), 0, 0},
+		{"повтор шага", code(current), current, 0},
+		{"шаг раньше использованного", code(current - 1), current, 0},
+		{"следующий после использованного", code(current + 1), current, current + 1},
+		{"короткий код", code(current)[:Digits-1], 0, 0},
+		{"неверный код", "000000", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, tt.code, now, tt.lastStep)
+			if tt.want == 0 {
+				if ok {
+					t.Fatalf("код принят для шага %d", step)
+				}
+				return
+			}
+			if !ok || step != tt.want {
+				t.Fatalf("Validate = %d, %v, ожидался шаг %d", step, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidSecret(t *testing.T) {
+	if _, ok := Validate("не base32", "123456", time.Now(), 0); ok {
+		t.Fatal("код принят с некорректным секретом")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("ошибка: %v", err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil || len(key) != secretSize {
+		t.Fatalf("секрет %q: %d байт, ошибка %v", secret, len(key), err)
+	}
+
+	other, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == secret {
+		t.Fatal("секреты совпали")
+	}
+}
@@ -1,5 +1,13 @@
 package types
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 type CheckValue string
 
 const (
@@ -7,3 +15,85 @@ const (
 	CheckValueFalse CheckValue = "false"
 	CheckValueAll   CheckValue = "all"
 )
+
+// Список строк, хранится в jsonb
+type StringList []string
+
+func (l StringList) GormDataType() string {
+	return "jsonb"
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (l *StringList) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = StringList{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("неподдерживаемый тип для StringList: %T", value)
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+// Список идентификаторов, хранится в integer[]
+type IDList []uint
+
+func (l IDList) GormDataType() string {
+	return "integer[]"
+}
+
+func (l IDList) Value() (driver.Value, error) {
+	items := make([]string, len(l))
+	for i, id := range l {
+		items[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	return "{" + strings.Join(items, ",") + "}", nil
+}
+
+func (l *IDList) Scan(value any) error {
+	var data string
+	switch v := value.(type) {
+	case nil:
+		*l = IDList{}
+		return nil
+	case []byte:
+		data = string(v)
+	case string:
+		data = v
+	default:
+		return fmt.Errorf("неподдерживаемый тип для IDList: %T", value)
+	}
+
+	data = strings.Trim(data, "{}")
+	if data == "" {
+		*l = IDList{}
+		return nil
+	}
+
+	items := strings.Split(data, ",")
+	list := make(IDList, len(items))
+	for i, item := range items {
+		id, err := strconv.ParseUint(strings.TrimSpace(item), 10, 64)
+		if err != nil {
+			return fmt.Errorf("некорректный идентификатор в IDList: %q", item)
+		}
+		list[i] = uint(id)
+	}
+	*l = list
+	return nil
+}
@@ -0,0 +1,102 @@
+package types
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIDListValue(t *testing.T) {
+	tests := []struct {
+		name string
+		list IDList
+		want string
+	}{
+		{"nil", nil, "{}"},
+		{"пустой", IDList{}, "{}"},
+		{"один", IDList{7}, "{7}"},
+		{"несколько", IDList{1, 20, 300}, "{1,20,300}"},
+		{"больше 32 бит", IDList{1 << 32}, "{4294967296}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.list.Value()
+			if err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("Value = %v, ожидалось %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIDListScan(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  IDList
+	}{
+		{"NULL", nil, IDList{}},
+		{"пустой массив", "{}", IDList{}},
+		{"строка", "{1,2,3}", IDList{1, 2, 3}},
+		{"байты", []byte("{4,5}"), IDList{4, 5}},
+		{"пробелы", "{1, 2}", IDList{1, 2}},
+		{"больше 32 бит", "{4294967296}", IDList{1 << 32}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got IDList
+			if err := got.Scan(tt.value); err != nil {
+				t.Fatalf("ошибка: %v", err)
+			}
+			if got == nil || !slices.Equal(got, tt.want) {
+				t.Fatalf("Scan = %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIDListScanErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"не число", "{1,a}"},
+		{"пустой элемент", "{1,,2}"},
+		{"отрицательное", "{-1}"},
+		{"NULL в массиве", "{1,NULL}"},
+		{"больше 64 бит", "{18446744073709551616}"},
+		{"неподдерживаемый тип", 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := IDList{9}
+			if err := list.Scan(tt.value); err == nil {
+				t.Fatalf("Scan(%v) без ошибки: %v", tt.value, list)
+			}
+			if !slices.Equal(list, IDList{9}) {
+				t.Fatalf("список изменен при ошибке: %v", list)
+			}
+		})
+	}
+}
+
+func TestIDListRoundTrip(t *testing.T) {
+	for _, list := range []IDList{{}, {1}, {3, 1, 2}, {1 << 40}} {
+		value, err := list.Value()
+		if err != nil {
+			t.Fatalf("Value: %v", err)
+		}
+
+		var got IDList
+		if err := got.Scan(value); err != nil {
+			t.Fatalf("Scan(%v): %v", value, err)
+		}
+		if !slices.Equal(got, list) {
+			t.Fatalf("после Value и Scan %v, ожидалось %v", got, list)
+		}
+	}
+}
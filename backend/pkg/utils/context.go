@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"context"
+	"record-services/pkg/consts"
+)
+
+// Пользователь, которого AuthMiddleware положил в контекст запроса
+func GetUserFromContext(ctx context.Context) *UserClaims {
+	if user, ok := ctx.Value(string(consts.ContextUserKey)).(*UserClaims); ok {
+		return user
+	}
+	return nil
+}
@@ -6,7 +6,8 @@ import (
 	"encoding/hex"
 )
 
-// Создает HMAC-SHA256 хеш от строки с секретным ключом
+// Создает HMAC-SHA256 хеш от строки с секретным ключом.
+// Для паролей не используется: см. пакет password
 func CreateHash(data string, secretKey string) string {
 	h := hmac.New(sha256.New, []byte(secretKey))
 	h.Write([]byte(data))
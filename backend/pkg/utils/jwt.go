@@ -2,6 +2,8 @@ package utils
 
 import (
 	"fmt"
+	"record-services/pkg/consts"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,18 +14,81 @@ type UserClaims struct {
 	ID    uint    `json:"id"`
 	Email string `json:"email"`
 	Name  string `json:"name"`
+	// Сессия (семейство refresh токенов), в которой выпущен токен
+	SessionID string `json:"sid,omitempty"`
+	// Владелец организации пользователя на момент выпуска токена.
+	// При его смене токены пользователя отзываются
+	OwnerID uint `json:"oid,omitempty"`
+	// Роли и права на момент выпуска токена. При их изменении токены
+	// пользователя отзываются, и клиент получает новые через refresh
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"perms,omitempty"`
+	// Идентификатор токена (jti) хранится в RegisteredClaims.ID
+	jwt.RegisteredClaims
+}
+
+func (c *UserClaims) HasPermission(permission consts.Permission) bool {
+	return slices.Contains(c.Permissions, string(permission))
+}
+
+func (c *UserClaims) HasRole(role consts.RoleName) bool {
+	return slices.Contains(c.Roles, string(role))
+}
+
+// Владелец, от имени которого пользователь создает данные: сотрудники
+// организации работают с данными ее владельца
+func (c *UserClaims) Owner() uint {
+	if c.OwnerID != 0 {
+		return c.OwnerID
+	}
+	return c.ID
+}
+
+// Владелец, данными которого ограничен пользователь.
+// 0 - без ограничений: администратор видит данные всех пользователей
+func (c *UserClaims) OwnerScope() uint {
+	if c.HasRole(consts.RoleAdmin) {
+		return 0
+	}
+	return c.Owner()
+}
+
+// Запись с владельцем ownerID доступна пользователю
+func (c *UserClaims) CanAccess(ownerID uint) bool {
+	scope := c.OwnerScope()
+	return scope == 0 || scope == ownerID
+}
+
+const (
+	jtiSize = 16
+
+	// Subject различает типы токенов, подписанных одним ключом
+	subjectUserAuth     = "user-auth"
+	subjectMFAChallenge = "mfa-challenge"
+)
+
+// Промежуточный токен между вводом пароля и кодом второго фактора.
+// Доступа к API не дает
+type MFAClaims struct {
+	UserID uint `json:"uid"`
 	jwt.RegisteredClaims
 }
 
 // Создание JWT токена из структуры
-func CreateToken(user UserClaims, jwtKey []byte) (string, error) {
+func CreateToken(user UserClaims, jwtKey []byte, ttl time.Duration) (string, error) {
+	jti, err := GenerateRandomToken(jtiSize)
+	if err != nil {
+		return "", err
+	}
+
 	// Устанавливаем время жизни токена
 	user.RegisteredClaims = jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24 часа
+		ID:        jti,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 		NotBefore: jwt.NewNumericDate(time.Now()),
 		Issuer:    "services",
-		Subject:   "user-auth",
+		Subject:   subjectUserAuth,
 	}
 
 	// Создаем токен с методом подписи HS256
@@ -41,13 +106,7 @@ func CreateToken(user UserClaims, jwtKey []byte) (string, error) {
 // Валидация и получение структуры из JWT токена
 func ParseToken(tokenString string, jwtKey []byte) (*UserClaims, error) {
 	// Парсим токен
-	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Проверяем метод подписи
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, keyFunc(jwtKey), jwt.WithSubject(subjectUserAuth))
 
 	if err != nil {
 		return nil, err
@@ -59,4 +118,50 @@ func ParseToken(tokenString string, jwtKey []byte) (*UserClaims, error) {
 	}
 
 	return nil, fmt.Errorf("invalid token")
-}
\ No newline at end of file
+}
+
+// Токен одноразовый: jti гасится после успешного входа
+func CreateMFAToken(userID uint, jwtKey []byte, ttl time.Duration) (string, error) {
+	jti, err := GenerateRandomToken(jtiSize)
+	if err != nil {
+		return "", err
+	}
+
+	claims := MFAClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    "services",
+			Subject:   subjectMFAChallenge,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtKey)
+}
+
+func ParseMFAToken(tokenString string, jwtKey []byte) (*MFAClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &MFAClaims{}, keyFunc(jwtKey), jwt.WithSubject(subjectMFAChallenge))
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*MFAClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid token")
+}
+
+func keyFunc(jwtKey []byte) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		// Проверяем метод подписи
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtKey, nil
+	}
+}
@@ -1,7 +1,20 @@
 package validator
 
-import "github.com/go-playground/validator/v10"
+import (
+	"record-services/pkg/timezone"
+
+	"github.com/go-playground/validator/v10"
+)
 
 func NewValidate() *validator.Validate {
-	return validator.New()
+	validate := validator.New()
+
+	// Встроенный тег timezone принимает "Local", а часовые пояса в БД
+	// должны загружаться так же, как при расчете слотов
+	validate.RegisterValidation("timezone", func(fl validator.FieldLevel) bool {
+		_, err := timezone.Load(fl.Field().String())
+		return err == nil
+	})
+
+	return validate
 }
\ No newline at end of file
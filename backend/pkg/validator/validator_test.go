@@ -0,0 +1,35 @@
+package validator
+
+import "testing"
+
+func TestTimeZone(t *testing.T) {
+	type data struct {
+		TimeZone string  `validate:"omitempty,timezone"`
+		Optional *string `validate:"omitempty,timezone"`
+	}
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name  string
+		data  data
+		valid bool
+	}{
+		{"пусто", data{}, true},
+		{"IANA", data{TimeZone: "Europe/Moscow"}, true},
+		{"UTC", data{TimeZone: "UTC"}, true},
+		{"пояс сервера", data{TimeZone: "Local"}, false},
+		{"неизвестный", data{TimeZone: "Mars/Olympus"}, false},
+		{"указатель", data{Optional: ptr("Asia/Tokyo")}, true},
+		{"указатель на пустую строку", data{Optional: ptr("")}, true},
+		{"указатель на пояс сервера", data{Optional: ptr("Local")}, false},
+	}
+
+	validate := NewValidate()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate.Struct(tt.data); (err == nil) != tt.valid {
+				t.Fatalf("ошибка %v, ожидалось valid=%v", err, tt.valid)
+			}
+		})
+	}
+}
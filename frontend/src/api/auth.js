@@ -7,6 +7,34 @@ const api = axios.create({
   withCredentials: true // для работы с cookies
 })
 
+// Access токен живет недолго: при 401 один раз обновляем его по refresh cookie
+// и повторяем исходный запрос
+let refreshPromise = null
+
+api.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config
+    const skipRefresh = ['/auth/login', '/auth/login/mfa', '/auth/register', '/auth/refresh'].includes(original?.url)
+
+    if (error.response?.status !== 401 || !original || original._retry || skipRefresh) {
+      return Promise.reject(error)
+    }
+
+    original._retry = true
+    try {
+      refreshPromise = refreshPromise || api.post('/auth/refresh')
+      await refreshPromise
+    } catch (refreshError) {
+      return Promise.reject(refreshError)
+    } finally {
+      refreshPromise = null
+    }
+
+    return api(original)
+  }
+)
+
 export const authAPI = {
   async register(userData) {
     const response = await api.post('/auth/register', userData)
@@ -18,6 +46,53 @@ export const authAPI = {
     return response.data
   },
 
+  async verifyEmail(token) {
+    const response = await api.post('/auth/verify-email', { token })
+    return response.data
+  },
+
+  async forgotPassword(email) {
+    const response = await api.post('/auth/forgot-password', { email })
+    return response.data
+  },
+
+  async resetPassword(data) {
+    const response = await api.post('/auth/reset-password', data)
+    return response.data
+  },
+
+  async changePassword(data) {
+    const response = await api.post('/auth/change-password', data)
+    return response.data
+  },
+
+  async refresh() {
+    const response = await api.post('/auth/refresh')
+    return response.data
+  },
+
+  // Второй шаг входа при включенной двухфакторной аутентификации
+  async loginMFA(data) {
+    const response = await api.post('/auth/login/mfa', data)
+    return response.data
+  },
+
+  async getMe() {
+    const response = await api.get('/auth/me')
+    return response.data
+  },
+
+  // Смена email вступает в силу после подтверждения по ссылке из письма
+  async updateMe(data) {
+    const response = await api.patch('/auth/me', data)
+    return response.data
+  },
+
+  async confirmEmail(token) {
+    const response = await api.post('/auth/confirm-email', { token })
+    return response.data
+  },
+
   async logout() {
     const response = await api.post('/auth/logout')
     return response.data
@@ -14,8 +14,8 @@ export const useAuthStore = defineStore('auth', () => {
     isLoading.value = true
     error.value = null
     try {
+      // Войти можно только после подтверждения email и одобрения администратором
       const response = await authAPI.register(userData)
-      await login({ email: userData.email, password: userData.password })
       return response
     } catch (err) {
       error.value = err.response?.data?.message || 'Ошибка регистрации'
@@ -42,6 +42,30 @@ export const useAuthStore = defineStore('auth', () => {
     }
   }
 
+  // Профиль текущего пользователя с сервера
+  const fetchMe = async () => {
+    try {
+      user.value = await authAPI.getMe()
+    } catch (err) {
+      user.value = null
+    }
+    return user.value
+  }
+
+  const updateProfile = async (data) => {
+    isLoading.value = true
+    error.value = null
+    try {
+      user.value = await authAPI.updateMe(data)
+      return user.value
+    } catch (err) {
+      error.value = err.response?.data?.message || 'Ошибка обновления профиля'
+      throw err
+    } finally {
+      isLoading.value = false
+    }
+  }
+
   const logout = async () => {
     try {
       await authAPI.logout()
@@ -64,6 +88,8 @@ export const useAuthStore = defineStore('auth', () => {
     register,
     login,
     logout,
+    fetchMe,
+    updateProfile,
     clearError
   }
 })